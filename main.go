@@ -1,106 +1,93 @@
+// Command g5 runs the Scheme programs it is given, or a REPL if there are
+// none.
 package main
 
 import (
-	"bufio"
-	_ "embed"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"unicode"
-)
-
-//go:embed init.scm
-var Init string
-
-//go:embed srfi/case-lambda.scm
-var CaseLambdaSRFI string
+	"path/filepath"
+	"strings"
 
-//go:embed srfi/lists.scm
-var ListsSRFI string
+	"g5/scheme"
+)
 
-func validate(code string) bool {
-	nonws := 0
-	count := 0
-	for _, r := range []rune(code) {
-		if !unicode.IsSpace(r) {
-			nonws++
-		}
-		if r == '(' {
-			count++
-		} else if r == ')' {
-			count--
-		}
+func main() {
+	compile := flag.Bool("c", false,
+		"compile each file to a .g5c image instead of running it")
+	listing := flag.Bool("S", false,
+		"print the instructions generated for each file instead of running it")
+	optLevel := flag.Int("O", 1,
+		"optimisation level (0 disables the optimiser, 2 also folds constants)")
+	flag.BoolVar(&scheme.FoldCase, "fold-case", false,
+		"fold symbols to lower case when reading, as R5RS did")
+	flag.IntVar(&scheme.MaxCallDepth, "max-depth", scheme.MaxCallDepth,
+		"how deeply calls can nest before raising an error (0 for no limit)")
+	flag.Parse()
+
+	scheme.Optimise = *optLevel > 0
+	scheme.FoldConstants = *optLevel > 1
+
+	if err := scheme.Start(); err != nil {
+		log.Fatalf("Error (prelude): %v\n", err)
 	}
-	return count == 0 && nonws != 0
-}
-
-func (ctx *Procedure) Run(code string, quiet bool) {
-	p := NewParser(code)
-	p.skipWs()
 
-	for len(p.data) > 0 {
-		v, err := p.GetValue()
-		p.skipWs()
-		if err != nil {
-			log.Fatalf("Error (parse): %v\n", err)
-		}
-
-		ctx.Ins = []Ins{}
-		if err := ctx.Gen(v); err != nil {
-			log.Fatalf("Error (gen): %v\n", err)
-		}
-
-		if err := ctx.Eval(); err != nil {
-			log.Fatalf("Error (eval): %v\n", err)
+	switch {
+	case *compile:
+		for _, fname := range flag.Args() {
+			b, err := os.ReadFile(fname)
+			if err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+			img, err := scheme.Top.Compile(string(b))
+			if err != nil {
+				log.Fatalf("Error (%s): %v\n", fname, err)
+			}
+			out, err := img.Encode()
+			if err != nil {
+				log.Fatalf("Error (%s): %v\n", fname, err)
+			}
+			dest := strings.TrimSuffix(fname, filepath.Ext(fname)) + ".g5c"
+			if err := os.WriteFile(dest, out, 0644); err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
 		}
-
-		if !quiet {
-			fmt.Println()
-			if len(stack) > 0 {
-				WriteValue(stack.Top(), false)
+	case *listing:
+		for _, fname := range flag.Args() {
+			b, err := os.ReadFile(fname)
+			if err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+			img, err := scheme.Top.Compile(string(b))
+			if err != nil {
+				log.Fatalf("Error (%s): %v\n", fname, err)
+			}
+			for i, form := range img.Forms {
+				fmt.Printf("; %s: form %d\n", fname, i+1)
+				scheme.Disassemble(form, 0)
 			}
-			fmt.Println()
 		}
-	}
-}
-
-func main() {
-	Top.Scope = TopScope // Put builtins into top-level scope
-
-	if int(SymLast) != len(SymbolNames) {
-		panic("Symbol table length mismatch")
-	}
-
-	Top.Run(Init, true)
-	Top.Run(CaseLambdaSRFI, true)
-	Top.Run(ListsSRFI, true)
-
-	for k, v := range TopScope.m { // Copy unmodified scope into basescope
-		BaseScope[k] = v
-	}
-
-	switch len(os.Args) {
-	case 1:
-		reader := bufio.NewReader(os.Stdin)
+	case flag.NArg() == 0:
+		// Share stdin's buffer so that read and read-line see what follows
+		reader := scheme.InputPortStack[0].Reader
 		for {
 			fmt.Print("> ")
 
 			code, _ := reader.ReadString('\n')
 
-			for !validate(code) {
+			for !scheme.Validate(code) {
 				fmt.Print(">> ")
 				next, _ := reader.ReadString('\n')
 				code += next
 			}
-			Top.Run(code, false)
-		}
-	case 2:
-		b, err := os.ReadFile(os.Args[1])
-		if err != nil {
-			log.Fatalln("Error: Could not read file")
+			scheme.Top.Run(code, false)
 		}
-		Top.Run(string(b), true)
 	default:
-		fmt.Printf("Usage: %s [filename]", os.Args[0])
+		for _, fname := range flag.Args() {
+			if _, err := scheme.Top.LoadFile(fname); err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+		}
 	}
 }
@@ -0,0 +1,509 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+// The bridge lets Go functions be called as procedures, with their arguments
+// and results converted by reflection, so that embedding g5 doesn't need a
+// builtin written for each of them.
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType    = reflect.TypeOf(big.Int{})
+	bigRatType    = reflect.TypeOf(big.Rat{})
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// Register binds name in env to a procedure calling the Go function fn, as
+// GoProcedure makes.
+func (env *Procedure) Register(name string, fn interface{}) error {
+	proc, err := GoProcedure(name, fn)
+	if err != nil {
+		return err
+	}
+	env.Scope.m[Str2Sym(name)] = proc
+	return nil
+}
+
+// Lookup returns the value name is bound to in env or a scope it is within.
+func (env *Procedure) Lookup(name string) (Value, error) {
+	sym := Str2Sym(name)
+	scope := env.Scope.Lookup(sym)
+	if scope == nil {
+		return nil, fmt.Errorf("Unbound variable: %s", name)
+	}
+	return scope.m[sym], nil
+}
+
+// Call calls proc from Go with args converted by ToValue, and returns its
+// result as goValue converts it.  proc may be a procedure, a Go function, or
+// the name of a procedure bound in env.
+func (env *Procedure) Call(proc interface{}, args ...interface{}) (interface{}, error) {
+	var f Value
+	var err error
+	switch proc := proc.(type) {
+	case string:
+		f, err = env.Lookup(proc)
+	default:
+		f, err = ToValue(proc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.(*Procedure); !ok {
+		return nil, fmt.Errorf("Cannot call %s", ValueString(f, false))
+	}
+
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		if values[i], err = ToValue(arg); err != nil {
+			return nil, err
+		}
+	}
+	res, err := Apply(f, values...)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := res.(MultipleValues); ok {
+		return nil, errors.New("Procedures can only return one value to Go")
+	}
+	return goValue(res), nil
+}
+
+// GoProcedure returns a procedure that calls the Go function fn.  Arguments
+// are converted to the types fn takes with FromValue, and its results back
+// with ToValue.  A last result of type error is raised if it isn't nil, and
+// any others are returned as multiple values.
+func GoProcedure(name string, fn interface{}) (*Procedure, error) {
+	f := reflect.ValueOf(fn)
+	if f.Kind() != reflect.Func || f.IsNil() {
+		return nil, fmt.Errorf("Cannot make a procedure of %T", fn)
+	}
+	t := f.Type()
+
+	return &Procedure{Name: name, Builtin: func(nargs int) error {
+		fixed := t.NumIn()
+		if t.IsVariadic() {
+			fixed--
+		}
+		if nargs < fixed || (!t.IsVariadic() && nargs > fixed) {
+			if t.IsVariadic() {
+				return fmt.Errorf("%s takes at least %d arguments", name, fixed)
+			}
+			return fmt.Errorf("%s takes %d arguments", name, fixed)
+		}
+
+		args := make([]reflect.Value, nargs)
+		for i := range args {
+			var at reflect.Type
+			if i < fixed {
+				at = t.In(i)
+			} else {
+				at = t.In(fixed).Elem() // The type of the variadic arguments
+			}
+			arg, err := FromValue(stack.Pop(), at)
+			if err != nil {
+				return fmt.Errorf("%s: argument %d: %v", name, i+1, err)
+			}
+			args[i] = arg
+		}
+
+		res, err := callGo(name, f, args)
+		if err != nil {
+			return err
+		}
+		if n := len(res); n > 0 && t.Out(n-1) == errorType {
+			if err, _ := res[n-1].Interface().(error); err != nil {
+				return err
+			}
+			res = res[:n-1]
+		}
+		return pushResults(name, res)
+	}}, nil
+}
+
+// callGo calls f, returning a panic in it as an error rather than letting it
+// take down the interpreter.
+func callGo(name string, f reflect.Value, args []reflect.Value) (res []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: panic: %v", name, r)
+		}
+	}()
+	return f.Call(args), nil
+}
+
+// pushResults pushes the results of a Go function, as multiple values if
+// there is more than one.
+func pushResults(name string, res []reflect.Value) error {
+	if len(res) == 0 {
+		stack.Push(Boolean(true))
+		return nil
+	}
+
+	values := make([]Value, len(res))
+	for i, r := range res {
+		v, err := ToValue(r.Interface())
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		values[i] = v
+	}
+	for i := len(values) - 1; i >= 0; i-- {
+		stack.Push(values[i])
+	}
+	if len(values) > 1 {
+		stack.Push(MultipleValues(len(values)))
+	}
+	return nil
+}
+
+// ToValue converts a Go value to the Scheme value it corresponds to.  Numbers
+// become exact integers or rationals, strings become strings, byte slices
+// bytevectors, other slices and arrays lists, and maps alists sorted by key.
+// Go functions become procedures, and values already a Value are left alone.
+// Anything else, such as a struct or a pointer to one, is wrapped as a
+// GoValue.
+func ToValue(x interface{}) (Value, error) {
+	if v, ok := x.(Value); ok {
+		return v, nil
+	}
+
+	switch x := x.(type) {
+	case nil:
+		return Empty, nil
+	case *big.Int:
+		return Integer(*new(big.Int).Set(x)), nil
+	case big.Int:
+		return Integer(*new(big.Int).Set(&x)), nil
+	case *big.Rat:
+		return ratValue(new(big.Rat).Set(x)), nil
+	case big.Rat:
+		return ratValue(new(big.Rat).Set(&x)), nil
+	case []byte:
+		b := append([]byte{}, x...)
+		return Bytevector{&b}, nil
+	case error:
+		return &ErrorObject{Message: x.Error(), Irritants: Empty}, nil
+	}
+
+	rv := reflect.ValueOf(x)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Boolean(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Integer(*big.NewInt(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return Integer(*new(big.Int).SetUint64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("Cannot convert %v to an exact number", f)
+		}
+		return ratValue(new(big.Rat).SetFloat64(f)), nil
+	case reflect.String:
+		return NewString(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return Empty, nil
+		}
+		items := make([]Value, rv.Len())
+		for i := range items {
+			item, err := ToValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return vec2list(items), nil
+	case reflect.Map:
+		keys := make([]Value, 0, rv.Len())
+		pairs := map[string]Value{}
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, err := ToValue(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			v, err := ToValue(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			name := ValueString(k, false)
+			if _, ok := pairs[name]; ok {
+				return nil, fmt.Errorf("Cannot convert %T: more than one key "+
+					"converts to %s", x, name)
+			}
+			keys = append(keys, k)
+			pairs[name] = &Pair{&k, &v}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return ValueString(keys[i], false) < ValueString(keys[j], false)
+		})
+		alist := make([]Value, len(keys))
+		for i, k := range keys {
+			alist[i] = pairs[ValueString(k, false)]
+		}
+		return vec2list(alist), nil
+	case reflect.Func:
+		return GoProcedure("", x)
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return Empty, nil
+		}
+	}
+	return NewGoValue(x, ""), nil
+}
+
+// FromValue converts v to the Go type t, as the opposite of ToValue.  Lists
+// and vectors convert to slices, alists and hash tables to maps, and Go
+// values to what they wrap.  Any value is true as a bool except #f, and
+// procedures convert to Go functions that call them.  An empty interface
+// takes the Go value nearest to v.
+func FromValue(v Value, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Value{}, fmt.Errorf("Cannot convert no value to %v", t)
+	}
+	if reflect.TypeOf(v).AssignableTo(t) && t != interfaceType {
+		return reflect.ValueOf(v).Convert(t), nil
+	}
+	if g, ok := v.(*GoValue); ok && g.Object != nil &&
+		reflect.TypeOf(g.Object).AssignableTo(t) {
+		return reflect.ValueOf(g.Object), nil
+	}
+
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("Cannot convert %s to %v",
+			ValueString(v, false), t)
+	}
+	res := reflect.New(t).Elem()
+
+	switch t {
+	case bigIntType, reflect.PtrTo(bigIntType):
+		i, ok := toInt(v)
+		if !ok {
+			return fail()
+		}
+		if t == bigIntType {
+			return reflect.ValueOf(*i), nil
+		}
+		return reflect.ValueOf(i), nil
+	case bigRatType, reflect.PtrTo(bigRatType):
+		r, ok := toRat(v)
+		if !ok {
+			return fail()
+		}
+		if t == bigRatType {
+			return reflect.ValueOf(*r), nil
+		}
+		return reflect.ValueOf(r), nil
+	case interfaceType:
+		x := goValue(v)
+		if x == nil {
+			return res, nil
+		}
+		return reflect.ValueOf(x), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		res.SetBool(v != Boolean(false))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := toInt(v)
+		if !ok || !i.IsInt64() || res.OverflowInt(i.Int64()) {
+			return fail()
+		}
+		res.SetInt(i.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		i, ok := toInt(v)
+		if !ok || !i.IsUint64() || res.OverflowUint(i.Uint64()) {
+			return fail()
+		}
+		res.SetUint(i.Uint64())
+	case reflect.Float32, reflect.Float64:
+		r, ok := toRat(v)
+		if !ok {
+			return fail()
+		}
+		f, _ := r.Float64()
+		res.SetFloat(f)
+	case reflect.String:
+		switch v := v.(type) {
+		case String:
+			res.SetString(v.String())
+		case Symbol:
+			res.SetString(SymbolNames[v])
+		default:
+			return fail()
+		}
+	case reflect.Slice, reflect.Array:
+		var items []Value
+		switch v := v.(type) {
+		case *Pair:
+			var err error
+			if items, err = list2vec(v); err != nil {
+				return fail()
+			}
+		case Vector:
+			items = *v.v
+		case Bytevector:
+			if t.Elem().Kind() != reflect.Uint8 {
+				return fail()
+			}
+			for _, b := range *v.b {
+				items = append(items, Integer(*big.NewInt(int64(b))))
+			}
+		default:
+			return fail()
+		}
+		if t.Kind() == reflect.Slice {
+			res = reflect.MakeSlice(t, len(items), len(items))
+		} else if len(items) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("Expected %d items for %v, got %d",
+				t.Len(), t, len(items))
+		}
+		for i, item := range items {
+			elem, err := FromValue(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			res.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		var entries []hashEntry
+		switch v := v.(type) {
+		case *HashTable:
+			entries = v.Entries()
+		case *Pair:
+			items, err := list2vec(v)
+			if err != nil {
+				return fail()
+			}
+			for _, item := range items {
+				pair, ok := item.(*Pair)
+				if !ok || pair == Empty {
+					return fail()
+				}
+				entries = append(entries, hashEntry{*pair.Car, *pair.Cdr})
+			}
+		default:
+			return fail()
+		}
+		res = reflect.MakeMapWithSize(t, len(entries))
+		for i := len(entries) - 1; i >= 0; i-- { // So earlier entries win
+			key, err := FromValue(entries[i].key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value, err := FromValue(entries[i].value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			res.SetMapIndex(key, value)
+		}
+	case reflect.Func:
+		proc, ok := v.(*Procedure)
+		if !ok {
+			return fail()
+		}
+		return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+			return callProcedure(proc, t, in)
+		}), nil
+	default:
+		return fail()
+	}
+	return res, nil
+}
+
+// callProcedure calls proc for a Go function of type t.  An error is returned
+// as the function's last result if t has one, and panics otherwise.
+func callProcedure(proc *Procedure, t reflect.Type, in []reflect.Value) []reflect.Value {
+	out := make([]reflect.Value, t.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(t.Out(i))
+	}
+	fail := func(err error) []reflect.Value {
+		if len(out) == 0 || t.Out(len(out)-1) != errorType {
+			panic(err)
+		}
+		out[len(out)-1] = reflect.ValueOf(&err).Elem()
+		return out
+	}
+
+	args := make([]Value, len(in))
+	for i, arg := range in {
+		v, err := ToValue(arg.Interface())
+		if err != nil {
+			return fail(err)
+		}
+		args[i] = v
+	}
+	res, err := Apply(proc, args...)
+	if err != nil {
+		return fail(err)
+	}
+
+	n := len(out)
+	if n > 0 && t.Out(n-1) == errorType {
+		n--
+	}
+	if n == 0 {
+		return out
+	} else if n > 1 {
+		return fail(errors.New("Procedures can only return one value to Go"))
+	}
+	if out[0], err = FromValue(res, t.Out(0)); err != nil {
+		return fail(err)
+	}
+	return out
+}
+
+// goValue returns the Go value nearest to v, for an empty interface.
+// Integers are ints where they fit in one, and *big.Ints otherwise.
+func goValue(v Value) interface{} {
+	switch v := v.(type) {
+	case Boolean:
+		return bool(v)
+	case Integer:
+		i := big.Int(v)
+		if i.IsInt64() && int64(int(i.Int64())) == i.Int64() {
+			return int(i.Int64())
+		}
+		return new(big.Int).Set(&i)
+	case Rational:
+		r := big.Rat(v)
+		return new(big.Rat).Set(&r)
+	case String:
+		return v.String()
+	case Bytevector:
+		return append([]byte{}, *v.b...)
+	case *Pair:
+		if v == Empty {
+			return nil
+		}
+		if items, err := list2vec(v); err == nil {
+			return goValues(items)
+		}
+	case Vector:
+		return goValues(*v.v)
+	case *GoValue:
+		return v.Object
+	}
+	return v
+}
+
+func goValues(items []Value) []interface{} {
+	res := make([]interface{}, len(items))
+	for i, item := range items {
+		res[i] = goValue(item)
+	}
+	return res
+}
@@ -0,0 +1,1089 @@
+package scheme
+
+var SymbolNames = []string{
+	"quote",
+	"unquote",
+	"quasiquote",
+	"unquote-splicing",
+	"...",
+
+	"null-environment",
+	"scheme-report-environment",
+	"sandbox-environment",
+	"eval",
+	"load",
+
+	"set!",
+	"define",
+	"define-values",
+	"define-record-type",
+	"lambda",
+	"if",
+	"define-syntax",
+	"let-syntax",
+	"letrec-syntax",
+	"save-scope",
+	"syntax-rules",
+
+	"call/cc",
+	"exit",
+	"dynamic-wind",
+	"values",
+	"call-with-values",
+	"raise",
+	"raise-continuable",
+	"error",
+	"with-exception-handler",
+	"%guard",
+	"error-object?",
+	"error-object-message",
+	"error-object-irritants",
+	"file-error?",
+	"read-error?",
+
+	"+",
+	"-",
+	"*",
+	"/",
+	">",
+	"<",
+	"=",
+	"quotient",
+	"remainder",
+	"modulo",
+	"numerator",
+	"denominator",
+	"floor",
+	"ceiling",
+	"truncate",
+	"round",
+	"char->integer",
+	"rexpt",
+	"log",
+	"sin",
+	"cos",
+	"asin",
+	"acos",
+	"atan",
+	"string->number",
+	"<=",
+	">=",
+	"min",
+	"max",
+	"abs",
+	"even?",
+	"odd?",
+	"gcd",
+	"lcm",
+	"floor/",
+	"floor-quotient",
+	"truncate/",
+	"exact-integer-sqrt",
+
+	"number?",
+	"complex?",
+	"real?",
+	"rational?",
+	"integer?",
+
+	"not",
+	"eqv?",
+	"eq?",
+	"equal?",
+	"boolean?",
+	"boolean=?",
+	"symbol?",
+	"symbol=?",
+	"string->uninterned-symbol",
+	"gensym",
+	"generate-uninterned-symbol",
+	"symbol-interned?",
+
+	"pair?",
+	"cons",
+	"car",
+	"cdr",
+	"set-car!",
+	"set-cdr!",
+	"apply",
+	"vector->list",
+	"string->list",
+	"list?",
+	"make-list",
+	"list-set!",
+
+	"vector?",
+	"make-vector",
+	"vector",
+	"vector-length",
+	"vector-ref",
+	"vector-set!",
+	"list->vector",
+	"vector-fill!",
+	"vector-copy",
+	"vector-copy!",
+	"vector-append",
+	"vector->string",
+	"vector-map",
+	"vector-for-each",
+
+	"bytevector?",
+	"make-bytevector",
+	"bytevector",
+	"bytevector-length",
+	"bytevector-u8-ref",
+	"bytevector-u8-set!",
+	"bytevector-copy",
+	"bytevector-copy!",
+	"bytevector-append",
+	"utf8->string",
+	"string->utf8",
+
+	"make-hash-table",
+	"hash-table?",
+	"hash-table-ref",
+	"hash-table-ref/default",
+	"hash-table-set!",
+	"hash-table-delete!",
+	"hash-table-contains?",
+	"hash-table-exists?",
+	"hash-table-update!",
+	"hash-table-update!/default",
+	"hash-table-size",
+	"hash-table-keys",
+	"hash-table-values",
+	"hash-table->alist",
+	"hash-table-walk",
+	"hash-table-fold",
+	"hash-table-clear!",
+	"hash-table-copy",
+	"alist->hash-table",
+	"hash-table-equivalence-function",
+	"hash-table-hash-function",
+	"hash",
+	"string-hash",
+	"string-ci-hash",
+	"hash-by-identity",
+
+	"make-record-type",
+	"record-constructor",
+	"record-predicate",
+	"record-accessor",
+	"record-modifier",
+	"record?",
+	"record-type-descriptor",
+	"record-type-name",
+	"record-type-field-names",
+
+	"char?",
+	"integer->char",
+	"char-upcase",
+	"char-downcase",
+	"char-foldcase",
+	"char-alphabetic?",
+	"char-numeric?",
+	"char-whitespace?",
+	"char-upper-case?",
+	"char-lower-case?",
+	"digit-value",
+	"char=?",
+	"char<?",
+	"char>?",
+	"char<=?",
+	"char>=?",
+	"char-ci=?",
+	"char-ci<?",
+	"char-ci>?",
+	"char-ci<=?",
+	"char-ci>=?",
+
+	"string?",
+	"make-string",
+	"string",
+	"string-length",
+	"string-ref",
+	"string-set!",
+	"string-downcase",
+	"string-foldcase",
+	"substring",
+	"string-append",
+	"string=?",
+	"symbol->string",
+	"number->string",
+	"list->string",
+	"string-upcase",
+	"string<?",
+	"string>?",
+	"string<=?",
+	"string>=?",
+	"string-ci=?",
+	"string-ci<?",
+	"string-ci>?",
+	"string-ci<=?",
+	"string-ci>=?",
+	"string->symbol",
+	"string-copy",
+	"string-copy!",
+	"string-fill!",
+	"string->vector",
+	"string-map",
+	"string-for-each",
+
+	"string-null?",
+	"string-index",
+	"string-index-right",
+	"string-skip",
+	"string-skip-right",
+	"string-count",
+	"string-contains",
+	"string-contains-right",
+	"string-prefix?",
+	"string-suffix?",
+	"string-prefix-ci?",
+	"string-suffix-ci?",
+	"string-search-forward",
+	"string-search-backward",
+	"string-search-all",
+	"string-take",
+	"string-drop",
+	"string-take-right",
+	"string-drop-right",
+	"string-pad",
+	"string-pad-right",
+	"string-trim",
+	"string-trim-right",
+	"string-trim-both",
+	"string-join",
+	"string-split",
+	"string-tokenize",
+	"string-replace",
+	"string-reverse",
+	"string-cursor-start",
+	"string-cursor-end",
+	"string-cursor-next",
+	"string-cursor-prev",
+	"string-cursor-forward",
+	"string-cursor-back",
+	"string-cursor-ref",
+	"string-cursor->index",
+	"string-index->cursor",
+	"string-cursor-diff",
+	"string-cursor=?",
+	"string-cursor<?",
+	"string-cursor>?",
+	"string-cursor<=?",
+	"string-cursor>=?",
+	"string-normalize-nfc",
+	"string-normalize-nfd",
+	"string-normalize-nfkc",
+	"string-normalize-nfkd",
+	"string-grapheme-count",
+	"string-graphemes",
+
+	"regexp",
+	"regexp?",
+	"regexp-quote",
+	"regexp-match",
+	"regexp-match-positions",
+	"regexp-search",
+	"regexp-matches?",
+	"regexp-replace",
+	"regexp-replace-all",
+	"regexp-split",
+
+	"json-read",
+	"json-read-string",
+	"json-write",
+	"json-write-string",
+	"json-null",
+	"json-null?",
+
+	"go-value?",
+	"go-value-tag",
+	"go-call",
+	"go-field",
+	"go-methods",
+
+	"port?",
+	"call-with-input-file",
+	"call-with-output-file",
+	"open-input-file",
+	"open-output-file",
+	"close-input-port",
+	"close-output-port",
+	"read",
+	"read-char",
+	"peek-char",
+	"eof-object?",
+	"char-ready?",
+	"write",
+	"write-shared",
+	"write-simple",
+	"pretty-print",
+	"pp",
+	"display",
+	"input-port?",
+	"output-port?",
+	"input-port-open?",
+	"output-port-open?",
+	"current-input-port",
+	"current-output-port",
+	"current-error-port",
+	"call-with-port",
+	"with-input-from-file",
+	"with-output-to-file",
+	"open-input-string",
+	"open-output-string",
+	"get-output-string",
+	"open-input-bytevector",
+	"open-output-bytevector",
+	"get-output-bytevector",
+	"close-port",
+	"flush-output-port",
+	"read-line",
+	"read-string",
+	"read-u8",
+	"peek-u8",
+	"u8-ready?",
+	"read-bytevector",
+	"read-bytevector!",
+	"eof-object",
+	"write-string",
+	"write-u8",
+	"write-bytevector",
+
+	"procedure?",
+	"disassemble",
+
+	"get-environment-variables",
+}
+
+const (
+	// Primitives
+	Quote = Symbol(iota)
+	Unquote
+	Quasiquote
+	UnquoteSplicing
+	Ellipsis
+
+	SymNullEnvironment
+	SymSchemeReportEnvironment
+	SymSandboxEnvironment
+	SymEval
+	SymLoad
+
+	SymSet
+	SymDefine
+	SymDefineValues
+	SymDefineRecordType
+	SymLambda
+	SymIf
+	SymDefineSyntax
+	SymLetSyntax
+	SymLetrecSyntax
+	SymSaveScope
+	SymSyntaxRules
+
+	// Builtin procedures
+	SymCallCC
+	SymExit
+	SymDynamicWind
+	SymValues
+	SymCallWithValues
+	SymRaise
+	SymRaiseContinuable
+	SymError
+	SymWithExceptionHandler
+	SymGuard
+	SymIsErrorObject
+	SymErrorObjectMessage
+	SymErrorObjectIrritants
+	SymIsFileError
+	SymIsReadError
+
+	SymAdd
+	SymSub
+	SymMul
+	SymDiv
+	SymGt
+	SymLt
+	SymEqu
+	SymQuotient
+	SymRemainder
+	SymModulo
+	SymNumerator
+	SymDenominator
+	SymFloor
+	SymCeiling
+	SymTruncate
+	SymRound
+	SymChar2Integer
+	SymRExpt
+	SymLog
+	SymSin
+	SymCos
+	SymAsin
+	SymAcos
+	SymAtan
+	SymString2Number
+	SymLe
+	SymGe
+	SymMin
+	SymMax
+	SymAbs
+	SymIsEven
+	SymIsOdd
+	SymGcd
+	SymLcm
+	SymFloorDiv
+	SymFloorQuotient
+	SymTruncateDiv
+	SymExactIntegerSqrt
+
+	SymIsNumber
+	SymIsComplex
+	SymIsReal
+	SymIsRational
+	SymIsInteger
+
+	SymNot
+	SymEqv
+	SymEq
+	SymEqual
+	SymIsBoolean
+	SymBooleanEq
+	SymIsSymbol
+	SymSymbolEq
+	SymString2UninternedSymbol
+	SymGensym
+	SymGenerateUninternedSymbol
+	SymIsSymbolInterned
+
+	SymIsPair
+	SymCons
+	SymCar
+	SymCdr
+	SymSetCar
+	SymSetCdr
+	SymApply
+	SymVector2List
+	SymString2List
+	SymIsList
+	SymMakeList
+	SymListSet
+
+	SymIsVector
+	SymMakeVector
+	SymVector
+	SymVectorLength
+	SymVectorRef
+	SymVectorSet
+	SymList2Vector
+	SymVectorFill
+	SymVectorCopy
+	SymVectorCopyInto
+	SymVectorAppend
+	SymVector2String
+	SymVectorMap
+	SymVectorForEach
+
+	SymIsBytevector
+	SymMakeBytevector
+	SymBytevector
+	SymBytevectorLength
+	SymBytevectorU8Ref
+	SymBytevectorU8Set
+	SymBytevectorCopy
+	SymBytevectorCopyInto
+	SymBytevectorAppend
+	SymUtf82String
+	SymString2Utf8
+
+	SymMakeHashTable
+	SymIsHashTable
+	SymHashTableRef
+	SymHashTableRefDefault
+	SymHashTableSet
+	SymHashTableDelete
+	SymHashTableContains
+	SymHashTableExists
+	SymHashTableUpdate
+	SymHashTableUpdateDefault
+	SymHashTableSize
+	SymHashTableKeys
+	SymHashTableValues
+	SymHashTable2Alist
+	SymHashTableWalk
+	SymHashTableFold
+	SymHashTableClear
+	SymHashTableCopy
+	SymAlist2HashTable
+	SymHashTableEquivalenceFunction
+	SymHashTableHashFunction
+	SymHash
+	SymStringHash
+	SymStringCiHash
+	SymHashByIdentity
+
+	SymMakeRecordType
+	SymRecordConstructor
+	SymRecordPredicate
+	SymRecordAccessor
+	SymRecordModifier
+	SymIsRecord
+	SymRecordTypeDescriptor
+	SymRecordTypeName
+	SymRecordTypeFieldNames
+
+	SymIsChar
+	SymInteger2Char
+	SymCharUpcase
+	SymCharDowncase
+	SymCharFoldcase
+	SymIsCharAlphabetic
+	SymIsCharNumeric
+	SymIsCharWhitespace
+	SymIsCharUpperCase
+	SymIsCharLowerCase
+	SymDigitValue
+	SymCharEq
+	SymCharLt
+	SymCharGt
+	SymCharLe
+	SymCharGe
+	SymCharCiEq
+	SymCharCiLt
+	SymCharCiGt
+	SymCharCiLe
+	SymCharCiGe
+
+	SymIsString
+	SymMakeString
+	SymString
+	SymStringLength
+	SymStringRef
+	SymStringSet
+	SymStringDowncase
+	SymStringFoldcase
+	SymSubstring
+	SymStringAppend
+	SymStringEq
+	SymSymbol2String
+	SymNumber2String
+	SymList2String
+	SymStringUpcase
+	SymStringLt
+	SymStringGt
+	SymStringLe
+	SymStringGe
+	SymStringCiEq
+	SymStringCiLt
+	SymStringCiGt
+	SymStringCiLe
+	SymStringCiGe
+	SymString2Symbol
+	SymStringCopy
+	SymStringCopyInto
+	SymStringFill
+	SymString2Vector
+	SymStringMap
+	SymStringForEach
+
+	SymIsStringNull
+	SymStringIndex
+	SymStringIndexRight
+	SymStringSkip
+	SymStringSkipRight
+	SymStringCount
+	SymStringContains
+	SymStringContainsRight
+	SymIsStringPrefix
+	SymIsStringSuffix
+	SymIsStringPrefixCi
+	SymIsStringSuffixCi
+	SymStringSearchForward
+	SymStringSearchBackward
+	SymStringSearchAll
+	SymStringTake
+	SymStringDrop
+	SymStringTakeRight
+	SymStringDropRight
+	SymStringPad
+	SymStringPadRight
+	SymStringTrim
+	SymStringTrimRight
+	SymStringTrimBoth
+	SymStringJoin
+	SymStringSplit
+	SymStringTokenize
+	SymStringReplace
+	SymStringReverse
+	SymStringCursorStart
+	SymStringCursorEnd
+	SymStringCursorNext
+	SymStringCursorPrev
+	SymStringCursorForward
+	SymStringCursorBack
+	SymStringCursorRef
+	SymStringCursor2Index
+	SymStringIndex2Cursor
+	SymStringCursorDiff
+	SymStringCursorEq
+	SymStringCursorLt
+	SymStringCursorGt
+	SymStringCursorLe
+	SymStringCursorGe
+	SymStringNormalizeNfc
+	SymStringNormalizeNfd
+	SymStringNormalizeNfkc
+	SymStringNormalizeNfkd
+	SymStringGraphemeCount
+	SymStringGraphemes
+
+	SymRegexp
+	SymIsRegexp
+	SymRegexpQuote
+	SymRegexpMatch
+	SymRegexpMatchPositions
+	SymRegexpSearch
+	SymIsRegexpMatches
+	SymRegexpReplace
+	SymRegexpReplaceAll
+	SymRegexpSplit
+
+	SymJsonRead
+	SymJsonReadString
+	SymJsonWrite
+	SymJsonWriteString
+	SymJsonNull
+	SymIsJsonNull
+
+	SymIsGoValue
+	SymGoValueTag
+	SymGoCall
+	SymGoField
+	SymGoMethods
+
+	SymIsPort
+	SymCallWithInputFile
+	SymCallWithOutputFile
+	SymOpenInputFile
+	SymOpenOutputFile
+	SymCloseInputPort
+	SymCloseOutputPort
+	SymRead
+	SymReadChar
+	SymPeekChar
+	SymIsEofObject
+	SymIsCharReady
+	SymWrite
+	SymWriteShared
+	SymWriteSimple
+	SymPrettyPrint
+	SymPp
+	SymDisplay
+	SymIsInputPort
+	SymIsOutputPort
+	SymIsInputPortOpen
+	SymIsOutputPortOpen
+	SymCurrentInputPort
+	SymCurrentOutputPort
+	SymCurrentErrorPort
+	SymCallWithPort
+	SymWithInputFromFile
+	SymWithOutputToFile
+	SymOpenInputString
+	SymOpenOutputString
+	SymGetOutputString
+	SymOpenInputBytevector
+	SymOpenOutputBytevector
+	SymGetOutputBytevector
+	SymClosePort
+	SymFlushOutputPort
+	SymReadLine
+	SymReadString
+	SymReadU8
+	SymPeekU8
+	SymIsU8Ready
+	SymReadBytevector
+	SymReadBytevectorInto
+	SymEofObject
+	SymWriteString
+	SymWriteU8
+	SymWriteBytevector
+
+	SymIsProcedure
+	SymDisassemble
+
+	SymGetEnvironmentVariables
+
+	SymLast
+)
+
+var BaseScope = map[Symbol]Value{}
+var TopScope = Scope{
+	map[Symbol]Value{
+		SymCallCC:         &Procedure{CallCC: FnCallCC},
+		SymExit:           &Procedure{Builtin: FnExit},
+		SymDynamicWind:    &Procedure{Builtin: FnDynamicWind},
+		SymValues:         &Procedure{Builtin: FnValues},
+		SymCallWithValues: &Procedure{Builtin: FnCallWithValues},
+
+		SymRaise:                &Procedure{Builtin: FnRaise},
+		SymRaiseContinuable:     &Procedure{Builtin: FnRaiseContinuable},
+		SymError:                &Procedure{Builtin: FnError},
+		SymWithExceptionHandler: &Procedure{Builtin: FnWithExceptionHandler},
+		SymGuard:                &Procedure{Builtin: FnGuard},
+		SymIsErrorObject:        &Procedure{Builtin: FnIsErrorObject},
+		SymErrorObjectMessage:   &Procedure{Builtin: FnErrorObjectMessage},
+		SymErrorObjectIrritants: &Procedure{Builtin: FnErrorObjectIrritants},
+		SymIsFileError:          &Procedure{Builtin: FnIsFileError},
+		SymIsReadError:          &Procedure{Builtin: FnIsReadError},
+
+		SymNullEnvironment: &Procedure{Builtin: FnNullEnvironment},
+		SymSchemeReportEnvironment: &Procedure{
+			Builtin: FnSchemeReportEnvironment,
+		},
+		SymSandboxEnvironment: &Procedure{Builtin: FnSandboxEnvironment},
+		SymEval:               &Procedure{Builtin: FnEval},
+		SymLoad:               &Procedure{Builtin: FnLoad},
+
+		SymAdd:           &Procedure{Builtin: FnAdd},
+		SymSub:           &Procedure{Builtin: FnSub},
+		SymMul:           &Procedure{Builtin: FnMul},
+		SymDiv:           &Procedure{Builtin: FnDiv},
+		SymGt:            &Procedure{Builtin: FnGt},
+		SymLt:            &Procedure{Builtin: FnLt},
+		SymEqu:           &Procedure{Builtin: FnNumEq},
+		SymQuotient:      &Procedure{Builtin: FnQuotient},
+		SymRemainder:     &Procedure{Builtin: FnRemainder},
+		SymModulo:        &Procedure{Builtin: FnModulo},
+		SymNumerator:     &Procedure{Builtin: FnNumerator},
+		SymDenominator:   &Procedure{Builtin: FnDenominator},
+		SymFloor:         &Procedure{Builtin: FnFloor},
+		SymCeiling:       &Procedure{Builtin: FnCeiling},
+		SymTruncate:      &Procedure{Builtin: FnTruncate},
+		SymRound:         &Procedure{Builtin: FnRound},
+		SymChar2Integer:  &Procedure{Builtin: FnChar2Integer},
+		SymRExpt:         &Procedure{Builtin: FnRExpt},
+		SymLog:           &Procedure{Builtin: FnLog},
+		SymSin:           &Procedure{Builtin: FnSin},
+		SymCos:           &Procedure{Builtin: FnCos},
+		SymAsin:          &Procedure{Builtin: FnAsin},
+		SymAcos:          &Procedure{Builtin: FnAcos},
+		SymAtan:          &Procedure{Builtin: FnAtan},
+		SymString2Number: &Procedure{Builtin: FnString2Number},
+
+		SymLe:               &Procedure{Builtin: FnLe},
+		SymGe:               &Procedure{Builtin: FnGe},
+		SymMin:              &Procedure{Builtin: FnMin},
+		SymMax:              &Procedure{Builtin: FnMax},
+		SymAbs:              &Procedure{Builtin: FnAbs},
+		SymIsEven:           &Procedure{Builtin: FnIsEven},
+		SymIsOdd:            &Procedure{Builtin: FnIsOdd},
+		SymGcd:              &Procedure{Builtin: FnGcd},
+		SymLcm:              &Procedure{Builtin: FnLcm},
+		SymFloorDiv:         &Procedure{Builtin: FnFloorDiv},
+		SymFloorQuotient:    &Procedure{Builtin: FnFloorQuotient},
+		SymTruncateDiv:      &Procedure{Builtin: FnTruncateDiv},
+		SymExactIntegerSqrt: &Procedure{Builtin: FnExactIntegerSqrt},
+
+		SymNot:   &Procedure{Builtin: FnNot},
+		SymEqv:   &Procedure{Builtin: FnEqv},
+		SymEq:    &Procedure{Builtin: FnEqv},
+		SymEqual: &Procedure{Builtin: FnEqual},
+
+		SymIsBoolean: &Procedure{Builtin: FnIsBoolean},
+		SymBooleanEq: &Procedure{Builtin: FnBooleanEq},
+		SymIsSymbol:  &Procedure{Builtin: FnIsSymbol},
+		SymSymbolEq:  &Procedure{Builtin: FnSymbolEq},
+		SymString2UninternedSymbol: &Procedure{
+			Builtin: FnString2UninternedSymbol,
+		},
+		SymGensym:                   &Procedure{Builtin: FnGensym},
+		SymGenerateUninternedSymbol: &Procedure{Builtin: FnGensym},
+		SymIsSymbolInterned:         &Procedure{Builtin: FnIsSymbolInterned},
+
+		SymIsNumber:   &Procedure{Builtin: FnIsNumber},
+		SymIsComplex:  &Procedure{Builtin: FnIsComplex},
+		SymIsReal:     &Procedure{Builtin: FnIsReal},
+		SymIsRational: &Procedure{Builtin: FnIsRational},
+		SymIsInteger:  &Procedure{Builtin: FnIsInteger},
+
+		SymIsPair:      &Procedure{Builtin: FnIsPair},
+		SymCons:        &Procedure{Builtin: FnCons},
+		SymCar:         &Procedure{Builtin: FnCar},
+		SymCdr:         &Procedure{Builtin: FnCdr},
+		SymSetCar:      &Procedure{Builtin: FnSetCar},
+		SymSetCdr:      &Procedure{Builtin: FnSetCdr},
+		SymApply:       &Procedure{Builtin: FnApply},
+		SymVector2List: &Procedure{Builtin: FnVector2List},
+		SymString2List: &Procedure{Builtin: FnString2List},
+
+		SymIsList:   &Procedure{Builtin: FnIsList},
+		SymMakeList: &Procedure{Builtin: FnMakeList},
+		SymListSet:  &Procedure{Builtin: FnListSet},
+
+		SymIsVector:     &Procedure{Builtin: FnIsVector},
+		SymMakeVector:   &Procedure{Builtin: FnMakeVector},
+		SymVector:       &Procedure{Builtin: FnVector},
+		SymVectorLength: &Procedure{Builtin: FnVectorLength},
+		SymVectorRef:    &Procedure{Builtin: FnVectorRef},
+		SymVectorSet:    &Procedure{Builtin: FnVectorSet},
+		SymList2Vector:  &Procedure{Builtin: FnList2Vector},
+
+		SymVectorFill:     &Procedure{Builtin: FnVectorFill},
+		SymVectorCopy:     &Procedure{Builtin: FnVectorCopy},
+		SymVectorCopyInto: &Procedure{Builtin: FnVectorCopyInto},
+		SymVectorAppend:   &Procedure{Builtin: FnVectorAppend},
+		SymVector2String:  &Procedure{Builtin: FnVector2String},
+		SymVectorMap:      &Procedure{Builtin: FnVectorMap},
+		SymVectorForEach:  &Procedure{Builtin: FnVectorForEach},
+
+		SymIsBytevector:       &Procedure{Builtin: FnIsBytevector},
+		SymMakeBytevector:     &Procedure{Builtin: FnMakeBytevector},
+		SymBytevector:         &Procedure{Builtin: FnBytevector},
+		SymBytevectorLength:   &Procedure{Builtin: FnBytevectorLength},
+		SymBytevectorU8Ref:    &Procedure{Builtin: FnBytevectorU8Ref},
+		SymBytevectorU8Set:    &Procedure{Builtin: FnBytevectorU8Set},
+		SymBytevectorCopy:     &Procedure{Builtin: FnBytevectorCopy},
+		SymBytevectorCopyInto: &Procedure{Builtin: FnBytevectorCopyInto},
+		SymBytevectorAppend:   &Procedure{Builtin: FnBytevectorAppend},
+		SymUtf82String:        &Procedure{Builtin: FnUtf82String},
+		SymString2Utf8:        &Procedure{Builtin: FnString2Utf8},
+
+		SymMakeHashTable:                &Procedure{Builtin: FnMakeHashTable},
+		SymIsHashTable:                  &Procedure{Builtin: FnIsHashTable},
+		SymHashTableRef:                 &Procedure{Builtin: FnHashTableRef},
+		SymHashTableRefDefault:          &Procedure{Builtin: FnHashTableRefDefault},
+		SymHashTableSet:                 &Procedure{Builtin: FnHashTableSet},
+		SymHashTableDelete:              &Procedure{Builtin: FnHashTableDelete},
+		SymHashTableContains:            &Procedure{Builtin: FnHashTableContains},
+		SymHashTableExists:              &Procedure{Builtin: FnHashTableContains},
+		SymHashTableUpdate:              &Procedure{Builtin: FnHashTableUpdate},
+		SymHashTableUpdateDefault:       &Procedure{Builtin: FnHashTableUpdateDefault},
+		SymHashTableSize:                &Procedure{Builtin: FnHashTableSize},
+		SymHashTableKeys:                &Procedure{Builtin: FnHashTableKeys},
+		SymHashTableValues:              &Procedure{Builtin: FnHashTableValues},
+		SymHashTable2Alist:              &Procedure{Builtin: FnHashTable2Alist},
+		SymHashTableWalk:                &Procedure{Builtin: FnHashTableWalk},
+		SymHashTableFold:                &Procedure{Builtin: FnHashTableFold},
+		SymHashTableClear:               &Procedure{Builtin: FnHashTableClear},
+		SymHashTableCopy:                &Procedure{Builtin: FnHashTableCopy},
+		SymAlist2HashTable:              &Procedure{Builtin: FnAlist2HashTable},
+		SymHashTableEquivalenceFunction: &Procedure{Builtin: FnHashTableEquivalenceFunction},
+		SymHashTableHashFunction:        &Procedure{Builtin: FnHashTableHashFunction},
+		SymHash:                         &Procedure{Builtin: FnHash},
+		SymStringHash:                   &Procedure{Builtin: FnStringHash},
+		SymStringCiHash:                 &Procedure{Builtin: FnStringCiHash},
+		SymHashByIdentity:               &Procedure{Builtin: FnHash},
+
+		SymMakeRecordType:       &Procedure{Builtin: FnMakeRecordType},
+		SymRecordConstructor:    &Procedure{Builtin: FnRecordConstructor},
+		SymRecordPredicate:      &Procedure{Builtin: FnRecordPredicate},
+		SymRecordAccessor:       &Procedure{Builtin: FnRecordAccessor},
+		SymRecordModifier:       &Procedure{Builtin: FnRecordModifier},
+		SymIsRecord:             &Procedure{Builtin: FnIsRecord},
+		SymRecordTypeDescriptor: &Procedure{Builtin: FnRecordTypeDescriptor},
+		SymRecordTypeName:       &Procedure{Builtin: FnRecordTypeName},
+		SymRecordTypeFieldNames: &Procedure{Builtin: FnRecordTypeFieldNames},
+
+		SymIsChar:           &Procedure{Builtin: FnIsChar},
+		SymInteger2Char:     &Procedure{Builtin: FnInteger2Char},
+		SymCharUpcase:       &Procedure{Builtin: FnCharUpcase},
+		SymCharDowncase:     &Procedure{Builtin: FnCharDowncase},
+		SymCharFoldcase:     &Procedure{Builtin: FnCharFoldcase},
+		SymIsCharAlphabetic: &Procedure{Builtin: FnIsCharAlphabetic},
+		SymIsCharNumeric:    &Procedure{Builtin: FnIsCharNumeric},
+		SymIsCharWhitespace: &Procedure{Builtin: FnIsCharWhitespace},
+		SymIsCharUpperCase:  &Procedure{Builtin: FnIsCharUpperCase},
+		SymIsCharLowerCase:  &Procedure{Builtin: FnIsCharLowerCase},
+		SymDigitValue:       &Procedure{Builtin: FnDigitValue},
+
+		SymCharEq:   &Procedure{Builtin: FnCharEq},
+		SymCharLt:   &Procedure{Builtin: FnCharLt},
+		SymCharGt:   &Procedure{Builtin: FnCharGt},
+		SymCharLe:   &Procedure{Builtin: FnCharLe},
+		SymCharGe:   &Procedure{Builtin: FnCharGe},
+		SymCharCiEq: &Procedure{Builtin: FnCharCiEq},
+		SymCharCiLt: &Procedure{Builtin: FnCharCiLt},
+		SymCharCiGt: &Procedure{Builtin: FnCharCiGt},
+		SymCharCiLe: &Procedure{Builtin: FnCharCiLe},
+		SymCharCiGe: &Procedure{Builtin: FnCharCiGe},
+
+		SymIsString:       &Procedure{Builtin: FnIsString},
+		SymMakeString:     &Procedure{Builtin: FnMakeString},
+		SymString:         &Procedure{Builtin: FnString},
+		SymStringLength:   &Procedure{Builtin: FnStringLength},
+		SymStringRef:      &Procedure{Builtin: FnStringRef},
+		SymStringSet:      &Procedure{Builtin: FnStringSet},
+		SymStringDowncase: &Procedure{Builtin: FnStringDowncase},
+		SymStringFoldcase: &Procedure{Builtin: FnStringFoldcase},
+		SymSubstring:      &Procedure{Builtin: FnSubstring},
+		SymStringAppend:   &Procedure{Builtin: FnStringAppend},
+		SymStringEq:       &Procedure{Builtin: FnStringEq},
+		SymSymbol2String:  &Procedure{Builtin: FnSymbol2String},
+		SymNumber2String:  &Procedure{Builtin: FnNumber2String},
+		SymList2String:    &Procedure{Builtin: FnList2String},
+
+		SymStringUpcase:   &Procedure{Builtin: FnStringUpcase},
+		SymStringLt:       &Procedure{Builtin: FnStringLt},
+		SymStringGt:       &Procedure{Builtin: FnStringGt},
+		SymStringLe:       &Procedure{Builtin: FnStringLe},
+		SymStringGe:       &Procedure{Builtin: FnStringGe},
+		SymStringCiEq:     &Procedure{Builtin: FnStringCiEq},
+		SymStringCiLt:     &Procedure{Builtin: FnStringCiLt},
+		SymStringCiGt:     &Procedure{Builtin: FnStringCiGt},
+		SymStringCiLe:     &Procedure{Builtin: FnStringCiLe},
+		SymStringCiGe:     &Procedure{Builtin: FnStringCiGe},
+		SymString2Symbol:  &Procedure{Builtin: FnString2Symbol},
+		SymStringCopy:     &Procedure{Builtin: FnStringCopy},
+		SymStringCopyInto: &Procedure{Builtin: FnStringCopyInto},
+		SymStringFill:     &Procedure{Builtin: FnStringFill},
+		SymString2Vector:  &Procedure{Builtin: FnString2Vector},
+		SymStringMap:      &Procedure{Builtin: FnStringMap},
+		SymStringForEach:  &Procedure{Builtin: FnStringForEach},
+
+		SymIsStringNull:         &Procedure{Builtin: FnIsStringNull},
+		SymStringIndex:          &Procedure{Builtin: FnStringIndex},
+		SymStringIndexRight:     &Procedure{Builtin: FnStringIndexRight},
+		SymStringSkip:           &Procedure{Builtin: FnStringSkip},
+		SymStringSkipRight:      &Procedure{Builtin: FnStringSkipRight},
+		SymStringCount:          &Procedure{Builtin: FnStringCount},
+		SymStringContains:       &Procedure{Builtin: FnStringContains},
+		SymStringContainsRight:  &Procedure{Builtin: FnStringContainsRight},
+		SymIsStringPrefix:       &Procedure{Builtin: FnIsStringPrefix},
+		SymIsStringSuffix:       &Procedure{Builtin: FnIsStringSuffix},
+		SymIsStringPrefixCi:     &Procedure{Builtin: FnIsStringPrefixCi},
+		SymIsStringSuffixCi:     &Procedure{Builtin: FnIsStringSuffixCi},
+		SymStringSearchForward:  &Procedure{Builtin: FnStringSearchForward},
+		SymStringSearchBackward: &Procedure{Builtin: FnStringSearchBackward},
+		SymStringSearchAll:      &Procedure{Builtin: FnStringSearchAll},
+		SymStringTake:           &Procedure{Builtin: FnStringTake},
+		SymStringDrop:           &Procedure{Builtin: FnStringDrop},
+		SymStringTakeRight:      &Procedure{Builtin: FnStringTakeRight},
+		SymStringDropRight:      &Procedure{Builtin: FnStringDropRight},
+		SymStringPad:            &Procedure{Builtin: FnStringPad},
+		SymStringPadRight:       &Procedure{Builtin: FnStringPadRight},
+		SymStringTrim:           &Procedure{Builtin: FnStringTrim},
+		SymStringTrimRight:      &Procedure{Builtin: FnStringTrimRight},
+		SymStringTrimBoth:       &Procedure{Builtin: FnStringTrimBoth},
+		SymStringJoin:           &Procedure{Builtin: FnStringJoin},
+		SymStringSplit:          &Procedure{Builtin: FnStringSplit},
+		SymStringTokenize:       &Procedure{Builtin: FnStringTokenize},
+		SymStringReplace:        &Procedure{Builtin: FnStringReplace},
+		SymStringReverse:        &Procedure{Builtin: FnStringReverse},
+		SymStringCursorStart:    &Procedure{Builtin: FnStringCursorStart},
+		SymStringCursorEnd:      &Procedure{Builtin: FnStringCursorEnd},
+		SymStringCursorNext:     &Procedure{Builtin: FnStringCursorNext},
+		SymStringCursorPrev:     &Procedure{Builtin: FnStringCursorPrev},
+		SymStringCursorForward:  &Procedure{Builtin: FnStringCursorForward},
+		SymStringCursorBack:     &Procedure{Builtin: FnStringCursorBack},
+		SymStringCursorRef:      &Procedure{Builtin: FnStringCursorRef},
+		SymStringCursor2Index:   &Procedure{Builtin: FnStringCursor2Index},
+		SymStringIndex2Cursor:   &Procedure{Builtin: FnStringCursor2Index},
+		SymStringCursorDiff:     &Procedure{Builtin: FnStringCursorDiff},
+		SymStringCursorEq:       &Procedure{Builtin: FnNumEq},
+		SymStringCursorLt:       &Procedure{Builtin: FnLt},
+		SymStringCursorGt:       &Procedure{Builtin: FnGt},
+		SymStringCursorLe:       &Procedure{Builtin: FnLe},
+		SymStringCursorGe:       &Procedure{Builtin: FnGe},
+		SymStringNormalizeNfc:   &Procedure{Builtin: FnStringNormalizeNfc},
+		SymStringNormalizeNfd:   &Procedure{Builtin: FnStringNormalizeNfd},
+		SymStringNormalizeNfkc:  &Procedure{Builtin: FnStringNormalizeNfkc},
+		SymStringNormalizeNfkd:  &Procedure{Builtin: FnStringNormalizeNfkd},
+		SymStringGraphemeCount:  &Procedure{Builtin: FnStringGraphemeCount},
+		SymStringGraphemes:      &Procedure{Builtin: FnStringGraphemes},
+
+		SymRegexp:               &Procedure{Builtin: FnRegexp},
+		SymIsRegexp:             &Procedure{Builtin: FnIsRegexp},
+		SymRegexpQuote:          &Procedure{Builtin: FnRegexpQuote},
+		SymRegexpMatch:          &Procedure{Builtin: FnRegexpMatch},
+		SymRegexpMatchPositions: &Procedure{Builtin: FnRegexpMatchPositions},
+		SymRegexpSearch:         &Procedure{Builtin: FnRegexpSearch},
+		SymIsRegexpMatches:      &Procedure{Builtin: FnIsRegexpMatches},
+		SymRegexpReplace:        &Procedure{Builtin: FnRegexpReplace},
+		SymRegexpReplaceAll:     &Procedure{Builtin: FnRegexpReplaceAll},
+		SymRegexpSplit:          &Procedure{Builtin: FnRegexpSplit},
+
+		SymJsonRead:        &Procedure{Builtin: FnJsonRead},
+		SymJsonReadString:  &Procedure{Builtin: FnJsonReadString},
+		SymJsonWrite:       &Procedure{Builtin: FnJsonWrite},
+		SymJsonWriteString: &Procedure{Builtin: FnJsonWriteString},
+		SymJsonNull:        &Procedure{Builtin: FnJsonNull},
+		SymIsJsonNull:      &Procedure{Builtin: FnIsJsonNull},
+
+		SymIsGoValue:  &Procedure{Builtin: FnIsGoValue},
+		SymGoValueTag: &Procedure{Builtin: FnGoValueTag},
+		SymGoCall:     &Procedure{Builtin: FnGoCall},
+		SymGoField:    &Procedure{Builtin: FnGoField},
+		SymGoMethods:  &Procedure{Builtin: FnGoMethods},
+
+		SymIsPort:             &Procedure{Builtin: FnIsPort},
+		SymCallWithInputFile:  &Procedure{Builtin: FnCallWithInputFile},
+		SymCallWithOutputFile: &Procedure{Builtin: FnCallWithOutputFile},
+		SymOpenInputFile:      &Procedure{Builtin: FnOpenInputFile},
+		SymOpenOutputFile:     &Procedure{Builtin: FnOpenOutputFile},
+		SymCloseInputPort:     &Procedure{Builtin: FnCloseInputPort},
+		SymCloseOutputPort:    &Procedure{Builtin: FnCloseOutputPort},
+		SymRead:               &Procedure{Builtin: FnRead},
+		SymReadChar:           &Procedure{Builtin: FnReadChar},
+		SymPeekChar:           &Procedure{Builtin: FnPeekChar},
+		SymIsEofObject:        &Procedure{Builtin: FnIsEofObject},
+		SymIsCharReady:        &Procedure{Builtin: FnIsCharReady},
+		SymWrite:              &Procedure{Builtin: FnWrite},
+		SymWriteShared:        &Procedure{Builtin: FnWriteShared},
+		SymPrettyPrint:        &Procedure{Builtin: FnPrettyPrint},
+		SymPp:                 &Procedure{Builtin: FnPrettyPrint},
+		SymWriteSimple:        &Procedure{Builtin: FnWriteSimple},
+		SymDisplay:            &Procedure{Builtin: FnDisplay},
+
+		SymIsInputPort:          &Procedure{Builtin: FnIsInputPort},
+		SymIsOutputPort:         &Procedure{Builtin: FnIsOutputPort},
+		SymIsInputPortOpen:      &Procedure{Builtin: FnIsInputPortOpen},
+		SymIsOutputPortOpen:     &Procedure{Builtin: FnIsOutputPortOpen},
+		SymCurrentInputPort:     &Procedure{Builtin: FnCurrentInputPort},
+		SymCurrentOutputPort:    &Procedure{Builtin: FnCurrentOutputPort},
+		SymCurrentErrorPort:     &Procedure{Builtin: FnCurrentErrorPort},
+		SymCallWithPort:         &Procedure{Builtin: FnCallWithPort},
+		SymWithInputFromFile:    &Procedure{Builtin: FnWithInputFromFile},
+		SymWithOutputToFile:     &Procedure{Builtin: FnWithOutputToFile},
+		SymOpenInputString:      &Procedure{Builtin: FnOpenInputString},
+		SymOpenOutputString:     &Procedure{Builtin: FnOpenOutputString},
+		SymGetOutputString:      &Procedure{Builtin: FnGetOutputString},
+		SymOpenInputBytevector:  &Procedure{Builtin: FnOpenInputBytevector},
+		SymOpenOutputBytevector: &Procedure{Builtin: FnOpenOutputBytevector},
+		SymGetOutputBytevector:  &Procedure{Builtin: FnGetOutputBytevector},
+		SymClosePort:            &Procedure{Builtin: FnClosePort},
+		SymFlushOutputPort:      &Procedure{Builtin: FnFlushOutputPort},
+		SymReadLine:             &Procedure{Builtin: FnReadLine},
+		SymReadString:           &Procedure{Builtin: FnReadString},
+		SymReadU8:               &Procedure{Builtin: FnReadU8},
+		SymPeekU8:               &Procedure{Builtin: FnPeekU8},
+		SymIsU8Ready:            &Procedure{Builtin: FnIsU8Ready},
+		SymReadBytevector:       &Procedure{Builtin: FnReadBytevector},
+		SymReadBytevectorInto:   &Procedure{Builtin: FnReadBytevectorInto},
+		SymEofObject:            &Procedure{Builtin: FnEofObject},
+		SymWriteString:          &Procedure{Builtin: FnWriteString},
+		SymWriteU8:              &Procedure{Builtin: FnWriteU8},
+		SymWriteBytevector:      &Procedure{Builtin: FnWriteBytevector},
+
+		SymIsProcedure: &Procedure{Builtin: FnIsProcedure},
+		SymDisassemble: &Procedure{Builtin: FnDisassemble},
+
+		SymGetEnvironmentVariables: &Procedure{
+			Builtin: FnGetEnvironmentVariables,
+		},
+	},
+	nil,
+}
+
+var Top = &Procedure{Macros: map[Symbol]SyntaxRules{}}
+
+func init() {
+	for sym, v := range TopScope.m {
+		if proc, ok := v.(*Procedure); ok && proc.Name == "" {
+			proc.Name = SymbolNames[sym]
+		}
+	}
+}
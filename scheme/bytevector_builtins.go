@@ -0,0 +1,241 @@
+package scheme
+
+import (
+	"errors"
+	"math/big"
+	"unicode/utf8"
+)
+
+func FnIsBytevector(nargs int) error {
+	if nargs != 1 {
+		return errors.New("bytevector? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(Bytevector)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnMakeBytevector(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("make-bytevector takes 1 or 2 arguments")
+	}
+
+	k, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("make-bytevector takes a length as the first argument")
+	}
+	var fill byte
+	if nargs == 2 {
+		if fill, ok = toByte(stack.Pop()); !ok {
+			return errors.New("make-bytevector takes a byte as the fill")
+		}
+	}
+
+	if err := reserve(k, 1); err != nil {
+		return err
+	}
+	b := make([]byte, k)
+	for i := range b {
+		if i%limitInterval == 0 {
+			if err := pollLimits(); err != nil {
+				return err
+			}
+		}
+		b[i] = fill
+	}
+	stack.Push(Bytevector{&b})
+	return nil
+}
+
+func FnBytevector(nargs int) error {
+	b := []byte{}
+	for i := 0; i < nargs; i++ {
+		v, ok := toByte(stack.Pop())
+		if !ok {
+			return errors.New("bytevector takes bytes as arguments")
+		}
+		b = append(b, v)
+	}
+	stack.Push(Bytevector{&b})
+	return nil
+}
+
+func FnBytevectorLength(nargs int) error {
+	if nargs != 1 {
+		return errors.New("bytevector-length takes 1 argument")
+	}
+
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New("bytevector-length takes a bytevector as the argument")
+	}
+	stack.Push(Integer(*big.NewInt(int64(len(*bv.b)))))
+	return nil
+}
+
+func FnBytevectorU8Ref(nargs int) error {
+	if nargs != 2 {
+		return errors.New("bytevector-u8-ref takes 2 arguments")
+	}
+
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"bytevector-u8-ref takes a bytevector as the first argument",
+		)
+	}
+	k, ok := toIndex(stack.Pop())
+	if !ok || k >= len(*bv.b) {
+		return errors.New("bytevector-u8-ref: index out of range")
+	}
+	stack.Push(Integer(*big.NewInt(int64((*bv.b)[k]))))
+	return nil
+}
+
+func FnBytevectorU8Set(nargs int) error {
+	if nargs != 3 {
+		return errors.New("bytevector-u8-set! takes 3 arguments")
+	}
+
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"bytevector-u8-set! takes a bytevector as the first argument",
+		)
+	}
+	k, ok := toIndex(stack.Pop())
+	if !ok || k >= len(*bv.b) {
+		return errors.New("bytevector-u8-set!: index out of range")
+	}
+	b, ok := toByte(stack.Pop())
+	if !ok {
+		return errors.New("bytevector-u8-set! takes a byte as the third argument")
+	}
+
+	(*bv.b)[k] = b
+	stack.Push(bv)
+	return nil
+}
+
+func FnBytevectorCopy(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("bytevector-copy takes 1 to 3 arguments")
+	}
+
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"bytevector-copy takes a bytevector as the first argument",
+		)
+	}
+	start, end, err := popRange("bytevector-copy", nargs, 1, len(*bv.b))
+	if err != nil {
+		return err
+	}
+
+	b := append([]byte{}, (*bv.b)[start:end]...)
+	stack.Push(Bytevector{&b})
+	return nil
+}
+
+func FnBytevectorCopyInto(nargs int) error {
+	if nargs < 3 || nargs > 5 {
+		return errors.New("bytevector-copy! takes 3 to 5 arguments")
+	}
+
+	to, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"bytevector-copy! takes a bytevector as the first argument",
+		)
+	}
+	at, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New(
+			"bytevector-copy! takes an index as the second argument",
+		)
+	}
+	from, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"bytevector-copy! takes a bytevector as the third argument",
+		)
+	}
+	start, end, err := popRange("bytevector-copy!", nargs, 3, len(*from.b))
+	if err != nil {
+		return err
+	}
+
+	if at+end-start > len(*to.b) {
+		return errors.New("bytevector-copy!: index out of range")
+	}
+	copy((*to.b)[at:], (*from.b)[start:end])
+	stack.Push(to)
+	return nil
+}
+
+func FnBytevectorAppend(nargs int) error {
+	n := 0
+	for _, v := range stack[len(stack)-nargs:] {
+		if bv, ok := v.(Bytevector); ok {
+			n += len(*bv.b)
+		}
+	}
+	if err := reserve(n, 1); err != nil {
+		return err
+	}
+
+	b := make([]byte, 0, n)
+	for i := 0; i < nargs; i++ {
+		bv, ok := stack.Pop().(Bytevector)
+		if !ok {
+			return errors.New("bytevector-append takes bytevectors as arguments")
+		}
+		b = append(b, *bv.b...)
+	}
+	stack.Push(Bytevector{&b})
+	return nil
+}
+
+func FnUtf82String(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("utf8->string takes 1 to 3 arguments")
+	}
+
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New("utf8->string takes a bytevector as the first argument")
+	}
+	start, end, err := popRange("utf8->string", nargs, 1, len(*bv.b))
+	if err != nil {
+		return err
+	}
+
+	if !utf8.Valid((*bv.b)[start:end]) {
+		return errors.New("utf8->string: invalid UTF-8")
+	}
+	s := string((*bv.b)[start:end])
+	stack.Push(NewString(s))
+	return nil
+}
+
+func FnString2Utf8(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("string->utf8 takes 1 to 3 arguments")
+	}
+
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string->utf8 takes a string as the first argument")
+	}
+	rs := []rune(str.String())
+	start, end, err := popRange("string->utf8", nargs, 1, len(rs))
+	if err != nil {
+		return err
+	}
+
+	b := []byte(string(rs[start:end]))
+	stack.Push(Bytevector{&b})
+	return nil
+}
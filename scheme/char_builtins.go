@@ -0,0 +1,228 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode"
+)
+
+func FnIsChar(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to char?")
+	}
+	_, ok := stack.Pop().(Char)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnInteger2Char(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to integer->char")
+	}
+	v := stack.Pop()
+	i, ok := v.(Integer)
+	if !ok {
+		return fmt.Errorf("Got non-char to integer->char (%T)", v)
+	}
+	bi := big.Int(i)
+	stack.Push(Char(rune(bi.Int64())))
+	return nil
+}
+
+func FnCharUpcase(nargs int) error {
+	if nargs != 1 {
+		return errors.New("char-upcase takes 1 argument")
+	}
+
+	cv := stack.Pop()
+	c, ok := cv.(Char)
+	if !ok {
+		return errors.New("char-upcase takes a character as the argument")
+	}
+
+	stack.Push(Char(unicode.ToUpper(rune(c))))
+	return nil
+}
+
+func FnCharDowncase(nargs int) error {
+	if nargs != 1 {
+		return errors.New("char-downcase takes 1 argument")
+	}
+
+	cv := stack.Pop()
+	c, ok := cv.(Char)
+	if !ok {
+		return errors.New("char-downcase takes a character as the argument")
+	}
+
+	stack.Push(Char(unicode.ToLower(rune(c))))
+	return nil
+}
+
+// foldRune returns the simple case folding of r, which for most characters is
+// its lower case form.
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+func FnCharFoldcase(nargs int) error {
+	if nargs != 1 {
+		return errors.New("char-foldcase takes 1 argument")
+	}
+
+	c, ok := stack.Pop().(Char)
+	if !ok {
+		return errors.New("char-foldcase takes a character as the argument")
+	}
+
+	stack.Push(Char(foldRune(rune(c))))
+	return nil
+}
+
+// compareChars implements the char comparisons, which hold if accept is true
+// of the difference between each argument and the next.  The -ci variants
+// compare the arguments' case folded forms.
+func compareChars(name string, nargs int, ci bool, accept func(int) bool) error {
+	res := true
+	var last rune
+	for i := 0; i < nargs; i++ {
+		v := stack.Pop()
+		ch, ok := v.(Char)
+		if !ok {
+			return fmt.Errorf("%s takes chars as arguments (got %T)", name, v)
+		}
+		r := rune(ch)
+		if ci {
+			r = foldRune(r)
+		}
+		if i != 0 && !accept(int(last-r)) {
+			res = false
+		}
+		last = r
+	}
+
+	stack.Push(Boolean(res))
+	return nil
+}
+
+func FnCharEq(nargs int) error {
+	return compareChars("char=?", nargs, false,
+		func(c int) bool { return c == 0 })
+}
+
+func FnCharLt(nargs int) error {
+	return compareChars("char<?", nargs, false,
+		func(c int) bool { return c < 0 })
+}
+
+func FnCharGt(nargs int) error {
+	return compareChars("char>?", nargs, false,
+		func(c int) bool { return c > 0 })
+}
+
+func FnCharLe(nargs int) error {
+	return compareChars("char<=?", nargs, false,
+		func(c int) bool { return c <= 0 })
+}
+
+func FnCharGe(nargs int) error {
+	return compareChars("char>=?", nargs, false,
+		func(c int) bool { return c >= 0 })
+}
+
+func FnCharCiEq(nargs int) error {
+	return compareChars("char-ci=?", nargs, true,
+		func(c int) bool { return c == 0 })
+}
+
+func FnCharCiLt(nargs int) error {
+	return compareChars("char-ci<?", nargs, true,
+		func(c int) bool { return c < 0 })
+}
+
+func FnCharCiGt(nargs int) error {
+	return compareChars("char-ci>?", nargs, true,
+		func(c int) bool { return c > 0 })
+}
+
+func FnCharCiLe(nargs int) error {
+	return compareChars("char-ci<=?", nargs, true,
+		func(c int) bool { return c <= 0 })
+}
+
+func FnCharCiGe(nargs int) error {
+	return compareChars("char-ci>=?", nargs, true,
+		func(c int) bool { return c >= 0 })
+}
+
+// charTest implements the char predicates, which take one char.
+func charTest(name string, nargs int, test func(rune) bool) error {
+	if nargs != 1 {
+		return fmt.Errorf("%s takes 1 argument", name)
+	}
+
+	c, ok := stack.Pop().(Char)
+	if !ok {
+		return fmt.Errorf("%s takes a character as the argument", name)
+	}
+	stack.Push(Boolean(test(rune(c))))
+	return nil
+}
+
+func FnIsCharAlphabetic(nargs int) error {
+	return charTest("char-alphabetic?", nargs, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.In(r, unicode.Nl, unicode.Other_Alphabetic)
+	})
+}
+
+// FnIsCharNumeric is true of decimal digits in any script, which are the
+// chars that digit-value knows the value of.
+func FnIsCharNumeric(nargs int) error {
+	return charTest("char-numeric?", nargs, func(r rune) bool {
+		return unicode.Is(unicode.Nd, r)
+	})
+}
+
+func FnIsCharWhitespace(nargs int) error {
+	return charTest("char-whitespace?", nargs, func(r rune) bool {
+		return unicode.Is(unicode.White_Space, r)
+	})
+}
+
+func FnIsCharUpperCase(nargs int) error {
+	return charTest("char-upper-case?", nargs, func(r rune) bool {
+		return unicode.IsUpper(r) || unicode.Is(unicode.Other_Uppercase, r)
+	})
+}
+
+func FnIsCharLowerCase(nargs int) error {
+	return charTest("char-lower-case?", nargs, func(r rune) bool {
+		return unicode.IsLower(r) || unicode.Is(unicode.Other_Lowercase, r)
+	})
+}
+
+// FnDigitValue returns the value of a decimal digit, or #f for other chars.
+// Unicode has its digits in runs of ten from zero, so the value is how far
+// the char is into its run.
+func FnDigitValue(nargs int) error {
+	if nargs != 1 {
+		return errors.New("digit-value takes 1 argument")
+	}
+
+	c, ok := stack.Pop().(Char)
+	if !ok {
+		return errors.New("digit-value takes a character as the argument")
+	}
+	r := rune(c)
+	if !unicode.Is(unicode.Nd, r) {
+		stack.Push(Boolean(false))
+		return nil
+	}
+	n := 0
+	for unicode.Is(unicode.Nd, r-rune(n)-1) {
+		n++
+	}
+	stack.Push(Integer(*big.NewInt(int64(n % 10))))
+	return nil
+}
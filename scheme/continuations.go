@@ -0,0 +1,185 @@
+package scheme
+
+// Continuations are taken from the heap frames that Eval keeps.  Each call to
+// Eval is an evaluation, and a continuation holds a copy of the frames and
+// value stack of the evaluation that captured it, along with the dynamic-wind
+// and exception handler state of the time.  Invoking it inside that
+// evaluation puts the copies back.  Invoking it anywhere else returns a jump
+// error, which carries it out through the builtins in between until it gets
+// back to that evaluation.  If that evaluation has already finished, as it
+// has for a continuation kept from an earlier top-level form, the outermost
+// evaluation takes it up instead, as a top-level prompt would.
+
+// An evaluation is the state of one call to Eval.
+type evaluation struct {
+	frames []frame
+	done   bool
+}
+
+// The evaluations still running, innermost last.
+var evaluations = []*evaluation{}
+
+// A frame is a call waiting for the procedure it called to return: the
+// caller, the instructions it carries on with, and how much of the stack was
+// in use when it made the call.
+type frame struct {
+	p         *Procedure
+	ins       []Ins
+	stack_pos int
+}
+
+// A Continuation is what a procedure made by call/cc returns to: the rest of
+// the evaluation that called call/cc.
+type Continuation struct {
+	ev       *evaluation
+	p        *Procedure
+	ins      []Ins
+	frames   []frame
+	stack    Stack
+	winding  *windFrame
+	handlers []Value
+}
+
+// jump carries the values passed to a continuation out to the evaluation
+// that resumes it.
+type jump struct {
+	k      *Continuation
+	values []Value
+}
+
+func (*jump) Error() string {
+	return "Continuation invoked outside of any evaluation"
+}
+
+// enter is returned by a builtin that finishes by running p, such as apply,
+// for Eval to call p as if the builtin's caller had, instead of the builtin
+// evaluating it on the Go stack.  Continuations captured inside p can then
+// return through the builtin's caller.  p's instructions take the nargs values
+// the builtin left on top of the stack.
+type enter struct {
+	p     *Procedure
+	nargs int
+}
+
+func (*enter) Error() string {
+	return "Builtin tried to enter a procedure outside of Eval"
+}
+
+// A windFrame is a dynamic-wind whose thunk is running.
+type windFrame struct {
+	before, after Value
+	parent        *windFrame
+	depth         int
+}
+
+// winding is the innermost dynamic-wind running, or nil outside of them all.
+var winding *windFrame
+
+// capture returns the continuation of a call that p made with nargs
+// arguments, which are still on the stack.
+func (ev *evaluation) capture(p *Procedure, nargs int) *Procedure {
+	return &Procedure{Cont: &Continuation{
+		ev:       ev,
+		p:        p,
+		ins:      p.Ins,
+		frames:   append([]frame{}, ev.frames...),
+		stack:    append(Stack{}, stack[:len(stack)-nargs]...),
+		winding:  winding,
+		handlers: HandlerStack,
+	}}
+}
+
+// resumes says whether ev is the evaluation that should take up j.
+func (j *jump) resumes(ev *evaluation) bool {
+	return j.k.ev == ev || (j.k.ev.done && ev == evaluations[0])
+}
+
+// resume puts the state saved in j's continuation back into ev, with j's
+// values returned to it, and returns the procedure to carry on running.
+func (ev *evaluation) resume(j *jump) *Procedure {
+	k := j.k
+	callDepth += len(k.frames) - len(ev.frames)
+	ev.frames = append([]frame{}, k.frames...)
+	stack = append(Stack{}, k.stack...)
+	HandlerStack = k.handlers
+
+	pos := len(stack)
+	for i := len(j.values) - 1; i >= 0; i-- {
+		stack.Push(j.values[i])
+	}
+	if len(j.values) != 1 {
+		stack.Push(MultipleValues(len(j.values)))
+	}
+	if !wantsValues(k.ins) {
+		firstValue(pos)
+	}
+
+	k.p.Ins = k.ins
+	return k.p
+}
+
+// rewind runs the after thunks of the dynamic-winds being left and the before
+// thunks of those being entered, to go from winding to to.
+func rewind(to *windFrame) error {
+	common, other := winding, to
+	for windDepth(common) > windDepth(other) {
+		common = common.parent
+	}
+	for windDepth(other) > windDepth(common) {
+		other = other.parent
+	}
+	for common != other {
+		common, other = common.parent, other.parent
+	}
+
+	for winding != common {
+		w := winding
+		winding = w.parent
+		if _, err := Apply(w.after); err != nil {
+			return err
+		}
+	}
+	entering := []*windFrame{}
+	for w := to; w != common; w = w.parent {
+		entering = append(entering, w)
+	}
+	for i := len(entering) - 1; i >= 0; i-- {
+		if _, err := Apply(entering[i].before); err != nil {
+			return err
+		}
+		winding = entering[i]
+	}
+	return nil
+}
+
+func windDepth(w *windFrame) int {
+	if w == nil {
+		return 0
+	}
+	return w.depth
+}
+
+// wantsValues says whether the code that runs after a call, ins, takes every
+// value the call returns rather than just one: either it returns them itself,
+// or it passes them to call-with-values' consumer.
+func wantsValues(ins []Ins) bool {
+	return isTail(ins) ||
+		len(ins) >= 2 && ins[0].op == Imm && ins[1].op == Call && ins[1].nargs == -1
+}
+
+// firstValue leaves only the first of the values a call returned above
+// stack_pos, for code that takes one value.  No values at all are taken as
+// an unspecified one.
+func firstValue(stack_pos int) {
+	n, ok := stack.Top().(MultipleValues)
+	if !ok || len(stack)-int(n)-1 < stack_pos {
+		return
+	}
+	stack.Pop()
+	if n == 0 {
+		stack.Push(Boolean(true))
+		return
+	}
+	first := stack.Top()
+	stack = append(stack[:len(stack)-int(n)], first)
+}
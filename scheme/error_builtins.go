@@ -0,0 +1,222 @@
+package scheme
+
+import (
+	"errors"
+)
+
+// Raised carries an object passed to raise out through the Go call stack,
+// until a guard or the top level stops it.
+type Raised struct {
+	Obj Value
+}
+
+func (r *Raised) Error() string {
+	if e, ok := r.Obj.(*ErrorObject); ok {
+		return e.Error()
+	}
+	return "Uncaught exception: " + ValueString(r.Obj, false)
+}
+
+// The handlers installed by with-exception-handler, innermost last.  A nil
+// entry stands for a guard, which sees everything raised inside it before any
+// handler outside it does.
+var HandlerStack = []Value{}
+
+// ErrorValue returns the object that handlers see for err.
+func ErrorValue(err error) Value {
+	switch err := err.(type) {
+	case *Raised:
+		return err.Obj
+	case *ErrorObject:
+		return err
+	}
+	return &ErrorObject{err.Error(), Empty, GeneralError}
+}
+
+func fileError(err error) error {
+	return &ErrorObject{err.Error(), Empty, FileError}
+}
+
+// pushHandler installs handler, copying the stack so that slices saved by
+// raise aren't overwritten.
+func pushHandler(handler Value) {
+	n := len(HandlerStack)
+	HandlerStack = append(HandlerStack[:n:n], handler)
+}
+
+// raise passes obj to the innermost handler, which runs with only the
+// handlers outside it installed.  For raise-continuable, the handler's result
+// is pushed as the result of the raise.  Otherwise a returning handler passes
+// obj on outwards, and once a guard or the last handler is reached, obj
+// unwinds the Go stack as a *Raised.
+func raise(obj Value, continuable bool) error {
+	saved := HandlerStack
+	defer func() { HandlerStack = saved }()
+
+	for len(HandlerStack) > 0 && HandlerStack[len(HandlerStack)-1] != nil {
+		handler := HandlerStack[len(HandlerStack)-1]
+		HandlerStack = HandlerStack[:len(HandlerStack)-1]
+
+		res, err := Apply(handler, obj)
+		if err != nil {
+			return err
+		}
+		if continuable {
+			stack.Push(res)
+			return nil
+		}
+	}
+	return &Raised{obj}
+}
+
+func FnRaise(nargs int) error {
+	if nargs != 1 {
+		return errors.New("raise takes 1 argument")
+	}
+	return raise(stack.Pop(), false)
+}
+
+func FnRaiseContinuable(nargs int) error {
+	if nargs != 1 {
+		return errors.New("raise-continuable takes 1 argument")
+	}
+	return raise(stack.Pop(), true)
+}
+
+func FnError(nargs int) error {
+	if nargs == 0 {
+		return errors.New("error takes at least 1 argument")
+	}
+
+	msg, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("error takes a string as the first argument")
+	}
+	irritants := []Value{}
+	for i := 1; i < nargs; i++ {
+		irritants = append(irritants, stack.Pop())
+	}
+
+	return raise(&ErrorObject{msg.String(), vec2list(irritants), GeneralError}, false)
+}
+
+func FnWithExceptionHandler(nargs int) error {
+	if nargs != 2 {
+		return errors.New("with-exception-handler takes 2 arguments")
+	}
+
+	handler, ok := stack.Pop().(*Procedure)
+	if !ok {
+		return errors.New(
+			"with-exception-handler takes a procedure as the first argument",
+		)
+	}
+	thunk := stack.Pop()
+
+	saved := HandlerStack
+	pushHandler(handler)
+	res, err := Apply(thunk)
+	switch err.(type) {
+	case nil, *Raised, *LimitError, *jump:
+	default:
+		// Errors from builtins haven't been through the handlers yet
+		err = raise(ErrorValue(err), false)
+	}
+	HandlerStack = saved
+
+	if err != nil {
+		return err
+	}
+	stack.Push(res)
+	return nil
+}
+
+// FnGuard calls thunk, and if anything is raised inside it, returns the
+// result of calling handler on the raised object instead.  It is the runtime
+// half of the guard syntax defined in init.scm.
+func FnGuard(nargs int) error {
+	if nargs != 2 {
+		return errors.New("%guard takes 2 arguments")
+	}
+
+	thunk := stack.Pop()
+	handler := stack.Pop()
+
+	saved := HandlerStack
+	pushHandler(nil)
+	res, err := Apply(thunk)
+	HandlerStack = saved
+
+	switch err.(type) {
+	case nil:
+	case *LimitError, *jump:
+		return err
+	default:
+		if res, err = Apply(handler, ErrorValue(err)); err != nil {
+			return err
+		}
+	}
+	stack.Push(res)
+	return nil
+}
+
+func FnIsErrorObject(nargs int) error {
+	if nargs != 1 {
+		return errors.New("error-object? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(*ErrorObject)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnErrorObjectMessage(nargs int) error {
+	if nargs != 1 {
+		return errors.New("error-object-message takes 1 argument")
+	}
+
+	e, ok := stack.Pop().(*ErrorObject)
+	if !ok {
+		return errors.New(
+			"error-object-message takes an error object as the argument",
+		)
+	}
+	msg := e.Message
+	stack.Push(NewString(msg))
+	return nil
+}
+
+func FnErrorObjectIrritants(nargs int) error {
+	if nargs != 1 {
+		return errors.New("error-object-irritants takes 1 argument")
+	}
+
+	e, ok := stack.Pop().(*ErrorObject)
+	if !ok {
+		return errors.New(
+			"error-object-irritants takes an error object as the argument",
+		)
+	}
+	stack.Push(e.Irritants)
+	return nil
+}
+
+func FnIsFileError(nargs int) error {
+	if nargs != 1 {
+		return errors.New("file-error? takes 1 argument")
+	}
+
+	e, ok := stack.Pop().(*ErrorObject)
+	stack.Push(Boolean(ok && e.Kind == FileError))
+	return nil
+}
+
+func FnIsReadError(nargs int) error {
+	if nargs != 1 {
+		return errors.New("read-error? takes 1 argument")
+	}
+
+	e, ok := stack.Pop().(*ErrorObject)
+	stack.Push(Boolean(ok && e.Kind == ReadError))
+	return nil
+}
@@ -0,0 +1,180 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// GoValuePrinters has, for some tags, what Go values with that tag print as
+// after the tag, as in #<tag ...>.  Values without a printer print what their
+// String method returns, if they have one.
+var GoValuePrinters = map[string]func(interface{}) string{}
+
+// goKey holds the identity of a Go value as a hash table key, which keeps it
+// apart from the keys of Scheme values.
+type goKey struct {
+	id interface{}
+}
+
+// NewGoValue wraps x, tagged with tag, or with its Go type if tag is empty.
+func NewGoValue(x interface{}, tag string) *GoValue {
+	if tag == "" {
+		tag = fmt.Sprintf("%T", x)
+	}
+	return &GoValue{Object: x, Tag: tag}
+}
+
+// identity returns what a wrapper is the same as another if it equals.  For
+// pointers, channels and plain values that is the Go value, so that wrapping
+// it twice gives the same object, but other values are only themselves.
+func (g *GoValue) identity() interface{} {
+	switch reflect.ValueOf(g.Object).Kind() {
+	case reflect.Struct, reflect.Array, reflect.Interface, reflect.Slice,
+		reflect.Map, reflect.Func, reflect.Invalid:
+		return g
+	}
+	return g.Object
+}
+
+func (g *GoValue) describe() string {
+	if print, ok := GoValuePrinters[g.Tag]; ok {
+		return "#<" + g.Tag + " " + print(g.Object) + ">"
+	}
+	if s, ok := g.Object.(fmt.Stringer); ok {
+		return "#<" + g.Tag + " " + s.String() + ">"
+	}
+	return "#<" + g.Tag + ">"
+}
+
+func popGoValue(name string) (*GoValue, error) {
+	g, ok := stack.Pop().(*GoValue)
+	if !ok {
+		return nil, fmt.Errorf("%s takes a Go value as the first argument", name)
+	}
+	return g, nil
+}
+
+func popMemberName(name string) (string, error) {
+	switch v := stack.Pop().(type) {
+	case Symbol:
+		return SymbolNames[v], nil
+	case String:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("%s takes a symbol or string as the name", name)
+}
+
+// FnIsGoValue says whether its argument is a Go value, and if given a tag,
+// whether it has that tag.
+func FnIsGoValue(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("go-value? takes 1 or 2 arguments")
+	}
+
+	g, ok := stack.Pop().(*GoValue)
+	if nargs == 2 {
+		tag, isString := stack.Pop().(String)
+		if !isString {
+			return errors.New("go-value? takes a string as the tag")
+		}
+		ok = ok && g.Tag == tag.String()
+	}
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnGoValueTag(nargs int) error {
+	if nargs != 1 {
+		return errors.New("go-value-tag takes 1 argument")
+	}
+
+	g, err := popGoValue("go-value-tag")
+	if err != nil {
+		return err
+	}
+	stack.Push(NewString(g.Tag))
+	return nil
+}
+
+// FnGoCall calls the named method of a Go value with the rest of the
+// arguments, which are converted as for any Go function.
+func FnGoCall(nargs int) error {
+	if nargs < 2 {
+		return errors.New("go-call takes at least 2 arguments")
+	}
+
+	g, err := popGoValue("go-call")
+	if err != nil {
+		return err
+	}
+	name, err := popMemberName("go-call")
+	if err != nil {
+		return err
+	}
+	method := reflect.ValueOf(g.Object).MethodByName(name)
+	if !method.IsValid() {
+		return fmt.Errorf("go-call: %s has no method %s", g.Tag, name)
+	}
+	proc, err := GoProcedure(g.Tag+"."+name, method.Interface())
+	if err != nil {
+		return err
+	}
+	return proc.Builtin(nargs - 2)
+}
+
+// FnGoField returns the named field of a Go struct, or of what a pointer
+// points to.
+func FnGoField(nargs int) error {
+	if nargs != 2 {
+		return errors.New("go-field takes 2 arguments")
+	}
+
+	g, err := popGoValue("go-field")
+	if err != nil {
+		return err
+	}
+	name, err := popMemberName("go-field")
+	if err != nil {
+		return err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(g.Object))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("go-field: %s is not a struct", g.Tag)
+	}
+	field := rv.FieldByName(name)
+	if !field.IsValid() || !field.CanInterface() {
+		return fmt.Errorf("go-field: %s has no exported field %s", g.Tag, name)
+	}
+	v, err := ToValue(field.Interface())
+	if err != nil {
+		return err
+	}
+	stack.Push(v)
+	return nil
+}
+
+// FnGoMethods lists the names of the methods go-call can call on a Go value.
+func FnGoMethods(nargs int) error {
+	if nargs != 1 {
+		return errors.New("go-methods takes 1 argument")
+	}
+
+	g, err := popGoValue("go-methods")
+	if err != nil {
+		return err
+	}
+	t := reflect.TypeOf(g.Object)
+	names := []string{}
+	for i := 0; t != nil && i < t.NumMethod(); i++ {
+		names = append(names, t.Method(i).Name)
+	}
+	sort.Strings(names)
+	res := make([]Value, len(names))
+	for i, name := range names {
+		res[i] = NewString(name)
+	}
+	stack.Push(vec2list(res))
+	return nil
+}
@@ -1,18 +1,31 @@
-package main
+package scheme
 
 import (
 	"errors"
 	"fmt"
 )
 
+// literal returns the constant v as a literal.  Strings are made immutable,
+// as R7RS has literals be, and copied first unless they already are, since v
+// may be data the program still holds, as it is for eval of a quote form.
+// Pairs and vectors are returned as they are, as quote must give back its
+// datum itself, so only strings read from source within them are immutable.
+func literal(v Value) Value {
+	if s, ok := v.(String); ok && !s.immutable {
+		rs := append([]rune{}, *s.r...)
+		return String{r: &rs, immutable: true}
+	}
+	return v
+}
+
 func (p *Procedure) Gen(v Value) error {
 	switch v.(type) {
-	case Vector:
-		panic("Vector macros not yet implemented")
-	case Boolean, String, Char, Integer, Rational:
-		p.Ins = append(p.Ins, Ins{Imm, v, 0})
+	case Boolean, String, Char, Integer, Rational, Vector, Bytevector:
+		p.Ins = append(p.Ins, Ins{Imm, literal(v), 0})
 	case Symbol, Scoped:
 		p.Ins = append(p.Ins, Ins{GetVar, v, 0})
+	case *Procedure: // Only put here by generated code, never read
+		p.Ins = append(p.Ins, Ins{Imm, v, 0})
 	case *Pair:
 		args, err := list2vec(v.(*Pair))
 		if err != nil {
@@ -102,10 +115,11 @@ func (p *Procedure) Gen(v Value) error {
 						lambda.Macros[k] = v
 					}
 
-					for _, expr := range args[2:] {
-						if err := lambda.Gen(Unscope(expr)); err != nil {
-							return err
-						}
+					if err := lambda.GenBody(args[2:]); err != nil {
+						return err
+					}
+					if sym, ok := Unscope(dest).(Symbol); ok {
+						lambda.Name = SymbolNames[sym]
 					}
 
 					p.Ins = append(p.Ins, Ins{Lambda, lambda, 0})
@@ -117,6 +131,13 @@ func (p *Procedure) Gen(v Value) error {
 					if err := p.Gen(args[2]); err != nil {
 						return err
 					}
+					// Name a lambda after the variable it's defined as
+					last := &p.Ins[len(p.Ins)-1]
+					if lambda, ok := last.imm.(Procedure); ok && last.op == Lambda &&
+						lambda.Name == "" {
+						lambda.Name = SymbolNames[args[1].(Symbol)]
+						last.imm = lambda
+					}
 					p.Ins = append(p.Ins, Ins{Define, args[1], 1})
 				default:
 					return fmt.Errorf(
@@ -124,6 +145,52 @@ func (p *Procedure) Gen(v Value) error {
 					)
 				}
 				return nil
+			case SymDefineValues:
+				if len(args) != 3 {
+					return errors.New("define-values takes 2 args")
+				}
+
+				// Each name is bound first, and then set from an uninterned
+				// temporary of the same name.  That is (call-with-values
+				// (lambda () expr) (lambda (a' b' . c') (set! a a') ...))
+				var params Value = Empty
+				tail := &params
+				body := []Value{}
+				for cur := Unscope(args[1]); cur != Empty; {
+					name := cur
+					pair, isPair := cur.(*Pair)
+					if isPair {
+						name = *pair.Car
+					}
+					sym, ok := name.(Symbol)
+					if !ok {
+						return errors.New("define-values takes symbols to bind")
+					}
+					var temp Value = Uninterned(SymbolNames[sym])
+					body = append(body, vec2list([]Value{SymSet, sym, temp}))
+					p.Ins = append(p.Ins,
+						Ins{Imm, Boolean(false), 0},
+						Ins{Define, sym, 1},
+						Ins{Pop, nil, 0},
+					)
+
+					if !isPair { // Dotted rest
+						*tail = temp
+						break
+					}
+					var next Value = Empty
+					*tail = &Pair{&temp, &next}
+					tail, cur = &next, *pair.Cdr
+				}
+				if len(body) == 0 {
+					body = append(body, Boolean(false))
+				}
+
+				producer := vec2list([]Value{SymLambda, Empty, args[2]})
+				consumer := vec2list(append([]Value{SymLambda, params}, body...))
+				return p.Gen(vec2list([]Value{SymCallWithValues, producer, consumer}))
+			case SymDefineRecordType:
+				return p.genDefineRecordType(args)
 			case SymLambda:
 				if len(args) < 3 {
 					return errors.New("lambda requires at least one statement")
@@ -138,52 +205,51 @@ func (p *Procedure) Gen(v Value) error {
 					lambda.Macros[k] = v
 				}
 
-				for _, expr := range args[2:] {
-					if err := lambda.Gen(Unscope(expr)); err != nil {
-						return err
-					}
+				if err := lambda.GenBody(args[2:]); err != nil {
+					return err
 				}
 				p.Ins = append(p.Ins, Ins{Lambda, lambda, 0})
 				return nil
 			case SymIf:
-				lt := Procedure{
-					Args:   p.Args,
-					Ins:    []Ins{},
-					Macros: map[Symbol]SyntaxRules{},
-				}
-				for k, v := range p.Macros {
-					lt.Macros[k] = v
-				}
-				lf := lt
-				lf.Macros = map[Symbol]SyntaxRules{}
-				for k, v := range p.Macros {
-					lf.Macros[k] = v
-				}
-
-				if err := lt.Gen(args[2]); err != nil {
-					return err
-				}
 				if len(args) > 4 {
 					return errors.New("Too many args to if")
-				} else if len(args) == 4 {
-					if err := lf.Gen(args[3]); err != nil {
-						return err
-					}
-					p.Ins = append(p.Ins, Ins{Imm, lf, 0})
 				} else if len(args) < 3 {
 					return errors.New("Too few args to if")
 				}
-				p.Ins = append(p.Ins, Ins{Imm, lt, 0})
+
+				// test JUMP-IF-FALSE(else) then JUMP(end) else
 				if err := p.Gen(args[1]); err != nil {
 					return err
 				}
-				p.Ins = append(p.Ins, Ins{If, nil, len(args) - 1})
+				test := len(p.Ins)
+				p.Ins = append(p.Ins, Ins{JumpIfFalse, nil, 0})
+
+				if err := p.Gen(args[2]); err != nil {
+					return err
+				}
+				then := len(p.Ins)
+				p.Ins = append(p.Ins, Ins{Jump, nil, 0})
+
+				if len(args) == 4 {
+					if err := p.Gen(args[3]); err != nil {
+						return err
+					}
+				} else {
+					p.Ins = append(p.Ins, Ins{Imm, Boolean(false), 0})
+				}
+
+				p.Ins[test].nargs = then - test
+				p.Ins[then].nargs = len(p.Ins) - then - 1
 				return nil
 			case Quote:
 				if len(args) != 2 {
 					return errors.New("Wrong number of args to quote")
 				}
-				p.Ins = append(p.Ins, Ins{Imm, Unscope(args[1]), 0})
+				datum := args[1]
+				if hasScopes(datum) { // Only copy what macros have made
+					datum = Unscope(datum)
+				}
+				p.Ins = append(p.Ins, Ins{Imm, literal(datum), 0})
 				return nil
 
 			// These are for the implementation of (hygenic) macros
@@ -230,7 +296,7 @@ func (p *Procedure) Gen(v Value) error {
 				p.Ins = append(p.Ins, Ins{Define, name, 1})
 				return nil
 			case SymLetrecSyntax, SymLetSyntax:
-				if len(args) != 3 {
+				if len(args) < 3 {
 					return errors.New("Wrong number of args to letrec-syntax")
 				}
 
@@ -314,7 +380,7 @@ func (p *Procedure) Gen(v Value) error {
 							0,
 						})
 
-						lambda.Ins = append(lambda.Ins, Ins{Lambda, lambda, 0})
+						lambda.Ins = append(lambda.Ins, Ins{Lambda, mlambda, 0})
 						lambda.Ins = append(lambda.Ins, Ins{Call, nil, 0})
 						lambda.Ins = append(lambda.Ins, Ins{
 							Define,
@@ -322,10 +388,13 @@ func (p *Procedure) Gen(v Value) error {
 							1,
 						})
 					}
+					for i := range names {
+						lambda.Macros[names[i]] = rules[i]
+					}
 				}
 
-				if err := lambda.Gen(args[2]); err != nil {
-					return nil
+				if err := lambda.GenBody(args[2:]); err != nil {
+					return err
 				}
 
 				p.Ins = append(p.Ins, Ins{Lambda, lambda, 0})
@@ -344,3 +413,99 @@ func (p *Procedure) Gen(v Value) error {
 	}
 	return nil
 }
+
+// GenBody generates code for the body of a lambda, discarding the value of
+// each expression but the last.
+func (p *Procedure) GenBody(body []Value) error {
+	for i, expr := range body {
+		if i != 0 {
+			p.Ins = append(p.Ins, Ins{Pop, nil, 0})
+		}
+		if err := p.Gen(Unscope(expr)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genDefineRecordType generates (define-record-type <type> (ctor field ...)
+// pred (field accessor [modifier]) ...) as a define of each name, from the
+// record procedures.  The constructor may also be a bare name, taking every
+// field, and either it or the predicate may be #f to leave it out.  The record
+// procedures are called as values rather than by name, so that local bindings
+// of those names don't change what the definitions mean.
+func (p *Procedure) genDefineRecordType(args []Value) error {
+	if len(args) < 3 {
+		return errors.New("define-record-type takes a type, constructor " +
+			"and predicate")
+	}
+	for i := range args {
+		args[i] = Unscope(args[i])
+	}
+
+	typeName, ok := args[1].(Symbol)
+	if !ok {
+		return errors.New("define-record-type takes a symbol as the type name")
+	}
+	quote := func(v Value) Value { return vec2list([]Value{Quote, v}) }
+	defs := [][2]Value{}
+	fields := []Value{}
+	for _, spec := range args[4:] {
+		switch spec := spec.(type) {
+		case Symbol:
+			fields = append(fields, spec)
+		case *Pair:
+			vec, err := list2vec(spec)
+			if err != nil {
+				return err
+			}
+			if len(vec) < 1 || len(vec) > 3 {
+				return errors.New("define-record-type fields are (field " +
+					"accessor [modifier])")
+			}
+			fields = append(fields, vec[0])
+			procs := []Value{
+				BaseScope[SymRecordAccessor], BaseScope[SymRecordModifier],
+			}
+			for i, name := range vec[1:] {
+				defs = append(defs, [2]Value{name, vec2list([]Value{
+					procs[i], typeName, quote(vec[0]),
+				})})
+			}
+		default:
+			return errors.New("define-record-type takes lists as field specs")
+		}
+	}
+
+	switch ctor := args[2].(type) {
+	case Symbol:
+		defs = append([][2]Value{{ctor, vec2list([]Value{
+			BaseScope[SymRecordConstructor], typeName,
+		})}}, defs...)
+	case *Pair:
+		if ctor == Empty {
+			return errors.New("define-record-type constructor needs a name")
+		}
+		defs = append([][2]Value{{*ctor.Car, vec2list([]Value{
+			BaseScope[SymRecordConstructor], typeName, quote(*ctor.Cdr),
+		})}}, defs...)
+	}
+	if pred, ok := args[3].(Symbol); ok {
+		defs = append([][2]Value{{pred, vec2list([]Value{
+			BaseScope[SymRecordPredicate], typeName,
+		})}}, defs...)
+	}
+	defs = append([][2]Value{{typeName, vec2list([]Value{
+		BaseScope[SymMakeRecordType], quote(typeName), quote(vec2list(fields)),
+	})}}, defs...)
+
+	for i, def := range defs {
+		if i != 0 {
+			p.Ins = append(p.Ins, Ins{Pop, nil, 0})
+		}
+		if err := p.Gen(vec2list([]Value{SymDefine, def[0], def[1]})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,772 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type hashKind uint8
+
+const (
+	hashEqv hashKind = iota // For eq? as well, which is the same procedure
+	hashEqual
+	hashString
+	hashStringCi
+	hashCustom // Keys are bucketed by Hash and compared with Equiv
+)
+
+type hashEntry struct {
+	key, value Value
+}
+
+type builtinEquiv struct {
+	kind hashKind
+	hash Value
+}
+
+// The builtin equivalences that tables handle without calling them, with the
+// hash procedure that goes with each.
+var builtinEquivs = map[*Procedure]builtinEquiv{}
+
+// The equivalence for tables made without one
+var defaultEquiv Value
+
+func init() {
+	for _, e := range []struct {
+		equiv, hash Symbol
+		kind        hashKind
+	}{
+		{SymEq, SymHashByIdentity, hashEqv},
+		{SymEqv, SymHashByIdentity, hashEqv},
+		{SymEqual, SymHash, hashEqual},
+		{SymStringEq, SymStringHash, hashString},
+		{SymStringCiEq, SymStringCiHash, hashStringCi},
+	} {
+		builtinEquivs[TopScope.m[e.equiv].(*Procedure)] = builtinEquiv{
+			e.kind, TopScope.m[e.hash],
+		}
+	}
+	defaultEquiv = TopScope.m[SymEqual]
+}
+
+// NewHashTable returns an empty table comparing keys with equiv.  The builtin
+// equivalences don't need hash, and ignore it if given.
+func NewHashTable(equiv, hash Value) (*HashTable, error) {
+	proc, ok := equiv.(*Procedure)
+	if !ok {
+		return nil, errors.New(
+			"make-hash-table takes a procedure as the equivalence",
+		)
+	}
+
+	kind := hashCustom
+	if builtin, ok := builtinEquivs[proc]; ok {
+		kind, hash = builtin.kind, builtin.hash
+	} else if _, ok := hash.(*Procedure); !ok {
+		return nil, errors.New(
+			"make-hash-table needs a hash procedure for this equivalence",
+		)
+	}
+
+	return &HashTable{
+		Equiv:   equiv,
+		Hash:    hash,
+		kind:    kind,
+		entries: map[interface{}][]*hashEntry{},
+	}, nil
+}
+
+// eqvKey returns a Go value that is == for keys that are eqv?.
+func eqvKey(k Value) (interface{}, error) {
+	switch k := k.(type) {
+	case Integer:
+		i := big.Int(k)
+		return "i" + i.String(), nil
+	case Rational:
+		r := big.Rat(k)
+		return "r" + r.RatString(), nil
+	case String:
+		return k.r, nil
+	case *GoValue:
+		return goKey{k.identity()}, nil
+	}
+	if !reflect.TypeOf(k).Comparable() {
+		return nil, fmt.Errorf("Cannot use %T as a hash table key", k)
+	}
+	return k, nil
+}
+
+// equalKeyLimit is how many pairs and vector items equalKey looks at.  Keys
+// that go on past it, as cyclic ones always do, are keyed on the part it saw.
+const equalKeyLimit = 10000
+
+// partialKey is the key of a value that equalKey only saw part of.  Values
+// that are keyed the same needn't be equal?, so the table compares them.
+type partialKey string
+
+// equalKey returns a string that is the same for keys that are equal?, and
+// false if it couldn't look at the whole of k.
+func equalKey(k Value) (string, bool) {
+	var sb strings.Builder
+	budget := equalKeyLimit
+	writeEqualKey(&sb, k, &budget)
+	return sb.String(), budget >= 0
+}
+
+// writeEqualKey writes k's key, taking one from budget for each pair and
+// vector item, and stopping once it runs out.
+func writeEqualKey(sb *strings.Builder, k Value, budget *int) {
+	switch k := k.(type) {
+	case *Pair:
+		if k == Empty {
+			sb.WriteString("()")
+			return
+		}
+		if *budget--; *budget < 0 {
+			sb.WriteString("...")
+			return
+		}
+		sb.WriteString("(")
+		writeEqualKey(sb, *k.Car, budget)
+		sb.WriteString(" . ")
+		writeEqualKey(sb, *k.Cdr, budget)
+		sb.WriteString(")")
+	case Vector:
+		sb.WriteString("#(")
+		for _, item := range *k.v {
+			if *budget--; *budget < 0 {
+				sb.WriteString("...")
+				break
+			}
+			writeEqualKey(sb, item, budget)
+			sb.WriteString(" ")
+		}
+		sb.WriteString(")")
+	case String:
+		sb.WriteString(strconv.Quote(k.String()))
+	case Bytevector:
+		sb.WriteString("#u8" + strconv.Quote(string(*k.b)))
+	case Symbol:
+		fmt.Fprintf(sb, "'%d", k)
+	case Integer, Rational, Boolean, Char, Eof, JSONNull:
+		key, _ := eqvKey(k)
+		fmt.Fprintf(sb, "%T:%v", k, key)
+	case InputPort:
+		fmt.Fprintf(sb, "<input %p>", k.PortState)
+	case OutputPort:
+		fmt.Fprintf(sb, "<output %p>", k.PortState)
+	case *GoValue:
+		switch id := k.identity(); reflect.ValueOf(id).Kind() {
+		case reflect.Ptr, reflect.Chan, reflect.Map, reflect.Func,
+			reflect.UnsafePointer:
+			fmt.Fprintf(sb, "<go %T %p>", id, id)
+		default:
+			fmt.Fprintf(sb, "<go %T %#v>", id, id)
+		}
+	default:
+		if reflect.ValueOf(k).Kind() == reflect.Ptr {
+			fmt.Fprintf(sb, "<%T %p>", k, k)
+		} else {
+			fmt.Fprintf(sb, "<%T %v>", k, k)
+		}
+	}
+}
+
+// key returns the Go value that k is stored under.
+func (h *HashTable) key(k Value) (interface{}, error) {
+	switch h.kind {
+	case hashEqv:
+		return eqvKey(k)
+	case hashEqual:
+		key, whole := equalKey(k)
+		if !whole {
+			return partialKey(key), nil
+		}
+		return key, nil
+	case hashString, hashStringCi:
+		s, ok := k.(String)
+		if !ok {
+			return nil, errors.New("String hash tables take strings as keys")
+		}
+		if h.kind == hashStringCi {
+			return strings.Map(foldRune, s.String()), nil
+		}
+		return s.String(), nil
+	}
+
+	res, err := Apply(h.Hash, k)
+	if err != nil {
+		return nil, err
+	}
+	key, _ := equalKey(res)
+	return key, nil
+}
+
+// lookup returns the Go key that k is stored under, and k's index in that
+// bucket, or -1 if k isn't in the table.
+func (h *HashTable) lookup(k Value) (interface{}, int, error) {
+	key, err := h.key(k)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	_, partial := key.(partialKey)
+	for i, e := range h.entries[key] {
+		if partial {
+			if IsEqual(e.key, k) {
+				return key, i, nil
+			}
+			continue
+		}
+		if h.kind != hashCustom {
+			return key, i, nil
+		}
+		res, err := Apply(h.Equiv, e.key, k)
+		if err != nil {
+			return nil, -1, err
+		}
+		if res != Boolean(false) {
+			return key, i, nil
+		}
+	}
+	return key, -1, nil
+}
+
+func (h *HashTable) Ref(k Value) (Value, bool, error) {
+	key, i, err := h.lookup(k)
+	if err != nil || i < 0 {
+		return nil, false, err
+	}
+	return h.entries[key][i].value, true, nil
+}
+
+func (h *HashTable) Set(k, v Value) error {
+	key, i, err := h.lookup(k)
+	if err != nil {
+		return err
+	}
+
+	if i >= 0 {
+		h.entries[key][i].value = v
+	} else {
+		h.entries[key] = append(h.entries[key], &hashEntry{k, v})
+		h.size++
+	}
+	return nil
+}
+
+func (h *HashTable) Delete(k Value) (bool, error) {
+	key, i, err := h.lookup(k)
+	if err != nil || i < 0 {
+		return false, err
+	}
+
+	bucket := h.entries[key]
+	if len(bucket) == 1 {
+		delete(h.entries, key)
+	} else {
+		h.entries[key] = append(bucket[:i:i], bucket[i+1:]...)
+	}
+	h.size--
+	return true, nil
+}
+
+func (h *HashTable) Size() int {
+	return h.size
+}
+
+// Entries returns a snapshot of the table's entries, in no particular order,
+// which stays valid if the table is changed while walking it.
+func (h *HashTable) Entries() []hashEntry {
+	res := make([]hashEntry, 0, h.size)
+	for _, bucket := range h.entries {
+		for _, e := range bucket {
+			res = append(res, *e)
+		}
+	}
+	return res
+}
+
+func popHashTable(name string) (*HashTable, error) {
+	h, ok := stack.Pop().(*HashTable)
+	if !ok {
+		return nil, fmt.Errorf("%s takes a hash table as the first argument", name)
+	}
+	return h, nil
+}
+
+func FnMakeHashTable(nargs int) error {
+	equiv, hash := defaultEquiv, Value(nil)
+	if nargs >= 1 {
+		equiv = stack.Pop()
+	}
+	if nargs >= 2 {
+		hash = stack.Pop()
+	}
+	for i := 2; i < nargs; i++ { // Size hints and the like are ignored
+		stack.Pop()
+	}
+
+	h, err := NewHashTable(equiv, hash)
+	if err != nil {
+		return err
+	}
+	stack.Push(h)
+	return nil
+}
+
+func FnIsHashTable(nargs int) error {
+	if nargs != 1 {
+		return errors.New("hash-table? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(*HashTable)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnHashTableRef(nargs int) error {
+	if nargs < 2 || nargs > 4 {
+		return errors.New("hash-table-ref takes 2 to 4 arguments")
+	}
+
+	h, err := popHashTable("hash-table-ref")
+	if err != nil {
+		return err
+	}
+	k := stack.Pop()
+	var fail, succeed Value
+	if nargs >= 3 {
+		fail = stack.Pop()
+	}
+	if nargs == 4 {
+		succeed = stack.Pop()
+	}
+
+	v, found, err := h.Ref(k)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !found && fail == nil:
+		return fmt.Errorf("hash-table-ref: no value for key %s",
+			ValueString(k, false))
+	case !found:
+		v, err = Apply(fail)
+	case succeed != nil:
+		v, err = Apply(succeed, v)
+	}
+	if err != nil {
+		return err
+	}
+	stack.Push(v)
+	return nil
+}
+
+func FnHashTableRefDefault(nargs int) error {
+	if nargs != 3 {
+		return errors.New("hash-table-ref/default takes 3 arguments")
+	}
+
+	h, err := popHashTable("hash-table-ref/default")
+	if err != nil {
+		return err
+	}
+	k, def := stack.Pop(), stack.Pop()
+
+	v, found, err := h.Ref(k)
+	if err != nil {
+		return err
+	}
+	if !found {
+		v = def
+	}
+	stack.Push(v)
+	return nil
+}
+
+func FnHashTableSet(nargs int) error {
+	if nargs < 3 || nargs%2 != 1 {
+		return errors.New("hash-table-set! takes a table and keys with values")
+	}
+
+	h, err := popHashTable("hash-table-set!")
+	if err != nil {
+		return err
+	}
+	for i := 1; i < nargs; i += 2 {
+		k, v := stack.Pop(), stack.Pop()
+		if err := h.Set(k, v); err != nil {
+			return err
+		}
+	}
+	stack.Push(h)
+	return nil
+}
+
+func FnHashTableDelete(nargs int) error {
+	if nargs < 1 {
+		return errors.New("hash-table-delete! takes at least 1 argument")
+	}
+
+	h, err := popHashTable("hash-table-delete!")
+	if err != nil {
+		return err
+	}
+	count := 0
+	for i := 1; i < nargs; i++ {
+		deleted, err := h.Delete(stack.Pop())
+		if err != nil {
+			return err
+		}
+		if deleted {
+			count++
+		}
+	}
+	stack.Push(Integer(*big.NewInt(int64(count))))
+	return nil
+}
+
+func FnHashTableContains(nargs int) error {
+	if nargs != 2 {
+		return errors.New("hash-table-contains? takes 2 arguments")
+	}
+
+	h, err := popHashTable("hash-table-contains?")
+	if err != nil {
+		return err
+	}
+	_, found, err := h.Ref(stack.Pop())
+	if err != nil {
+		return err
+	}
+	stack.Push(Boolean(found))
+	return nil
+}
+
+func FnHashTableUpdate(nargs int) error {
+	if nargs < 3 || nargs > 5 {
+		return errors.New("hash-table-update! takes 3 to 5 arguments")
+	}
+
+	h, err := popHashTable("hash-table-update!")
+	if err != nil {
+		return err
+	}
+	k, updater := stack.Pop(), stack.Pop()
+	var fail, succeed Value
+	if nargs >= 4 {
+		fail = stack.Pop()
+	}
+	if nargs == 5 {
+		succeed = stack.Pop()
+	}
+
+	v, found, err := h.Ref(k)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !found && fail == nil:
+		return fmt.Errorf("hash-table-update!: no value for key %s",
+			ValueString(k, false))
+	case !found:
+		v, err = Apply(fail)
+	case succeed != nil:
+		v, err = Apply(succeed, v)
+	}
+	if err != nil {
+		return err
+	}
+
+	if v, err = Apply(updater, v); err != nil {
+		return err
+	}
+	if err := h.Set(k, v); err != nil {
+		return err
+	}
+	stack.Push(v)
+	return nil
+}
+
+func FnHashTableUpdateDefault(nargs int) error {
+	if nargs != 4 {
+		return errors.New("hash-table-update!/default takes 4 arguments")
+	}
+
+	h, err := popHashTable("hash-table-update!/default")
+	if err != nil {
+		return err
+	}
+	k, updater, def := stack.Pop(), stack.Pop(), stack.Pop()
+
+	v, found, err := h.Ref(k)
+	if err != nil {
+		return err
+	}
+	if !found {
+		v = def
+	}
+
+	if v, err = Apply(updater, v); err != nil {
+		return err
+	}
+	if err := h.Set(k, v); err != nil {
+		return err
+	}
+	stack.Push(v)
+	return nil
+}
+
+func FnHashTableSize(nargs int) error {
+	if nargs != 1 {
+		return errors.New("hash-table-size takes 1 argument")
+	}
+
+	h, err := popHashTable("hash-table-size")
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*big.NewInt(int64(h.Size()))))
+	return nil
+}
+
+// hashTableList pushes a list made from each of the table's entries.
+func hashTableList(name string, nargs int, f func(hashEntry) Value) error {
+	if nargs != 1 {
+		return fmt.Errorf("%s takes 1 argument", name)
+	}
+
+	h, err := popHashTable(name)
+	if err != nil {
+		return err
+	}
+	res := []Value{}
+	for _, e := range h.Entries() {
+		res = append(res, f(e))
+	}
+	stack.Push(vec2list(res))
+	return nil
+}
+
+func FnHashTableKeys(nargs int) error {
+	return hashTableList("hash-table-keys", nargs,
+		func(e hashEntry) Value { return e.key })
+}
+
+func FnHashTableValues(nargs int) error {
+	return hashTableList("hash-table-values", nargs,
+		func(e hashEntry) Value { return e.value })
+}
+
+func FnHashTable2Alist(nargs int) error {
+	return hashTableList("hash-table->alist", nargs,
+		func(e hashEntry) Value { return &Pair{&e.key, &e.value} })
+}
+
+func FnHashTableWalk(nargs int) error {
+	if nargs != 2 {
+		return errors.New("hash-table-walk takes 2 arguments")
+	}
+
+	h, err := popHashTable("hash-table-walk")
+	if err != nil {
+		return err
+	}
+	proc := stack.Pop()
+	for _, e := range h.Entries() {
+		if _, err := Apply(proc, e.key, e.value); err != nil {
+			return err
+		}
+	}
+	stack.Push(h)
+	return nil
+}
+
+// FnHashTableFold takes its arguments in SRFI 69's order, table kons knil, or
+// SRFI 125's, kons knil table.
+func FnHashTableFold(nargs int) error {
+	if nargs != 3 {
+		return errors.New("hash-table-fold takes 3 arguments")
+	}
+
+	args := []Value{stack.Pop(), stack.Pop(), stack.Pop()}
+	h, ok := args[0].(*HashTable)
+	kons, acc := args[1], args[2]
+	if !ok {
+		h, ok = args[2].(*HashTable)
+		kons, acc = args[0], args[1]
+	}
+	if !ok {
+		return errors.New("hash-table-fold takes a hash table")
+	}
+
+	var err error
+	for _, e := range h.Entries() {
+		if acc, err = Apply(kons, e.key, e.value, acc); err != nil {
+			return err
+		}
+	}
+	stack.Push(acc)
+	return nil
+}
+
+func FnHashTableClear(nargs int) error {
+	if nargs != 1 {
+		return errors.New("hash-table-clear! takes 1 argument")
+	}
+
+	h, err := popHashTable("hash-table-clear!")
+	if err != nil {
+		return err
+	}
+	h.entries = map[interface{}][]*hashEntry{}
+	h.size = 0
+	stack.Push(h)
+	return nil
+}
+
+func FnHashTableCopy(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("hash-table-copy takes 1 or 2 arguments")
+	}
+
+	h, err := popHashTable("hash-table-copy")
+	if err != nil {
+		return err
+	}
+	if nargs == 2 { // Every table is mutable
+		stack.Pop()
+	}
+
+	cp := *h
+	cp.entries = map[interface{}][]*hashEntry{}
+	for key, bucket := range h.entries {
+		for _, e := range bucket {
+			e := *e
+			cp.entries[key] = append(cp.entries[key], &e)
+		}
+	}
+	stack.Push(&cp)
+	return nil
+}
+
+func FnAlist2HashTable(nargs int) error {
+	if nargs < 1 {
+		return errors.New("alist->hash-table takes at least 1 argument")
+	}
+
+	alist, ok := stack.Pop().(*Pair)
+	if !ok {
+		return errors.New("alist->hash-table takes a list as the first argument")
+	}
+	if err := FnMakeHashTable(nargs - 1); err != nil {
+		return err
+	}
+	h := stack.Top().(*HashTable)
+
+	entries, err := list2vec(alist)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		pair, ok := entry.(*Pair)
+		if !ok || pair == Empty {
+			return errors.New("alist->hash-table takes a list of pairs")
+		}
+		// Earlier associations take precedence, as with assoc
+		if _, found, err := h.Ref(*pair.Car); err != nil {
+			return err
+		} else if !found {
+			if err := h.Set(*pair.Car, *pair.Cdr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func FnHashTableEquivalenceFunction(nargs int) error {
+	if nargs != 1 {
+		return errors.New("hash-table-equivalence-function takes 1 argument")
+	}
+
+	h, err := popHashTable("hash-table-equivalence-function")
+	if err != nil {
+		return err
+	}
+	stack.Push(h.Equiv)
+	return nil
+}
+
+func FnHashTableHashFunction(nargs int) error {
+	if nargs != 1 {
+		return errors.New("hash-table-hash-function takes 1 argument")
+	}
+
+	h, err := popHashTable("hash-table-hash-function")
+	if err != nil {
+		return err
+	}
+	stack.Push(h.Hash)
+	return nil
+}
+
+// pushHash pushes the hash of s, below the optional bound that follows the
+// hashed argument.
+func pushHash(name string, nargs int, s string) error {
+	if nargs != 1 && nargs != 2 {
+		return fmt.Errorf("%s takes 1 or 2 arguments", name)
+	}
+
+	f := fnv.New32a()
+	f.Write([]byte(s))
+	res := big.NewInt(int64(f.Sum32()))
+	if nargs == 2 {
+		bound, ok := stack.Pop().(Integer)
+		if bi := big.Int(bound); !ok || bi.Sign() <= 0 {
+			return fmt.Errorf("%s takes a positive integer as the bound", name)
+		}
+		bi := big.Int(bound)
+		res.Mod(res, &bi)
+	}
+	stack.Push(Integer(*res))
+	return nil
+}
+
+// FnHash hashes by equal?, which also serves as hash-by-identity since objects
+// that are eqv? are equal?.
+func FnHash(nargs int) error {
+	if nargs < 1 {
+		return errors.New("hash takes 1 or 2 arguments")
+	}
+	key, _ := equalKey(stack.Pop())
+	return pushHash("hash", nargs, key)
+}
+
+func FnStringHash(nargs int) error {
+	if nargs < 1 {
+		return errors.New("string-hash takes 1 or 2 arguments")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-hash takes a string as the first argument")
+	}
+	return pushHash("string-hash", nargs, s.String())
+}
+
+func FnStringCiHash(nargs int) error {
+	if nargs < 1 {
+		return errors.New("string-ci-hash takes 1 or 2 arguments")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-ci-hash takes a string as the first argument")
+	}
+	return pushHash("string-ci-hash", nargs, strings.Map(foldRune, s.String()))
+}
@@ -0,0 +1,624 @@
+package scheme
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+)
+
+// Images hold the instructions generated for each top-level form of one or
+// more source files, along with the macros those files defined, so that the
+// code can be replayed into an environment without being parsed or passed
+// through Gen again.  The encoding is a plain byte stream, so an image can be
+// written to disk, or embedded into a host program with go:embed and handed to
+// DecodeImage.
+//
+// Lambda templates are stored without their macro tables, since those are only
+// consulted by Gen, which has already run.
+type Image struct {
+	Forms  [][]Ins
+	Macros map[Symbol]SyntaxRules
+}
+
+const ImageMagic = "g5c\x00"
+
+// ImageVersion must be bumped whenever the opcodes or the encoding below
+// change.  The prelude cache is keyed on the build instead, as any change to
+// Gen, the macros or the builtins can change what the prelude compiles to.
+const ImageVersion = 9
+
+const (
+	tagNil byte = iota
+	tagEmpty
+	tagBoolean
+	tagSymbol
+	tagChar
+	tagString
+	tagInteger
+	tagRational
+	tagPair
+	tagVector
+	tagScoped
+	tagProcedure
+	tagEof
+	tagBytevector
+	tagUninterned
+	tagBuiltin
+)
+
+// Compile generates code for every form in the sources, in order.  Macros
+// defined along the way are available to later forms, but nothing is
+// evaluated, so the environment itself is left untouched.
+func (env *Procedure) Compile(sources ...string) (*Image, error) {
+	ctx := &Procedure{Scope: env.Scope, Macros: map[Symbol]SyntaxRules{}}
+	for k, v := range env.Macros {
+		ctx.Macros[k] = v
+	}
+
+	img := &Image{Forms: [][]Ins{}}
+	for _, code := range sources {
+		p := NewParser(code)
+		p.skipWs()
+
+		for len(p.data) > 0 {
+			v, err := p.GetValue()
+			p.skipWs()
+			if err != nil {
+				return nil, err
+			}
+
+			ctx.Ins = []Ins{}
+			if err := ctx.GenForm(v); err != nil {
+				return nil, err
+			}
+			img.Forms = append(img.Forms, ctx.Ins)
+		}
+	}
+	img.Macros = ctx.Macros
+	return img, nil
+}
+
+// Replay installs the image's macros into env and evaluates each form.
+func (env *Procedure) Replay(img *Image) error {
+	for k, v := range img.Macros {
+		env.Macros[k] = v
+	}
+
+	for _, form := range img.Forms {
+		ctx := &Procedure{Scope: env.Scope, Macros: env.Macros, Ins: form}
+		if err := ctx.Eval(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildKey identifies the build of g5 that is running, or is empty if it
+// can't be told apart from others.  A clean checkout is known by its revision
+// and the sums of its dependencies, and anything else by the executable's
+// path, size and modification time, which a rebuild changes.
+func buildKey() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		settings := map[string]string{}
+		for _, s := range info.Settings {
+			settings[s.Key] = s.Value
+		}
+		if rev := settings["vcs.revision"]; rev != "" && settings["vcs.modified"] == "false" {
+			key := rev
+			for _, dep := range info.Deps {
+				key += " " + dep.Path + "@" + dep.Version + " " + dep.Sum
+			}
+			return key
+		}
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		if info, err := os.Stat(exe); err == nil {
+			return fmt.Sprintf("%s %d %d", exe, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return ""
+}
+
+// PreludeCacheDir returns the directory the compiled prelude is cached in, or
+// "" if it shouldn't be cached.  G5_CACHE overrides the user's cache directory,
+// and setting it to the empty string turns the cache off.
+func PreludeCacheDir() string {
+	if dir, ok := os.LookupEnv("G5_CACHE"); ok {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "g5")
+	}
+	return ""
+}
+
+// LoadPrelude brings the builtin Scheme libraries into env, using a cached
+// image from the cache directory when one matches the embedded sources and the
+// running build, and writing one otherwise.  Writing one removes any others,
+// which were left by earlier builds and will never match again.
+func (env *Procedure) LoadPrelude() error {
+	sources := []string{Init, CaseLambdaSRFI, ListsSRFI}
+	build := buildKey()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s%d%t%t%t\x00%s\x00",
+		ImageMagic, ImageVersion, Optimise, FoldConstants, FoldCase, build)
+	for _, src := range sources {
+		fmt.Fprintf(h, "%d\x00%s", len(src), src)
+	}
+
+	path := ""
+	if dir := PreludeCacheDir(); dir != "" && build != "" {
+		path = filepath.Join(dir, fmt.Sprintf("prelude-%x.g5c", h.Sum(nil)[:8]))
+		if b, err := os.ReadFile(path); err == nil {
+			if img, err := DecodeImage(b); err == nil {
+				return env.Replay(img)
+			}
+		}
+	}
+
+	img, err := env.Compile(sources...)
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		// The cache is only an optimisation, so failing to write it is fine
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			if b, err := img.Encode(); err == nil && os.WriteFile(path, b, 0644) == nil {
+				stale, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "prelude-*.g5c"))
+				for _, old := range stale {
+					if old != path {
+						os.Remove(old)
+					}
+				}
+			}
+		}
+	}
+	return env.Replay(img)
+}
+
+func (img *Image) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	w := &imageWriter{bufio.NewWriter(&buf), map[interface{}]bool{}}
+
+	w.WriteString(ImageMagic)
+	w.uvarint(ImageVersion)
+
+	w.uvarint(uint64(len(img.Forms)))
+	for _, form := range img.Forms {
+		if err := w.ins(form); err != nil {
+			return nil, err
+		}
+	}
+
+	names := []string{}
+	for sym := range img.Macros {
+		names = append(names, SymbolNames[sym])
+	}
+	sort.Strings(names) // Keep the output deterministic
+
+	w.uvarint(uint64(len(names)))
+	for _, name := range names {
+		w.str(name)
+		if err := w.rules(img.Macros[Str2Sym(name)]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecodeImage(b []byte) (*Image, error) {
+	if !bytes.HasPrefix(b, []byte(ImageMagic)) {
+		return nil, errors.New("Not a compiled image")
+	}
+	r := &imageReader{bytes.NewReader(b[len(ImageMagic):]), map[uint64]Symbol{}}
+
+	version, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if version != ImageVersion {
+		return nil, fmt.Errorf(
+			"Image version mismatch (got %d, expected %d)",
+			version, ImageVersion,
+		)
+	}
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	img := &Image{[][]Ins{}, map[Symbol]SyntaxRules{}}
+	for i := uint64(0); i < n; i++ {
+		form, err := r.ins()
+		if err != nil {
+			return nil, err
+		}
+		img.Forms = append(img.Forms, form)
+	}
+
+	n, err = binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		rules, err := r.rules()
+		if err != nil {
+			return nil, err
+		}
+		img.Macros[Str2Sym(name)] = rules
+	}
+	return img, nil
+}
+
+type imageWriter struct {
+	*bufio.Writer
+	within map[interface{}]bool // The pairs and vectors being written
+}
+
+func (w *imageWriter) uvarint(n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func (w *imageWriter) varint(n int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutVarint(b[:], n)])
+}
+
+func (w *imageWriter) str(s string) {
+	w.uvarint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *imageWriter) ins(ins []Ins) error {
+	w.uvarint(uint64(len(ins)))
+	for _, in := range ins {
+		w.WriteByte(byte(in.op))
+		w.varint(int64(in.nargs))
+		if err := w.value(in.imm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *imageWriter) rules(rules SyntaxRules) error {
+	w.uvarint(uint64(len(rules.Literals)))
+	for _, lit := range rules.Literals {
+		w.str(SymbolNames[lit])
+	}
+
+	w.uvarint(uint64(len(rules.Patterns)))
+	for i := range rules.Patterns {
+		if err := w.value(rules.Patterns[i]); err != nil {
+			return err
+		}
+		if err := w.value(rules.Templates[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *imageWriter) value(v Value) error {
+	if v == nil {
+		return w.WriteByte(tagNil)
+	}
+	if v == Empty {
+		return w.WriteByte(tagEmpty)
+	}
+
+	switch v := v.(type) {
+	case Boolean:
+		w.WriteByte(tagBoolean)
+		if v {
+			return w.WriteByte(1)
+		}
+		return w.WriteByte(0)
+	case Symbol:
+		if !IsInterned(v) {
+			// Written with its number, so that each use reads back as the
+			// same new symbol
+			w.WriteByte(tagUninterned)
+			w.uvarint(uint64(v))
+			w.str(SymbolNames[v])
+			return nil
+		}
+		w.WriteByte(tagSymbol)
+		w.str(SymbolNames[v])
+	case Char:
+		w.WriteByte(tagChar)
+		w.varint(int64(v))
+	case String:
+		w.WriteByte(tagString)
+		w.str(v.String())
+	case Integer:
+		i := big.Int(v)
+		w.WriteByte(tagInteger)
+		w.str(i.String())
+	case Rational:
+		r := big.Rat(v)
+		w.WriteByte(tagRational)
+		w.str(r.RatString())
+	case *Pair:
+		if w.within[v] {
+			return errors.New("Cannot compile a cyclic constant")
+		}
+		w.within[v] = true
+		defer delete(w.within, v)
+
+		w.WriteByte(tagPair)
+		if err := w.value(*v.Car); err != nil {
+			return err
+		}
+		return w.value(*v.Cdr)
+	case Vector:
+		if w.within[v.v] {
+			return errors.New("Cannot compile a cyclic constant")
+		}
+		w.within[v.v] = true
+		defer delete(w.within, v.v)
+
+		w.WriteByte(tagVector)
+		w.uvarint(uint64(len(*v.v)))
+		for _, item := range *v.v {
+			if err := w.value(item); err != nil {
+				return err
+			}
+		}
+	case Scoped:
+		w.WriteByte(tagScoped)
+		w.str(SymbolNames[v.Symbol])
+		w.str(SymbolNames[v.Scope])
+	case *Procedure:
+		// Builtins are written by name, and only the ones that Gen refers
+		// to directly will be found that way
+		if v.Name == "" || BaseScope[Str2Sym(v.Name)] != v {
+			return errors.New("Cannot compile a builtin or continuation")
+		}
+		w.WriteByte(tagBuiltin)
+		w.str(v.Name)
+	case Procedure:
+		if v.Builtin != nil || v.CallCC != nil || v.Cont != nil {
+			return errors.New("Cannot compile a builtin or continuation")
+		}
+		w.WriteByte(tagProcedure)
+		w.str(v.Name)
+		if err := w.value(v.Args); err != nil {
+			return err
+		}
+		return w.ins(v.Ins)
+	case Eof:
+		return w.WriteByte(tagEof)
+	case Bytevector:
+		w.WriteByte(tagBytevector)
+		w.str(string(*v.b))
+	default:
+		return fmt.Errorf("Cannot compile value of type %T", v)
+	}
+	return nil
+}
+
+type imageReader struct {
+	*bytes.Reader
+	uninterned map[uint64]Symbol // By their number when written
+}
+
+func (r *imageReader) str() (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return string(b), err
+}
+
+func (r *imageReader) ins() ([]Ins, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := []Ins{}
+	for i := uint64(0); i < n; i++ {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		nargs, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		imm, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		ins = append(ins, Ins{Op(op), imm, int(nargs)})
+	}
+	return ins, nil
+}
+
+func (r *imageReader) rules() (SyntaxRules, error) {
+	rules := SyntaxRules{[]Symbol{}, []*Pair{}, []Value{}}
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return rules, err
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := r.str()
+		if err != nil {
+			return rules, err
+		}
+		rules.Literals = append(rules.Literals, Str2Sym(name))
+	}
+
+	n, err = binary.ReadUvarint(r)
+	if err != nil {
+		return rules, err
+	}
+	for i := uint64(0); i < n; i++ {
+		pattern, err := r.value()
+		if err != nil {
+			return rules, err
+		}
+		p, ok := pattern.(*Pair)
+		if !ok {
+			return rules, errors.New("Corrupt image: non-list macro pattern")
+		}
+		template, err := r.value()
+		if err != nil {
+			return rules, err
+		}
+		rules.Patterns = append(rules.Patterns, p)
+		rules.Templates = append(rules.Templates, template)
+	}
+	return rules, nil
+}
+
+func (r *imageReader) value() (Value, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagNil:
+		return nil, nil
+	case tagEmpty:
+		return Empty, nil
+	case tagBoolean:
+		b, err := r.ReadByte()
+		return Boolean(b != 0), err
+	case tagSymbol:
+		name, err := r.str()
+		return Str2Sym(name), err
+	case tagChar:
+		ch, err := binary.ReadVarint(r)
+		return Char(rune(ch)), err
+	case tagString:
+		s, err := r.str() // Only literals are in images
+		str := NewString(s)
+		str.immutable = true
+		return str, err
+	case tagInteger:
+		s, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		var i big.Int
+		if _, ok := i.SetString(s, 10); !ok {
+			return nil, fmt.Errorf("Corrupt image: bad integer %q", s)
+		}
+		return Integer(i), nil
+	case tagRational:
+		s, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		var rat big.Rat
+		if _, ok := rat.SetString(s); !ok {
+			return nil, fmt.Errorf("Corrupt image: bad rational %q", s)
+		}
+		return Rational(rat), nil
+	case tagPair:
+		car, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		cdr, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		return &Pair{&car, &cdr}, nil
+	case tagVector:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		vec := []Value{}
+		for i := uint64(0); i < n; i++ {
+			item, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			vec = append(vec, item)
+		}
+		return Vector{&vec}, nil
+	case tagScoped:
+		sym, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		scope, err := r.str()
+		return Scoped{Str2Sym(sym), Str2Sym(scope)}, err
+	case tagProcedure:
+		name, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		args, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		ins, err := r.ins()
+		if err != nil {
+			return nil, err
+		}
+		return Procedure{
+			Args:   args,
+			Ins:    ins,
+			Macros: map[Symbol]SyntaxRules{},
+			Name:   name,
+		}, nil
+	case tagEof:
+		return Eof{}, nil
+	case tagUninterned:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.str()
+		if _, ok := r.uninterned[n]; !ok {
+			r.uninterned[n] = Uninterned(name)
+		}
+		return r.uninterned[n], err
+	case tagBytevector:
+		s, err := r.str()
+		b := []byte(s)
+		return Bytevector{&b}, err
+	case tagBuiltin:
+		name, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := BaseScope[Str2Sym(name)].(*Procedure); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("Corrupt image: unknown builtin %s", name)
+	}
+	return nil, fmt.Errorf("Corrupt image: unknown tag %d", tag)
+}
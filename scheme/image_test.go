@@ -0,0 +1,160 @@
+package scheme
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageRoundTrip(t *testing.T) {
+	ctx := &Procedure{Macros: map[Symbol]SyntaxRules{}}
+	img, err := ctx.Compile(Init, CaseLambdaSRFI, ListsSRFI)
+	if err != nil {
+		t.Fatalf("Could not compile prelude: %v", err)
+	}
+
+	b, err := img.Encode()
+	if err != nil {
+		t.Fatalf("Could not encode image: %v", err)
+	}
+
+	decoded, err := DecodeImage(b)
+	if err != nil {
+		t.Fatalf("Could not decode image: %v", err)
+	}
+
+	again, err := decoded.Encode()
+	if err != nil {
+		t.Fatalf("Could not re-encode image: %v", err)
+	}
+
+	if !bytes.Equal(b, again) {
+		t.Errorf("Image changed after a decode/encode round trip")
+	}
+}
+
+func TestImageReplay(t *testing.T) {
+	img, err := Top.Compile(`
+		(define (image-fact n)
+		  (let loop ((n n) (acc 1))
+		    (cond ((= n 0) acc)
+		          (else (loop (- n 1) (* acc n))))))`)
+	if err != nil {
+		t.Fatalf("Could not compile: %v", err)
+	}
+
+	b, err := img.Encode()
+	if err != nil {
+		t.Fatalf("Could not encode image: %v", err)
+	}
+
+	decoded, err := DecodeImage(b)
+	if err != nil {
+		t.Fatalf("Could not decode image: %v", err)
+	}
+
+	if err := Top.Replay(decoded); err != nil {
+		t.Fatalf("Could not replay image: %v", err)
+	}
+
+	Top.Run("(image-fact 5)", true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Fatalf("Expected integer, got %T", stack.Top())
+	}
+	if result := big.Int(result); result.Cmp(big.NewInt(120)) != 0 {
+		t.Errorf("Expected 120, got %v", result.String())
+	}
+}
+
+func TestImageUninterned(t *testing.T) {
+	// define-values binds through uninterned temporaries, which must still
+	// match each other once read back
+	img, err := Top.Compile(`(define-values (image-q image-r) (floor/ 7 2))`)
+	if err != nil {
+		t.Fatalf("Could not compile: %v", err)
+	}
+
+	b, err := img.Encode()
+	if err != nil {
+		t.Fatalf("Could not encode image: %v", err)
+	}
+
+	decoded, err := DecodeImage(b)
+	if err != nil {
+		t.Fatalf("Could not decode image: %v", err)
+	}
+
+	if err := Top.Replay(decoded); err != nil {
+		t.Fatalf("Could not replay image: %v", err)
+	}
+
+	Top.Run("(list image-q image-r)", true)
+	if res := ValueString(stack.Top(), false); res != "(3 1)" {
+		t.Errorf("Expected (3 1), got %s", res)
+	}
+}
+
+func TestImageRecordType(t *testing.T) {
+	// define-record-type refers to the record builtins directly
+	img, err := Top.Compile(`(define-record-type image-rec (make-image-rec a)
+		image-rec? (a image-rec-a))`)
+	if err != nil {
+		t.Fatalf("Could not compile: %v", err)
+	}
+
+	b, err := img.Encode()
+	if err != nil {
+		t.Fatalf("Could not encode image: %v", err)
+	}
+
+	decoded, err := DecodeImage(b)
+	if err != nil {
+		t.Fatalf("Could not decode image: %v", err)
+	}
+
+	if err := Top.Replay(decoded); err != nil {
+		t.Fatalf("Could not replay image: %v", err)
+	}
+
+	Top.Run("(image-rec-a (make-image-rec 'field))", true)
+	if got := ValueString(stack.Top(), false); got != "field" {
+		t.Errorf("Expected field, got %s", got)
+	}
+}
+
+func TestBuildKey(t *testing.T) {
+	// The test binary has no VCS stamp, so it is known by its executable
+	key := buildKey()
+	if key == "" {
+		t.Fatalf("Expected a key for the running build")
+	}
+	if again := buildKey(); again != key {
+		t.Errorf("Expected the same key twice, got %q and %q", key, again)
+	}
+}
+
+func TestPreludeCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("G5_CACHE", dir)
+	stale := filepath.Join(dir, "prelude-0123456789abcdef.g5c")
+	os.WriteFile(stale, []byte("left by an older build"), 0644)
+
+	for i := 0; i < 2; i++ {
+		scope := map[Symbol]Value{}
+		for k, v := range BaseScope {
+			scope[k] = v
+		}
+		env := &Procedure{Scope: Scope{scope, nil}, Macros: map[Symbol]SyntaxRules{}}
+		if err := env.LoadPrelude(); err != nil {
+			t.Fatalf("Could not load prelude: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "prelude-*.g5c"))
+	if len(files) != 1 || files[0] == stale {
+		t.Errorf("Expected a single fresh cache file, got %v", files)
+	}
+}
@@ -0,0 +1,462 @@
+(define call-with-current-continuation call/cc)
+(define (null? x) (eqv? x '()))
+(define (zero? z) (= z 0))
+(define (positive? x) (> x 0))
+(define (negative? x) (< x 0))
+(define exact? number?)
+(define (inexact? z) #f) ; Every number is exact
+(define exact-integer? integer?)
+(define (exact z) z)
+(define (inexact z) z)
+(define exact->inexact inexact)
+(define inexact->exact exact)
+(define (square z) (* z z))
+(define truncate-quotient quotient)
+(define truncate-remainder remainder)
+(define floor-remainder modulo)
+
+(define (list . x) x)
+
+(define-syntax begin
+  (syntax-rules ()
+    ((begin exp ...)
+     ((lambda () exp ...)))))
+
+(define-syntax cond
+  (syntax-rules (else =>)
+    ((cond (else result1 result2 ...))
+     (begin result1 result2 ...))
+    ((cond (test => result))
+     (let ((temp test))
+       (if temp (result temp))))
+    ((cond (test => result) clause1 clause2 ...)
+     (let ((temp test))
+       (if temp
+           (result temp)
+           (cond clause1 clause2 ...))))
+    ((cond (test)) test)
+    ((cond (test) clause1 clause2 ...)
+     (let ((temp test))
+       (if temp
+           temp
+           (cond clause1 clause2 ...))))
+    ((cond (test result1 result2 ...))
+     (if test (begin result1 result2 ...)))
+    ((cond (test result1 result2 ...)
+           clause1 clause2 ...)
+     (if test
+         (begin result1 result2 ...)
+         (cond clause1 clause2 ...)))))
+
+(define-syntax when
+  (syntax-rules ()
+    ((when test result1 result2 ...)
+     (if test (begin result1 result2 ...)))))
+
+(define-syntax unless
+  (syntax-rules ()
+    ((unless test result1 result2 ...)
+     (if test #f (begin result1 result2 ...)))))
+
+(define-syntax case
+  (syntax-rules (else)
+    ((case (key ...)
+       clauses ...)
+     (let ((atom-key (key ...)))
+       (case atom-key clauses ...)))
+    ((case key
+       (else result1 result2 ...))
+     (begin result1 result2 ...))
+    ((case key
+       ((atoms ...) result1 result2 ...))
+     (if (memv key '(atoms ...))
+         (begin result1 result2 ...)))
+    ((case key
+       ((atoms ...) result1 result2 ...)
+       clause clauses ...)
+     (if (memv key '(atoms ...))
+         (begin result1 result2 ...)
+         (case key clause clauses ...)))))
+
+(define-syntax and
+  (syntax-rules ()
+    ((and) #t)
+    ((and test) test)
+    ((and test1 test2 ...)
+     (if test1 (and test2 ...) #f))))
+
+(define-syntax or
+  (syntax-rules ()
+    ((or) #f)
+    ((or test) test)
+    ((or test1 test2 ...)
+     (let ((x test1))
+       (if x x (or test2 ...))))))
+  
+(define-syntax letrec 
+  (syntax-rules () 
+    ((_ ((var init) ...) body ...)
+     (let () 
+       (define var init) ... 
+       (let () body ...))))) 
+
+ (define-syntax let
+   (syntax-rules ()
+     ((let ((name val) ...) body1 body2 ...)
+     ((lambda (name ...) body1 body2 ...) val ...))
+    ((let tag ((name val) ...) body1 body2 ...)
+     ((letrec ((tag (lambda (name ...) body1 body2 ...))) tag) val ...))))
+
+(define-syntax letrec*
+  (syntax-rules ()
+    ((_ ((var init) ...) body ...)
+     (letrec ((var init) ...) body ...))))
+
+(define-syntax let*
+  (syntax-rules ()
+    ((let* () body1 body2 ...)
+     (let () body1 body2 ...))
+    ((let* ((name1 val1) (name2 val2) ...)
+       body1 body2 ...)
+     (let ((name1 val1))
+       (let* ((name2 val2) ...)
+         body1 body2 ...)))))
+
+(define-syntax do
+  (syntax-rules ()
+    ((_ ((var init step) ...)
+         (test expr ...)
+         command ...)
+     (letrec
+       ((loop
+         (lambda (var ...)
+           (if test
+               (begin
+                 (if #f #f)
+                 expr ...)
+               (begin
+                 command
+                 ...
+                 (loop (do "step" var step) ...))))))
+       (loop init ...)))
+    ((do "step" x) x)
+    ((do "step" x y) y)
+    ;; Give every variable without a step itself as its step
+    ((do "norm" (done ...) () clause command ...)
+     (do (done ...) clause command ...))
+    ((do "norm" (done ...) ((var init) binding ...) clause command ...)
+     (do "norm" (done ... (var init var)) (binding ...) clause command ...))
+    ((do "norm" (done ...) ((var init step) binding ...) clause command ...)
+     (do "norm" (done ... (var init step)) (binding ...) clause command ...))
+    ((do (binding ...) clause command ...)
+     (do "norm" () (binding ...) clause command ...))))
+
+(define (%heads l) (if (null? l) '() (cons (car (car l)) (%heads (cdr l)))))
+(define (%tails l) (if (null? l) '() (cons (cdr (car l)) (%tails (cdr l)))))
+(define (%all-pairs? l) (or (null? l) (and (pair? (car l)) (%all-pairs? (cdr l)))))
+
+(define (for-each f l . ls)
+  (if (null? ls)
+    (let loop ((l l))
+      (when (pair? l)
+        (f (car l))
+        (loop (cdr l))))
+    (let loop ((ls (cons l ls)))
+      (when (%all-pairs? ls)
+        (apply f (%heads ls))
+        (loop (%tails ls))))))
+
+(define (print . x)
+  (for-each (lambda (x) (display x) (display #\space)) x)
+  (newline))
+
+;;; Promises, as in the R7RS reference implementation: a promise holds a box
+;;; that forcing a delay-force chain shares between the promises in it
+(define %promise-tag (list 'promise))
+(define (%make-promise done? value) (list %promise-tag (cons done? value)))
+(define (promise? x) (and (pair? x) (eq? (car x) %promise-tag)))
+(define (%promise-done? p) (car (car (cdr p))))
+(define (%promise-value p) (cdr (car (cdr p))))
+(define (%promise-update! new old)
+  (set-car! (car (cdr old)) (%promise-done? new))
+  (set-cdr! (car (cdr old)) (%promise-value new))
+  (set-car! (cdr new) (car (cdr old))))
+
+(define (make-promise obj)
+  (if (promise? obj) obj (%make-promise #t obj)))
+
+(define (force p)
+  (cond ((not (promise? p)) p)
+        ((%promise-done? p) (%promise-value p))
+        (else
+          (let ((p* ((%promise-value p))))
+            (unless (%promise-done? p) (%promise-update! p* p))
+            (force p)))))
+
+(define-syntax delay-force
+  (syntax-rules ()
+    ((delay-force expression)
+     (%make-promise #f (lambda () expression)))))
+
+(define-syntax delay
+  (syntax-rules ()
+    ((delay expression)
+     (delay-force (%make-promise #t expression)))))
+
+(define (length list)
+  (define (lengthl list . count)
+    (if (null? list)
+      (car count)
+      (lengthl (cdr list)
+              (if (null? count)
+                1
+                (+ (car count) 1)))))
+  (lengthl list 0))
+
+(define (reverse l)
+  (let loop ((l l) (res '()))
+    (if (null? l)
+      res
+      (loop (cdr l) (cons (car l) res)))))
+
+(define (list-tail x k)
+                  (if (zero? k)
+                    x
+                    (list-tail (cdr x) (- k 1))))
+
+;(define (memf f? x l)
+;  (cond
+;    ((null? l) #f)
+;    ((f? (car l) x) l)
+;    (member x (cdr l))))
+;
+;(define (member x l) (memf equal? x l))
+;(define (memv x l) (memf eqv? x l))
+;(define (memq x l) (memf eq? x l))
+;;;; Defined in lists.scm
+
+(define (assf f? thing alist)
+   (if (null? alist)
+       #f
+       (if (f? (car (car alist)) thing)
+           (car alist)
+           (assf f? thing (cdr alist)))))
+
+; (define (assoc x l) (assf equal? x l)) ;;;; also defined in lists.scm
+(define (assv x l) (assf eqv? x l))
+(define (assq x l) (assf eq? x l))
+
+(define (append . l)
+  (define (append2 l1 l2)
+    (if (null? l1)
+      l2
+      (cons (car l1) (append2 (cdr l1) l2))))
+  (case (length l)
+    ((0) '())
+    ((1) (car l))
+    ((2) (append2 (car l) (car (cdr l))))
+    (else (append2 (car l) (apply append (cdr l))))))
+
+(define (list-ref l n)
+  (if (zero? n)
+    (car l)
+    (list-ref (cdr l) (- n 1))))
+
+(define-syntax quasiquote
+  (syntax-rules (unquote unquote-splicing)
+    ((_ ((unquote x) . xs))          (cons x (quasiquote xs)))
+    ((_ ((unquote-splicing x) . xs)) (append x (quasiquote xs)))
+    ((_ (x . xs))                    (cons (quasiquote x) (quasiquote xs)))
+    ((_ (unquote x)) x)
+    ((_ x) (quote x))))
+
+
+(define (get-environment-variable k)
+  (define (get-var k env)
+    (if (null? env)
+      #f
+      (if (string=? (car (car env)) k)
+        (cdr (car env))
+        (get-var k (cdr env)))))
+  (get-var k (get-environment-variables)))
+
+(define (caar   x) (car (car x)))
+(define (cadr   x) (car (cdr x)))
+(define (cdar   x) (cdr (car x)))
+(define (cddr   x) (cdr (cdr x)))
+
+(define (caaar  x) (caar (car x)))
+(define (caadr  x) (caar (cdr x)))
+(define (cadar  x) (cadr (car x)))
+(define (caddr  x) (cadr (cdr x)))
+(define (cdaar  x) (cdar (car x)))
+(define (cdadr  x) (cdar (cdr x)))
+(define (cddar  x) (cddr (car x)))
+(define (cdddr  x) (cddr (cdr x)))
+
+(define (caaaar x) (caaar (car x)))
+(define (caaadr x) (caaar (cdr x)))
+(define (caadar x) (caadr (car x)))
+(define (caaddr x) (caadr (cdr x)))
+(define (cadaar x) (cadar (car x)))
+(define (cadadr x) (cadar (cdr x)))
+(define (caddar x) (caddr (car x)))
+(define (cadddr x) (caddr (cdr x)))
+(define (cdaaar x) (cdaar (car x)))
+(define (cdaadr x) (cdaar (cdr x)))
+(define (cdadar x) (cdadr (car x)))
+(define (cdaddr x) (cdadr (cdr x)))
+(define (cddaar x) (cddar (car x)))
+(define (cddadr x) (cddar (cdr x)))
+(define (cdddar x) (cdddr (car x)))
+(define (cddddr x) (cdddr (cdr x)))
+
+(define-syntax receive
+  (syntax-rules ()
+    ((receive formals expression body ...)
+     (call-with-values (lambda () expression)
+                       (lambda formals body ...)))))
+
+(define (check-arg pred val caller)
+  (let lp ((val val))
+    (if (pred val) val (lp (error "Bad argument" val pred caller)))))
+
+(define-syntax :optional
+   (syntax-rules ()
+     ((_ val default-value)
+      (if (null? val) default-value (car val)))))
+
+(define (write-char ch . port)
+  (if (not (char? ch))
+    (error "write-char takes a char as the argument")
+    (apply display (cons ch port))))
+
+(define (newline . port) (apply write-char (cons #\newline port)))
+
+(define (rationalize x y) ; The simplest rational within y of x
+  (define (simplest lo hi)
+    (let ((fl (floor lo)))
+      (cond ((= fl lo) fl)
+            ((< fl (floor hi)) (+ fl 1))
+            (else (+ fl (/ 1 (simplest (/ 1 (- hi fl)) (/ 1 (- lo fl)))))))))
+  (let ((lo (- x (abs y))) (hi (+ x (abs y))))
+    (cond ((positive? lo) (simplest lo hi))
+          ((negative? hi) (- (simplest (- hi) (- lo))))
+          (else 0))))
+
+(define (iexpt x y)
+  (let loop ((count y) (res 1))
+    (cond
+      ((zero? count) res)
+      ((> count 0) (loop (- count 1) (* res x)))
+      ((< count 0) (loop (+ count 1) (/ res x))))))
+
+(define (expt x y)
+  (if (integer? y)
+    (iexpt x y)
+    (rexpt x y)))
+
+(define (sqrt x) (expt x 0.5))
+(define pi 3.141592654)
+(define (tan x) (/ (sin x) (cos x)))
+(define (exp x) (expt 2.718281828 x))
+
+(define (features) '(r7rs exact-closed ratios full-unicode g5))
+
+(define (%feature? requirement)
+  (cond ((symbol? requirement) (memq requirement (features)))
+        ((eq? (car requirement) 'and) (every %feature? (cdr requirement)))
+        ((eq? (car requirement) 'or) (any %feature? (cdr requirement)))
+        ((eq? (car requirement) 'not) (not (%feature? (cadr requirement))))
+        (else #f)))
+
+;;; Requirements are tested when the form runs rather than when it expands
+(define-syntax cond-expand
+  (syntax-rules (else)
+    ((cond-expand) (if #f #f))
+    ((cond-expand (else body ...))
+     (begin body ...))
+    ((cond-expand (requirement body ...) clause ...)
+     (if (%feature? 'requirement)
+         (begin body ...)
+         (cond-expand clause ...)))))
+
+(define-syntax syntax-error
+  (syntax-rules ()
+    ((syntax-error message args ...)
+     (error message 'args ...))))
+
+(define textual-port? port?)
+(define binary-port? port?)
+
+(define-syntax guard
+  (syntax-rules ()
+    ((guard (var clause ...) body ...)
+     (%guard (lambda () body ...)
+             (lambda (var)
+               (guard-aux (raise-continuable var) clause ...))))))
+
+(define-syntax guard-aux
+  (syntax-rules (else =>)
+    ((guard-aux reraise (else result1 result2 ...))
+     (begin result1 result2 ...))
+    ((guard-aux reraise (test => result))
+     (let ((temp test))
+       (if temp (result temp) reraise)))
+    ((guard-aux reraise (test => result) clause1 clause2 ...)
+     (let ((temp test))
+       (if temp
+           (result temp)
+           (guard-aux reraise clause1 clause2 ...))))
+    ((guard-aux reraise (test))
+     (or test reraise))
+    ((guard-aux reraise (test) clause1 clause2 ...)
+     (let ((temp test))
+       (if temp
+           temp
+           (guard-aux reraise clause1 clause2 ...))))
+    ((guard-aux reraise (test result1 result2 ...))
+     (if test
+         (begin result1 result2 ...)
+         reraise))
+    ((guard-aux reraise (test result1 result2 ...) clause1 clause2 ...)
+     (if test
+         (begin result1 result2 ...)
+         (guard-aux reraise clause1 clause2 ...)))))
+
+;;; A parameter answers the messages <param-set!> and <param-convert> as
+;;; well as being called with no arguments, so that parameterize can rebind it
+(define (make-parameter value . converter)
+  (let* ((convert (if (null? converter) (lambda (x) x) (car converter)))
+         (value (convert value)))
+    (lambda args
+      (cond ((null? args) value)
+            ((eq? (car args) '<param-set!>) (set! value (car (cdr args))))
+            ((eq? (car args) '<param-convert>) convert)
+            (else (error "parameter called with arguments" args))))))
+
+(define-syntax parameterize
+  (syntax-rules ()
+    ((parameterize ((param value) ...) body ...)
+     (let* ((params (list param ...))
+            (new (map (lambda (p v) ((p '<param-convert>) v))
+                      params
+                      (list value ...)))
+            (old (map (lambda (p) (p)) params)))
+       (dynamic-wind
+         (lambda () (for-each (lambda (p v) (p '<param-set!> v)) params new))
+         (lambda () body ...)
+         (lambda () (for-each (lambda (p v) (p '<param-set!> v)) params old)))))))
+
+(define-syntax let-values
+  (syntax-rules ()
+    ((let-values () body ...)
+     (let () body ...))
+    ((let-values ((formals expression) binding ...) body ...)
+     (call-with-values (lambda () expression)
+                       (lambda formals (let-values (binding ...) body ...))))))
+
+(define-syntax let*-values
+  (syntax-rules ()
+    ((let*-values bindings body ...)
+     (let-values bindings body ...))))
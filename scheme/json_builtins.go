@@ -0,0 +1,587 @@
+package scheme
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// JSON objects read as alists, or hash tables if asked for, keyed by strings.
+// Arrays read as vectors, null as the value json-null returns, and numbers as
+// exact integers or rationals, so that none lose precision.  Writing takes
+// these back, along with symbol keys.
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE]([+-]?[0-9]+))?$`)
+
+// jsonMaxExponent bounds the exponents of the numbers read, as each is read
+// exactly, and 1e1000000000 would take gigabytes.
+const jsonMaxExponent = 10000
+
+// JSONNull is what null reads as.  It is a type of its own, so that it is
+// none of the values a program could otherwise have.
+type JSONNull struct{}
+
+func (JSONNull) isValue() {}
+
+type jsonReader struct {
+	r          io.RuneScanner
+	hashTables bool // Whether objects read as hash tables
+}
+
+// next returns the next rune that isn't whitespace.
+func (jr *jsonReader) next() (rune, error) {
+	for {
+		r, _, err := jr.r.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		switch r {
+		case ' ', '\t', '\n', '\r':
+		default:
+			return r, nil
+		}
+	}
+}
+
+func (jr *jsonReader) expect(want string) error {
+	for _, w := range want {
+		r, _, err := jr.r.ReadRune()
+		if err != nil || r != w {
+			return fmt.Errorf("JSON: expected %s", want)
+		}
+	}
+	return nil
+}
+
+// value reads the value that starts with r.
+func (jr *jsonReader) value(r rune) (Value, error) {
+	switch {
+	case r == '{':
+		return jr.object()
+	case r == '[':
+		return jr.array()
+	case r == '"':
+		s, err := jr.str()
+		return NewString(s), err
+	case r == 't':
+		return Boolean(true), jr.expect("rue")
+	case r == 'f':
+		return Boolean(false), jr.expect("alse")
+	case r == 'n':
+		return JSONNull{}, jr.expect("ull")
+	case r == '-' || (r >= '0' && r <= '9'):
+		return jr.number(r)
+	}
+	return nil, fmt.Errorf("JSON: unexpected %q", r)
+}
+
+func (jr *jsonReader) number(r rune) (Value, error) {
+	var sb strings.Builder
+	for {
+		if err := reserve(1, runeSize); err != nil {
+			return nil, err
+		}
+		sb.WriteRune(r)
+		var err error
+		if r, _, err = jr.r.ReadRune(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !strings.ContainsRune("+-.0123456789eE", r) {
+			jr.r.UnreadRune()
+			break
+		}
+	}
+
+	s := sb.String()
+	match := jsonNumber.FindStringSubmatch(s)
+	if match == nil {
+		return nil, fmt.Errorf("JSON: invalid number %s", s)
+	}
+	if exp, err := strconv.Atoi(match[4]); match[4] != "" &&
+		(err != nil || exp > jsonMaxExponent || exp < -jsonMaxExponent) {
+		return nil, fmt.Errorf("JSON: exponent out of range in %s", s)
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("JSON: invalid number %s", s)
+	}
+	return ratValue(rat), nil
+}
+
+func (jr *jsonReader) hex4() (rune, error) {
+	var digits [4]rune
+	for i := range digits {
+		r, _, err := jr.r.ReadRune()
+		if err != nil {
+			return 0, errors.New("JSON: unterminated string")
+		}
+		digits[i] = r
+	}
+	n, err := strconv.ParseUint(string(digits[:]), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("JSON: invalid escape \\u%s", string(digits[:]))
+	}
+	return rune(n), nil
+}
+
+// str reads the rest of a string after its opening quote.
+func (jr *jsonReader) str() (string, error) {
+	var sb strings.Builder
+	for {
+		// A port can go on for longer than the limit allows
+		if err := reserve(1, runeSize); err != nil {
+			return "", err
+		}
+		r, _, err := jr.r.ReadRune()
+		if err != nil {
+			return "", errors.New("JSON: unterminated string")
+		}
+		switch {
+		case r == '"':
+			return sb.String(), nil
+		case r < 0x20:
+			return "", errors.New("JSON: control character in string")
+		case r != '\\':
+			sb.WriteRune(r)
+			continue
+		}
+
+		if r, _, err = jr.r.ReadRune(); err != nil {
+			return "", errors.New("JSON: unterminated string")
+		}
+		switch r {
+		case '"', '\\', '/':
+			sb.WriteRune(r)
+		case 'b':
+			sb.WriteRune('\b')
+		case 'f':
+			sb.WriteRune('\f')
+		case 'n':
+			sb.WriteRune('\n')
+		case 'r':
+			sb.WriteRune('\r')
+		case 't':
+			sb.WriteRune('\t')
+		case 'u':
+			r, err := jr.hex4()
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) { // The other half follows as \uXXXX
+				if err := jr.expect(`\u`); err != nil {
+					return "", err
+				}
+				low, err := jr.hex4()
+				if err != nil {
+					return "", err
+				}
+				r = utf16.DecodeRune(r, low)
+			}
+			sb.WriteRune(r)
+		default:
+			return "", fmt.Errorf("JSON: invalid escape \\%c", r)
+		}
+	}
+}
+
+func (jr *jsonReader) array() (Value, error) {
+	items := []Value{}
+	r, err := jr.next()
+	if err == nil && r == ']' {
+		return Vector{&items}, nil
+	}
+	for ; err == nil; r, err = jr.next() {
+		if err := reserve(1, valueSize); err != nil {
+			return nil, err
+		}
+		item, err := jr.value(r)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		if r, err = jr.next(); err != nil {
+			break
+		} else if r == ']' {
+			return Vector{&items}, nil
+		} else if r != ',' {
+			return nil, fmt.Errorf("JSON: expected , or ] but got %q", r)
+		}
+	}
+	return nil, errors.New("JSON: unterminated array")
+}
+
+func (jr *jsonReader) object() (Value, error) {
+	keys, values := []Value{}, []Value{}
+	r, err := jr.next()
+	if err == nil && r == '}' {
+		return jr.makeObject(keys, values)
+	}
+	for ; err == nil; r, err = jr.next() {
+		if r != '"' {
+			return nil, fmt.Errorf("JSON: expected a key but got %q", r)
+		}
+		if err := reserve(1, 2*valueSize+pairSize); err != nil {
+			return nil, err
+		}
+		key, err := jr.str()
+		if err != nil {
+			return nil, err
+		}
+		if r, err = jr.next(); err != nil || r != ':' {
+			return nil, errors.New("JSON: expected : after a key")
+		}
+		if r, err = jr.next(); err != nil {
+			break
+		}
+		value, err := jr.value(r)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, NewString(key))
+		values = append(values, value)
+
+		if r, err = jr.next(); err != nil {
+			break
+		} else if r == '}' {
+			return jr.makeObject(keys, values)
+		} else if r != ',' {
+			return nil, fmt.Errorf("JSON: expected , or } but got %q", r)
+		}
+	}
+	return nil, errors.New("JSON: unterminated object")
+}
+
+func (jr *jsonReader) makeObject(keys, values []Value) (Value, error) {
+	if jr.hashTables {
+		h, err := NewHashTable(defaultEquiv, nil)
+		if err != nil {
+			return nil, err
+		}
+		for i := range keys {
+			if err := h.Set(keys[i], values[i]); err != nil {
+				return nil, err
+			}
+		}
+		return h, nil
+	}
+
+	pairs := make([]Value, len(keys))
+	for i := range keys {
+		pairs[i] = &Pair{&keys[i], &values[i]}
+	}
+	return vec2list(pairs), nil
+}
+
+// read reads the next value, or returns an eof object if there are no more.
+func (jr *jsonReader) read() (Value, error) {
+	r, err := jr.next()
+	if err == io.EOF {
+		return Eof{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return jr.value(r)
+}
+
+// popObjectType pops the optional symbol saying what objects read as.
+func popObjectType(name string, nargs, k int) (bool, error) {
+	if nargs <= k {
+		return false, nil
+	}
+	switch v := stack.Pop(); v {
+	case Str2Sym("alist"):
+		return false, nil
+	case Str2Sym("hash-table"):
+		return true, nil
+	}
+	return false, fmt.Errorf("%s: objects can be read as alist or hash-table", name)
+}
+
+// FnJsonRead reads one value from the port, leaving the rest to be read, so
+// that streams of values can be read one by one.
+func FnJsonRead(nargs int) error {
+	if nargs > 2 {
+		return errors.New("json-read takes at most 2 arguments")
+	}
+
+	port, err := inputPort("json-read", nargs, 0)
+	if err != nil {
+		return err
+	}
+	hashTables, err := popObjectType("json-read", nargs, 1)
+	if err != nil {
+		return err
+	}
+	v, err := (&jsonReader{r: port, hashTables: hashTables}).read()
+	if err != nil {
+		return err
+	}
+	stack.Push(v)
+	return nil
+}
+
+// FnJsonReadString reads the value in a string, which must hold just one.
+func FnJsonReadString(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("json-read-string takes 1 or 2 arguments")
+	}
+
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("json-read-string takes a string as the first argument")
+	}
+	hashTables, err := popObjectType("json-read-string", nargs, 1)
+	if err != nil {
+		return err
+	}
+	jr := &jsonReader{
+		r:          bufio.NewReader(strings.NewReader(s.String())),
+		hashTables: hashTables,
+	}
+	v, err := jr.read()
+	if err != nil {
+		return err
+	} else if _, ok := v.(Eof); ok {
+		return errors.New("JSON: no value in the string")
+	}
+	if _, err := jr.next(); err != io.EOF {
+		return errors.New("JSON: more than one value in the string")
+	}
+	stack.Push(v)
+	return nil
+}
+
+func writeJSONString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// writeJSONNumber writes rationals exactly where a decimal can, and as the
+// nearest float otherwise.
+func writeJSONNumber(sb *strings.Builder, r *big.Rat) {
+	if r.IsInt() {
+		sb.WriteString(r.Num().String())
+		return
+	}
+
+	digits := 0
+	d := new(big.Int).Set(r.Denom())
+	for _, p := range []int64{2, 5} {
+		n, m, prime := 0, new(big.Int), big.NewInt(p)
+		for {
+			q, rem := new(big.Int).QuoRem(d, prime, m)
+			if rem.Sign() != 0 {
+				break
+			}
+			d, n = q, n+1
+		}
+		if n > digits {
+			digits = n
+		}
+	}
+	if d.Cmp(big.NewInt(1)) == 0 {
+		sb.WriteString(r.FloatString(digits))
+	} else {
+		f, _ := r.Float64()
+		sb.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+}
+
+func jsonKey(v Value) (string, bool) {
+	switch v := v.(type) {
+	case Symbol:
+		return SymbolNames[v], true
+	case String:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// jsonWriter writes values as JSON, keeping track of the vectors and lists it
+// is in so as not to loop on cycles.
+type jsonWriter struct {
+	sb     strings.Builder
+	inside map[interface{}]bool
+}
+
+func (jw *jsonWriter) object(keys []string, values []Value) error {
+	jw.sb.WriteByte('{')
+	for i, key := range keys {
+		if i != 0 {
+			jw.sb.WriteByte(',')
+		}
+		writeJSONString(&jw.sb, key)
+		jw.sb.WriteByte(':')
+		if err := jw.write(values[i]); err != nil {
+			return err
+		}
+	}
+	jw.sb.WriteByte('}')
+	return nil
+}
+
+func (jw *jsonWriter) write(v Value) error {
+	switch v := v.(type) {
+	case Boolean:
+		jw.sb.WriteString(strconv.FormatBool(bool(v)))
+		return nil
+	case Integer, Rational:
+		r, _ := toRat(v)
+		writeJSONNumber(&jw.sb, r)
+		return nil
+	case String:
+		writeJSONString(&jw.sb, v.String())
+		return nil
+	case JSONNull:
+		jw.sb.WriteString("null")
+		return nil
+	case Vector:
+		if jw.inside[v.v] {
+			return errors.New("json-write: cannot write a cyclic value")
+		}
+		jw.inside[v.v] = true
+		defer delete(jw.inside, v.v)
+
+		jw.sb.WriteByte('[')
+		for i, item := range *v.v {
+			if i != 0 {
+				jw.sb.WriteByte(',')
+			}
+			if err := jw.write(item); err != nil {
+				return err
+			}
+		}
+		jw.sb.WriteByte(']')
+		return nil
+	case *Pair:
+		if jw.inside[v] {
+			return errors.New("json-write: cannot write a cyclic value")
+		}
+		jw.inside[v] = true
+		defer delete(jw.inside, v)
+
+		items, err := list2vec(v)
+		if err != nil {
+			return errors.New("json-write takes alists as objects")
+		}
+		keys, values := make([]string, len(items)), make([]Value, len(items))
+		for i, item := range items {
+			pair, ok := item.(*Pair)
+			if ok && pair != Empty {
+				keys[i], ok = jsonKey(*pair.Car)
+			}
+			if !ok {
+				return errors.New("json-write takes alists as objects, " +
+					"keyed by symbols or strings; arrays are vectors")
+			}
+			values[i] = *pair.Cdr
+		}
+		return jw.object(keys, values)
+	case *HashTable:
+		if jw.inside[v] {
+			return errors.New("json-write: cannot write a cyclic value")
+		}
+		jw.inside[v] = true
+		defer delete(jw.inside, v)
+
+		entries := v.Entries()
+		keys, values := make([]string, len(entries)), make([]Value, len(entries))
+		for _, entry := range entries {
+			if _, ok := jsonKey(entry.key); !ok {
+				return errors.New("json-write takes hash tables keyed by symbols or strings")
+			}
+		}
+		// In order of their keys, so that the same table always writes the same
+		sort.Slice(entries, func(i, j int) bool {
+			ki, _ := jsonKey(entries[i].key)
+			kj, _ := jsonKey(entries[j].key)
+			return ki < kj
+		})
+		for i, entry := range entries {
+			keys[i], _ = jsonKey(entry.key)
+			values[i] = entry.value
+		}
+		return jw.object(keys, values)
+	}
+	return fmt.Errorf("json-write cannot write %s", ValueString(v, false))
+}
+
+func FnJsonWrite(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("json-write takes 1 or 2 arguments")
+	}
+
+	v := stack.Pop()
+	port, err := outputPort("json-write", nargs, 1)
+	if err != nil {
+		return err
+	}
+	jw := &jsonWriter{inside: map[interface{}]bool{}}
+	if err := jw.write(v); err != nil {
+		return err
+	}
+	fmt.Fprint(port, jw.sb.String())
+	stack.Push(v)
+	return nil
+}
+
+func FnJsonWriteString(nargs int) error {
+	if nargs != 1 {
+		return errors.New("json-write-string takes 1 argument")
+	}
+
+	jw := &jsonWriter{inside: map[interface{}]bool{}}
+	if err := jw.write(stack.Pop()); err != nil {
+		return err
+	}
+	stack.Push(NewString(jw.sb.String()))
+	return nil
+}
+
+func FnJsonNull(nargs int) error {
+	if nargs != 0 {
+		return errors.New("json-null takes no arguments")
+	}
+
+	stack.Push(JSONNull{})
+	return nil
+}
+
+func FnIsJsonNull(nargs int) error {
+	if nargs != 1 {
+		return errors.New("json-null? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(JSONNull)
+	stack.Push(Boolean(ok))
+	return nil
+}
@@ -0,0 +1,145 @@
+package scheme
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limits bounds the work done by code run with Run, so that code that isn't
+// trusted can be stopped however it misbehaves.  A zero field is no limit.
+type Limits struct {
+	Context      context.Context // Stops evaluation once it is done
+	Instructions int64           // Instructions executed
+	StackDepth   int             // Values on the stack at once
+	CallDepth    int             // Nested calls, not counting tail calls
+	Allocation   uint64          // Bytes reserved by builtins that allocate
+}
+
+// LimitError is returned when code goes over one of its limits.  Err is the
+// context's error when it was the context that stopped it.  Handlers and
+// guards don't see it, so code can't carry on past its limits.
+type LimitError struct {
+	Limit string
+	Err   error
+}
+
+func (e *LimitError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Limit exceeded: %s (%v)", e.Limit, e.Err)
+	}
+	return "Limit exceeded: " + e.Limit
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// The context is only checked every limitInterval instructions, as it is more
+// work to look at than the other limits.
+const limitInterval = 1024
+
+// About how many bytes the items that builtins reserve take.
+const (
+	valueSize = 16               // A Value in a vector
+	pairSize  = 16 + 2*valueSize // A pair, with the car and cdr it points to
+	runeSize  = 4                // A character in a string
+)
+
+// limiter holds the limits in force and how much of them has been used.
+type limiter struct {
+	Limits
+	instructions int64
+	depth        int    // callDepth when Run was called
+	allocated    uint64 // Bytes reserved so far, never over Allocation
+	exceeded     *LimitError
+}
+
+// limits is what Run put in force, or nil outside of Run.
+var limits *limiter
+
+// Run calls fn with l in force for everything it evaluates, in place of any
+// limits already in force.  If fn went over a limit, the values it left on the
+// stack are dropped and a *LimitError is returned.
+func (l Limits) Run(fn func() error) error {
+	lim := &limiter{Limits: l, depth: callDepth}
+
+	saved, stack_pos := limits, len(stack)
+	limits = lim
+	defer func() { limits = saved }()
+
+	err := fn()
+
+	if lim.exceeded != nil {
+		if len(stack) > stack_pos {
+			stack = stack[:stack_pos]
+		}
+		return lim.exceeded
+	}
+	return err
+}
+
+// step is called by Eval before each instruction, and returns an error once a
+// limit has been gone over, and for every instruction after that.
+func (l *limiter) step() error {
+	if l.exceeded != nil {
+		return l.exceeded
+	}
+
+	l.instructions++
+	switch {
+	case l.Instructions > 0 && l.instructions > l.Instructions:
+		l.exceeded = &LimitError{Limit: "instructions"}
+	case l.StackDepth > 0 && len(stack) > l.StackDepth:
+		l.exceeded = &LimitError{Limit: "stack depth"}
+	case l.CallDepth > 0 && callDepth-l.depth > l.CallDepth:
+		l.exceeded = &LimitError{Limit: "call depth"}
+	case l.instructions%limitInterval != 0:
+		return nil
+	default:
+		return l.poll()
+	}
+	return l.exceeded
+}
+
+// poll checks the limits that step only checks every limitInterval
+// instructions.
+func (l *limiter) poll() error {
+	switch {
+	case l.exceeded != nil:
+	case l.Context != nil && l.Context.Err() != nil:
+		l.exceeded = &LimitError{Limit: "context", Err: l.Context.Err()}
+	default:
+		return nil
+	}
+	return l.exceeded
+}
+
+// pollLimits is for builtins to call every limitInterval times round a loop
+// that may run for long, as Eval doesn't check the limits until they return.
+func pollLimits() error {
+	if limits == nil {
+		return nil
+	}
+	return limits.poll()
+}
+
+// reserve is called by builtins before they allocate n items of size bytes,
+// and counts them against the allocation limit.  It returns an error if they
+// would go over it, so that they are never asked for.  Only what is reserved
+// counts, so every builtin whose allocation grows with its arguments must
+// reserve it.
+func reserve(n int, size uint64) error {
+	l := limits
+	if l == nil || l.Allocation == 0 || n <= 0 {
+		return nil
+	}
+	if l.exceeded != nil {
+		return l.exceeded
+	}
+	if uint64(n) > (l.Allocation-l.allocated)/size {
+		l.exceeded = &LimitError{Limit: "allocation"}
+		return l.exceeded
+	}
+	l.allocated += uint64(n) * size
+	return nil
+}
@@ -1,4 +1,4 @@
-package main
+package scheme
 
 import (
 	"errors"
@@ -18,6 +18,9 @@ func FnCons(nargs int) error {
 	if nargs != 2 {
 		return errors.New("Wrong arg count to cons")
 	}
+	if err := reserve(1, pairSize); err != nil {
+		return err
+	}
 	obj1 := stack.Pop()
 	obj2 := stack.Pop()
 	stack.Push(&Pair{&obj1, &obj2})
@@ -131,15 +134,15 @@ func FnApply(nargs int) error {
 	}
 	stack.Push(proc)
 
-	call := Procedure{
+	call := &Procedure{
 		Scope: Top.Scope,
 		Ins:   []Ins{{Call, nil, len(args)}},
 	}
-	return call.Eval()
+	return &enter{call, len(args) + 1}
 }
 
 func FnVector2List(nargs int) error {
-	if nargs != 1 {
+	if nargs < 1 || nargs > 3 {
 		return errors.New("Wrong arg count to vector->list")
 	}
 
@@ -147,25 +150,122 @@ func FnVector2List(nargs int) error {
 	if !ok {
 		return errors.New("vector->list takes a vector as the argument")
 	}
-	stack.Push(vec2list(*l.v))
+	start, end, err := popRange("vector->list", nargs, 1, len(*l.v))
+	if err != nil {
+		return err
+	}
+	if err := reserve(end-start, pairSize); err != nil {
+		return err
+	}
+	stack.Push(vec2list((*l.v)[start:end]))
 	return nil
 }
 
 func FnString2List(nargs int) error {
-	if nargs != 1 {
-		return errors.New("string->list takes 1 argument")
+	if nargs < 1 || nargs > 3 {
+		return errors.New("string->list takes 1 to 3 arguments")
 	}
 	str, ok := stack.Pop().(String)
 	if !ok {
 		return errors.New("string->list takes a string as the argument")
 	}
+	rs := []rune(str.String())
+	start, end, err := popRange("string->list", nargs, 1, len(rs))
+	if err != nil {
+		return err
+	}
+	if err := reserve(end-start, valueSize+pairSize); err != nil {
+		return err
+	}
 
 	v := []Value{}
-	rs := []rune(*str.s)
-	for i := range rs {
+	for i := start; i < end; i++ {
 		v = append(v, Char(rs[i]))
 	}
 	stack.Push(vec2list(v))
 	return nil
 }
 
+// Uses Floyd's cycle detection, so that circular lists are not lists
+func FnIsList(nargs int) error {
+	if nargs != 1 {
+		return errors.New("list? takes 1 argument")
+	}
+
+	slow := stack.Pop()
+	fast := slow
+	for {
+		for i := 0; i < 2; i++ {
+			p, ok := fast.(*Pair)
+			if !ok {
+				stack.Push(Boolean(false))
+				return nil
+			}
+			if p == Empty {
+				stack.Push(Boolean(true))
+				return nil
+			}
+			fast = *p.Cdr
+		}
+		slow = *slow.(*Pair).Cdr
+		if fast == slow {
+			stack.Push(Boolean(false))
+			return nil
+		}
+	}
+}
+
+func FnMakeList(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("make-list takes 1 or 2 arguments")
+	}
+
+	k, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("make-list takes a length as the first argument")
+	}
+	var fill Value = Boolean(false)
+	if nargs == 2 {
+		fill = stack.Pop()
+	}
+
+	if err := reserve(k, valueSize+pairSize); err != nil {
+		return err
+	}
+	v := make([]Value, k)
+	for i := range v {
+		if i%limitInterval == 0 {
+			if err := pollLimits(); err != nil {
+				return err
+			}
+		}
+		v[i] = fill
+	}
+	stack.Push(vec2list(v))
+	return nil
+}
+
+func FnListSet(nargs int) error {
+	if nargs != 3 {
+		return errors.New("list-set! takes 3 arguments")
+	}
+
+	list := stack.Pop()
+	k, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("list-set! takes an index as the second argument")
+	}
+	obj := stack.Pop()
+
+	cur, ok := list.(*Pair)
+	for ; ok && cur != Empty && k > 0; k-- {
+		cur, ok = (*cur.Cdr).(*Pair)
+	}
+	if !ok || cur == Empty {
+		return errors.New("list-set!: index out of range")
+	}
+
+	*cur.Car = obj
+	stack.Push(list)
+	return nil
+}
@@ -0,0 +1,149 @@
+package scheme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Directories of the files currently being loaded, innermost last.  Relative
+// names passed to load are resolved against the top entry before G5_PATH.
+var LoadDirStack = []string{}
+
+// A file loaded into a particular environment.
+type LoadKey struct {
+	Env  *Procedure
+	Path string
+}
+
+// Every file loaded so far, by environment and absolute path, so that each is
+// only evaluated once per environment.  Entries are added before evaluation,
+// which also breaks load cycles, and removed again if loading fails so that a
+// later load can try again.
+var Loaded = map[LoadKey]bool{}
+
+// LoadPath returns the directories listed in G5_PATH, in search order.
+func LoadPath() []string {
+	return filepath.SplitList(os.Getenv("G5_PATH"))
+}
+
+// ResolveLoad finds the file that a call to load refers to.  Absolute names
+// are used as-is; relative ones are tried against the directory of the file
+// currently being loaded (or the working directory at top level), then against
+// each G5_PATH entry.
+func ResolveLoad(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err != nil {
+			return "", err
+		}
+		return filepath.Clean(name), nil
+	}
+
+	dirs := []string{"."}
+	if len(LoadDirStack) > 0 {
+		dirs[0] = LoadDirStack[len(LoadDirStack)-1]
+	}
+	dirs = append(dirs, LoadPath()...)
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return filepath.Abs(path)
+		}
+	}
+	return "", fmt.Errorf("Could not find file to load: %s", name)
+}
+
+// Exec evaluates every form in code within env, stopping at the first error.
+// Unlike Run, it leaves env's own instructions alone, so it is safe to call
+// from a builtin while env is still executing.
+func (env *Procedure) Exec(code string) error {
+	p := NewParser(code)
+	p.skipWs()
+
+	for len(p.data) > 0 {
+		v, err := p.GetValue()
+		p.skipWs()
+		if err != nil {
+			return err
+		}
+
+		ctx := &Procedure{Scope: env.Scope, Macros: env.Macros}
+		if err := ctx.GenForm(v); err != nil {
+			return err
+		}
+		if err := ctx.Eval(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile resolves name and evaluates the file within env, which may hold
+// either source code or a compiled image.  It returns false without evaluating
+// anything if the file has already been loaded into env.
+func (env *Procedure) LoadFile(name string) (bool, error) {
+	path, err := ResolveLoad(name)
+	if err != nil {
+		return false, err
+	}
+
+	key := LoadKey{env, path}
+	if Loaded[key] {
+		return false, nil
+	}
+	Loaded[key] = true
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		delete(Loaded, key)
+		return false, err
+	}
+
+	LoadDirStack = append(LoadDirStack, filepath.Dir(path))
+	if bytes.HasPrefix(b, []byte(ImageMagic)) {
+		var img *Image
+		if img, err = DecodeImage(b); err == nil {
+			err = env.Replay(img)
+		}
+	} else {
+		err = env.Exec(string(b))
+	}
+	LoadDirStack = LoadDirStack[:len(LoadDirStack)-1]
+	if err != nil {
+		delete(Loaded, key)
+		return false, fmt.Errorf("%s: %v", path, err)
+	}
+	return true, nil
+}
+
+func FnLoad(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("load takes 1 or 2 arguments")
+	}
+
+	fname, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("load takes a string as the first argument")
+	}
+
+	env := Top
+	if nargs == 2 {
+		env, ok = stack.Pop().(*Procedure)
+		if !ok {
+			return errors.New("load takes an environment as the second argument")
+		}
+	}
+
+	stack_pos := len(stack)
+	loaded, err := env.LoadFile(fname.String())
+	stack = stack[:stack_pos]
+	if err != nil {
+		return err
+	}
+
+	stack.Push(Boolean(loaded))
+	return nil
+}
@@ -0,0 +1,206 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+func FnNot(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to not")
+	}
+
+	switch val := stack.Pop(); val.(type) {
+	case Boolean:
+		stack.Push(!val.(Boolean))
+	default:
+		stack.Push(Boolean(false))
+	}
+	return nil
+}
+
+func FnEqv(nargs int) error {
+	obj1, obj2 := stack.Pop(), stack.Pop()
+
+	// The eqv? procedure returns #f if:
+	// - obj1 and obj2 are of different types
+	if reflect.TypeOf(obj1) != reflect.TypeOf(obj2) {
+		stack.Push(Boolean(false))
+		return nil
+	}
+
+	// The eqv? procedure returns #t if:
+	switch obj1.(type) {
+	case String:
+		// Literals share the store of the strings they were made from
+		stack.Push(Boolean(obj1.(String).r == obj2.(String).r))
+		return nil
+	case *GoValue:
+		// Wrappers of the same Go value are the same object
+		stack.Push(Boolean(obj1.(*GoValue).identity() == obj2.(*GoValue).identity()))
+		return nil
+	case Boolean, Char, *Procedure, Vector, Bytevector, InputPort,
+		OutputPort, *ErrorObject, *HashTable, *Record, *RecordType, *Regexp,
+		Eof, JSONNull, *Scope:
+		// obj1 and obj2 are both #t or both #f.
+
+		// obj1 and obj2 are both characters and are the same character
+		// according to the char=? procedure
+
+		// obj1 and obj2 are procedures whose location tags are equal
+
+		// obj1 and obj2 are strings, vectors, bytevectors or other objects
+		// that denote the same locations in the store
+		stack.Push(Boolean(obj1 == obj2))
+		return nil
+	case Symbol:
+		// obj1 and obj2 are both symbols and are the same symbol according
+		// to the symbol=? procedure.  Interned symbols with the same name are
+		// the same symbol; an uninterned one is only the same as itself.
+		stack.Push(Boolean(obj1 == obj2))
+		return nil
+	case Integer, Rational:
+		// obj1 and obj2 are both numbers, are numerically equal,
+		// and are either both exact or both inexact.
+		// **Note: we don't have inexact numbers**
+		stack.Push(obj1)
+		stack.Push(obj2)
+		FnNumEq(2)
+		return nil
+	case *Pair:
+		// both obj1 and obj2 are the empty list.
+		if obj1 == Empty && obj2 == Empty {
+			stack.Push(Boolean(true))
+			return nil
+		}
+		// obj1 and obj2 are pairs, vectors, or strings that denote the same
+		// locations in the store
+		stack.Push(Boolean(obj1 == obj2))
+		return nil
+	}
+	stack.Push(Boolean(false))
+	return nil
+}
+
+func FnEqual(nargs int) error {
+	obj1, obj2 := stack.Pop(), stack.Pop()
+	stack.Push(Boolean(IsEqual(obj1, obj2)))
+	return nil
+}
+
+func FnIsBoolean(nargs int) error {
+	if nargs != 1 {
+		return errors.New("boolean? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(Boolean)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnBooleanEq(nargs int) error {
+	if nargs < 2 {
+		return errors.New("boolean=? takes at least 2 arguments")
+	}
+
+	first, ok := stack.Pop().(Boolean)
+	if !ok {
+		return errors.New("boolean=? takes booleans as arguments")
+	}
+	res := true
+	for i := 1; i < nargs; i++ {
+		b, ok := stack.Pop().(Boolean)
+		if !ok {
+			return errors.New("boolean=? takes booleans as arguments")
+		}
+		res = res && b == first
+	}
+	stack.Push(Boolean(res))
+	return nil
+}
+
+func FnIsSymbol(nargs int) error {
+	if nargs != 1 {
+		return errors.New("symbol? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(Symbol)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnString2UninternedSymbol(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string->uninterned-symbol takes 1 argument")
+	}
+
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New(
+			"string->uninterned-symbol takes a string as the argument",
+		)
+	}
+	stack.Push(Uninterned(str.String()))
+	return nil
+}
+
+var gensymCount = 0
+
+// FnGensym returns a fresh uninterned symbol, named after the optional prefix
+// and a counter so that generated code stays readable.
+func FnGensym(nargs int) error {
+	if nargs > 1 {
+		return errors.New("gensym takes at most 1 argument")
+	}
+
+	prefix := "g"
+	if nargs == 1 {
+		switch v := stack.Pop().(type) {
+		case String:
+			prefix = v.String()
+		case Symbol:
+			prefix = SymbolNames[v]
+		default:
+			return errors.New("gensym takes a string or symbol as the prefix")
+		}
+	}
+
+	gensymCount++
+	stack.Push(Uninterned(fmt.Sprintf("%s%d", prefix, gensymCount)))
+	return nil
+}
+
+func FnIsSymbolInterned(nargs int) error {
+	if nargs != 1 {
+		return errors.New("symbol-interned? takes 1 argument")
+	}
+
+	sym, ok := stack.Pop().(Symbol)
+	if !ok {
+		return errors.New("symbol-interned? takes a symbol as the argument")
+	}
+	stack.Push(Boolean(IsInterned(sym)))
+	return nil
+}
+
+func FnSymbolEq(nargs int) error {
+	if nargs < 2 {
+		return errors.New("symbol=? takes at least 2 arguments")
+	}
+
+	first, ok := stack.Pop().(Symbol)
+	if !ok {
+		return errors.New("symbol=? takes symbols as arguments")
+	}
+	res := true
+	for i := 1; i < nargs; i++ {
+		sym, ok := stack.Pop().(Symbol)
+		if !ok {
+			return errors.New("symbol=? takes symbols as arguments")
+		}
+		res = res && sym == first
+	}
+	stack.Push(Boolean(res))
+	return nil
+}
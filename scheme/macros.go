@@ -1,6 +1,7 @@
-package main
+package scheme
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -77,32 +78,67 @@ func ParseSyntaxRules(vp Value) (*SyntaxRules, error) {
 }
 
 func IsEqual(v1 Value, v2 Value) bool {
+	return isEqual(v1, v2, map[[2]interface{}]bool{})
+}
+
+// isEqual compares v1 and v2 given the pairs and vectors already compared,
+// which it takes to be equal.  That stops it looping on cyclic structure, and
+// if any of them turn out not to be equal, neither are v1 and v2.
+func isEqual(v1 Value, v2 Value, compared map[[2]interface{}]bool) bool {
 	if reflect.TypeOf(v1) != reflect.TypeOf(v2) {
 		return false
 	}
 
 	switch v1.(type) {
-	case Boolean, Symbol, Char, *Procedure, *Scope:
+	case Boolean, Symbol, Char, *Procedure, *Scope, InputPort, OutputPort,
+		*ErrorObject, *HashTable, *Record, *RecordType, *Regexp, Eof,
+		JSONNull:
 		return v1 == v2
+	case *GoValue:
+		return v1.(*GoValue).identity() == v2.(*GoValue).identity()
+	case Bytevector:
+		return bytes.Equal(*v1.(Bytevector).b, *v2.(Bytevector).b)
 	case String:
-		return *v1.(String).s == *v2.(String).s
+		return equalRunes(*v1.(String).r, *v2.(String).r)
 	case Vector:
 		if len(*v1.(Vector).v) != len(*v2.(Vector).v) {
 			return false
 		}
 
+		key := [2]interface{}{v1.(Vector).v, v2.(Vector).v}
+		if compared[key] {
+			return true
+		}
+		compared[key] = true
+
 		for i := range *v1.(Vector).v {
-			if !IsEqual((*v1.(Vector).v)[i], (*v2.(Vector).v)[i]) {
+			if !isEqual((*v1.(Vector).v)[i], (*v2.(Vector).v)[i], compared) {
 				return false
 			}
 		}
 		return true
 	case *Pair:
-		if v1 == Empty || v2 == Empty {
-			return v1 == Empty && v2 == Empty
+		for { // Along the cdrs, so that long lists don't recurse deeply
+			p1, ok1 := v1.(*Pair)
+			p2, ok2 := v2.(*Pair)
+			if !ok1 || !ok2 {
+				return isEqual(v1, v2, compared)
+			}
+			if p1 == Empty || p2 == Empty {
+				return p1 == p2
+			}
+
+			key := [2]interface{}{p1, p2}
+			if compared[key] {
+				return true
+			}
+			compared[key] = true
+
+			if !isEqual(*p1.Car, *p2.Car, compared) {
+				return false
+			}
+			v1, v2 = *p1.Cdr, *p2.Cdr
 		}
-		return IsEqual(*v1.(*Pair).Car, *v2.(*Pair).Car) &&
-			IsEqual(*v1.(*Pair).Cdr, *v2.(*Pair).Cdr)
 	case Integer:
 		b1, b2 := big.Int(v1.(Integer)), big.Int(v2.(Integer))
 		return b1.Cmp(&b2) == 0
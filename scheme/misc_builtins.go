@@ -1,7 +1,8 @@
-package main
+package scheme
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"math/big"
 	"strings"
@@ -70,12 +71,13 @@ func FnEval(nargs int) error {
 	if !ok {
 		return errors.New("eval takes a procedure for the environment")
 	}
-	env.Ins = []Ins{}
-	if err := env.Gen(expr); err != nil {
+	// Evaluate in a fresh procedure, since env may be the one that is
+	// running this call
+	ctx := &Procedure{Scope: env.Scope, Macros: env.Macros}
+	if err := ctx.GenForm(expr); err != nil {
 		return err
 	}
-	env.Eval()
-	return nil
+	return ctx.Eval()
 }
 
 func FnIsProcedure(nargs int) error {
@@ -84,28 +86,49 @@ func FnIsProcedure(nargs int) error {
 	return nil
 }
 
-func FnCallCC(p *Procedure, nargs int) error {
-	p.IsCont = true
+func FnDisassemble(nargs int) error {
+	if nargs != 1 {
+		return errors.New("disassemble takes 1 argument")
+	}
+
+	proc, ok := stack.Pop().(*Procedure)
+	if !ok {
+		return errors.New("disassemble takes a procedure as the argument")
+	}
+
+	port := OutputPortStack[len(OutputPortStack)-1]
+	switch {
+	case proc.Builtin != nil || proc.CallCC != nil:
+		fmt.Fprintln(port, "[builtin]")
+	case proc.Cont != nil:
+		fmt.Fprintln(port, "[continuation]")
+	default:
+		Ins{Lambda, *proc, 0}.Print(0, 0)
+	}
+
+	stack.Push(proc)
+	return nil
+}
+
+// FnCallCC calls its argument with k, the continuation of the call to
+// call/cc, which Eval captured.
+func FnCallCC(k *Procedure, nargs int) error {
 	proc := stack.Pop()
 	vec := []Value{}
-	for i := 0; i < nargs; i++ {
-		vec = append(vec)
+	for i := 1; i < nargs; i++ {
+		vec = append(vec, stack.Pop())
 	}
-	vec = append(vec, p)
+	vec = append(vec, k)
 
-	p.StackRest = &Stack{}
-	*p.StackRest = stack
 	for i := len(vec) - 1; i >= 0; i-- {
 		stack.Push(vec[i])
 	}
-
 	stack.Push(proc)
-	call := Procedure{
-		Scope: p.Scope,
+	call := &Procedure{
+		Scope: Top.Scope,
 		Ins:   []Ins{{Call, nil, nargs}},
 	}
-
-	return call.Eval()
+	return &enter{call, nargs + 1}
 }
 
 func FnExit(nargs int) error {
@@ -115,12 +138,17 @@ func FnExit(nargs int) error {
 
 	code := 0
 	if nargs == 1 {
-		v, ok := stack.Pop().(Integer)
-		if !ok {
-			return errors.New("exit takes an integer as the arg")
+		switch v := stack.Pop().(type) {
+		case Integer:
+			bi := big.Int(v)
+			code = int(bi.Int64())
+		case Boolean:
+			if !v {
+				code = 1
+			}
+		default:
+			return errors.New("exit takes an integer or boolean as the arg")
 		}
-		bi := big.Int(v)
-		code = int(bi.Int64())
 	}
 
 	os.Exit(code)
@@ -136,14 +164,14 @@ func FnGetEnvironmentVariables(nargs int) error {
 
 	for _, e := range os.Environ() {
 		kv := strings.SplitN(e, "=", 2)
-		var k Value = String{&kv[0]}
+		var k Value = NewString(kv[0])
 
 		var v Value
 		if len(kv) == 2 {
-			v = String{&kv[1]}
+			v = NewString(kv[1])
 		} else {
 			s := ""
-			v = String{&s}
+			v = NewString(s)
 		}
 		env_vals = append(env_vals, &Pair{&k, &v})
 	}
@@ -156,40 +184,49 @@ func FnDynamicWind(nargs int) error {
 		return errors.New("dynamic-wind takes 3 arguments")
 	}
 
-	before, ok := stack.Pop().(*Procedure)
-	if !ok {
-		return errors.New("dynamic-wind takes procedure arguments")
+	before, thunk, after := stack.Pop(), stack.Pop(), stack.Pop()
+	for _, v := range []Value{before, thunk, after} {
+		if _, ok := v.(*Procedure); !ok {
+			return errors.New("dynamic-wind takes procedure arguments")
+		}
 	}
-	thunk, ok := stack.Pop().(*Procedure)
-	if !ok {
-		return errors.New("dynamic-wind takes procedure arguments")
+
+	if _, err := Apply(before); err != nil {
+		return err
 	}
-	after, ok := stack.Pop().(*Procedure)
-	if !ok {
-		return errors.New("dynamic-wind takes procedure arguments")
-	}
-
-	thunk.Ins = append(
-		[]Ins{
-			{Imm, before, 0},
-			{Call, nil, 0},
-		},
-		thunk.Ins...,
-	)
-
-	thunk.Ins = append(
-		thunk.Ins,
-		[]Ins{
-			{Imm, after, 0},
-			{Call, nil, 0},
-		}...,
-	)
-
-	return thunk.Eval()
+	w := &windFrame{before, after, winding, windDepth(winding) + 1}
+	winding = w
+
+	// The thunk is entered rather than applied, so that continuations can
+	// leave and come back into it.  Its values are passed through unwind,
+	// which calls after once it returns, and Eval calls after if it fails
+	unwind := &Procedure{Name: "dynamic-wind", Builtin: func(nargs int) error {
+		values := make([]Value, nargs)
+		for i := range values {
+			values[i] = stack.Pop()
+		}
+		winding = w.parent
+		if _, err := Apply(after); err != nil {
+			return err
+		}
+		for i := len(values) - 1; i >= 0; i-- {
+			stack.Push(values[i])
+		}
+		return FnValues(nargs)
+	}}
+	stack.Push(thunk)
+	call := &Procedure{Scope: Top.Scope, Ins: []Ins{
+		{Call, nil, 0},
+		{Imm, unwind, 0},
+		{Call, nil, -1},
+	}}
+	return &enter{call, 1}
 }
 
 func FnValues(nargs int) error {
-	stack.Push(Integer(*big.NewInt(int64(nargs))))
+	if nargs != 1 {
+		stack.Push(MultipleValues(nargs))
+	}
 	return nil
 }
 
@@ -207,12 +244,11 @@ func FnCallWithValues(nargs int) error {
 		return errors.New("call-with-values takes procedures as the args")
 	}
 
-	producer.Ins = append(producer.Ins,
-		[]Ins{
-			{Imm, consumer, 0},
-			{Call, nil, -1},
-		}...,
-	)
-
-	return producer.Eval()
+	stack.Push(producer)
+	call := &Procedure{Scope: Top.Scope, Ins: []Ins{
+		{Call, nil, 0},
+		{Imm, consumer, 0},
+		{Call, nil, -1},
+	}}
+	return &enter{call, 1}
 }
@@ -0,0 +1,862 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// toRat returns a copy of a number's value.
+func toRat(v Value) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case Integer:
+		i := big.Int(v)
+		return new(big.Rat).SetInt(&i), true
+	case Rational:
+		r := big.Rat(v)
+		return new(big.Rat).Set(&r), true
+	}
+	return nil, false
+}
+
+// toInt returns a copy of an integer's value, which may be held by a
+// Rational.
+func toInt(v Value) (*big.Int, bool) {
+	switch v := v.(type) {
+	case Integer:
+		i := big.Int(v)
+		return new(big.Int).Set(&i), true
+	case Rational:
+		r := big.Rat(v)
+		if r.IsInt() {
+			return new(big.Int).Set(r.Num()), true
+		}
+	}
+	return nil, false
+}
+
+// ratValue returns r as an Integer if it is whole, or a Rational otherwise.
+func ratValue(r *big.Rat) Value {
+	if r.IsInt() {
+		return Integer(*new(big.Int).Set(r.Num()))
+	}
+	return Rational(*r)
+}
+
+func FnAdd(nargs int) error {
+	total := big.Rat{}
+	for ; nargs > 0; nargs-- {
+		n := stack.Pop()
+		x, ok := toRat(n)
+		if !ok {
+			return fmt.Errorf("Non-numeric argument to + (%T)", n)
+		}
+		total.Add(&total, x)
+	}
+
+	stack.Push(ratValue(&total))
+	return nil
+}
+
+func FnSub(nargs int) error {
+	if nargs == 0 {
+		return errors.New("Too few args: -")
+	}
+
+	n := stack.Pop()
+	total, ok := toRat(n)
+	if !ok {
+		return fmt.Errorf("Non-numeric argument to - (%T)", n)
+	}
+	if nargs == 1 {
+		stack.Push(ratValue(total.Neg(total)))
+		return nil
+	}
+
+	for nargs--; nargs > 0; nargs-- {
+		n := stack.Pop()
+		x, ok := toRat(n)
+		if !ok {
+			return fmt.Errorf("Non-numeric argument to - (%T)", n)
+		}
+		total.Sub(total, x)
+	}
+
+	stack.Push(ratValue(total))
+	return nil
+}
+
+func FnMul(nargs int) error {
+	total := big.NewRat(1, 1)
+	for ; nargs > 0; nargs-- {
+		n := stack.Pop()
+		x, ok := toRat(n)
+		if !ok {
+			return fmt.Errorf("Non-numeric argument to * (%T)", n)
+		}
+		total.Mul(total, x)
+	}
+
+	stack.Push(ratValue(total))
+	return nil
+}
+
+func FnDiv(nargs int) error {
+	if nargs == 0 {
+		return errors.New("Too few args: /")
+	}
+
+	n := stack.Pop()
+	total, ok := toRat(n)
+	if !ok {
+		return fmt.Errorf("Non-numeric argument to / (%T)", n)
+	}
+	if nargs == 1 {
+		if total.Sign() == 0 {
+			return errors.New("Division by zero")
+		}
+		stack.Push(ratValue(total.Inv(total)))
+		return nil
+	}
+
+	for nargs--; nargs > 0; nargs-- {
+		n := stack.Pop()
+		x, ok := toRat(n)
+		if !ok {
+			return fmt.Errorf("Non-numeric argument to / (%T)", n)
+		}
+		if x.Sign() == 0 {
+			return errors.New("Division by zero")
+		}
+		total.Quo(total, x)
+	}
+
+	stack.Push(ratValue(total))
+	return nil
+}
+
+// compare implements the numeric comparisons, which hold if accept is true of
+// the result of comparing each argument with the next.  All of the arguments
+// are popped, even once the result is known.
+func compare(name string, nargs int, accept func(int) bool) error {
+	res := true
+	var last *big.Rat
+	for ; nargs > 0; nargs-- {
+		n := stack.Pop()
+		x, ok := toRat(n)
+		if !ok {
+			return fmt.Errorf("Non-numeric argument to %s (%T)", name, n)
+		}
+		if last != nil && !accept(last.Cmp(x)) {
+			res = false
+		}
+		last = x
+	}
+
+	stack.Push(Boolean(res))
+	return nil
+}
+
+func FnGt(nargs int) error {
+	return compare(">", nargs, func(c int) bool { return c > 0 })
+}
+
+func FnLt(nargs int) error {
+	return compare("<", nargs, func(c int) bool { return c < 0 })
+}
+
+func FnGe(nargs int) error {
+	return compare(">=", nargs, func(c int) bool { return c >= 0 })
+}
+
+func FnLe(nargs int) error {
+	return compare("<=", nargs, func(c int) bool { return c <= 0 })
+}
+
+func FnNumEq(nargs int) error {
+	if nargs == 0 {
+		return errors.New("Too few args: =")
+	}
+	return compare("=", nargs, func(c int) bool { return c == 0 })
+}
+
+// extremum implements min and max, keeping whichever argument better is true
+// of when compared with the best so far.
+func extremum(name string, nargs int, better func(int) bool) error {
+	if nargs == 0 {
+		return fmt.Errorf("%s takes at least 1 argument", name)
+	}
+
+	var best Value
+	var bestRat *big.Rat
+	for ; nargs > 0; nargs-- {
+		n := stack.Pop()
+		x, ok := toRat(n)
+		if !ok {
+			return fmt.Errorf("Non-numeric argument to %s (%T)", name, n)
+		}
+		if best == nil || better(x.Cmp(bestRat)) {
+			best, bestRat = n, x
+		}
+	}
+
+	stack.Push(best)
+	return nil
+}
+
+func FnMin(nargs int) error {
+	return extremum("min", nargs, func(c int) bool { return c < 0 })
+}
+
+func FnMax(nargs int) error {
+	return extremum("max", nargs, func(c int) bool { return c > 0 })
+}
+
+func FnAbs(nargs int) error {
+	if nargs != 1 {
+		return errors.New("abs takes 1 argument")
+	}
+
+	n := stack.Pop()
+	x, ok := toRat(n)
+	if !ok {
+		return fmt.Errorf("Non-numeric argument to abs (%T)", n)
+	}
+	stack.Push(ratValue(x.Abs(x)))
+	return nil
+}
+
+func FnIsNumber(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to number?")
+	}
+
+	switch v := stack.Pop(); v.(type) {
+	case Integer, Rational:
+		stack.Push(Boolean(true))
+	default:
+		stack.Push(Boolean(false))
+	}
+	return nil
+}
+
+func FnIsComplex(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to complex?")
+	}
+
+	switch v := stack.Pop(); v.(type) {
+	case Integer, Rational:
+		stack.Push(Boolean(true))
+	default:
+		stack.Push(Boolean(false))
+	}
+	return nil
+}
+
+func FnIsReal(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to real?")
+	}
+
+	switch v := stack.Pop(); v.(type) {
+	case Integer, Rational:
+		stack.Push(Boolean(true))
+	default:
+		stack.Push(Boolean(false))
+	}
+	return nil
+}
+
+func FnIsRational(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to rational?")
+	}
+
+	switch v := stack.Pop(); v.(type) {
+	case Integer, Rational:
+		stack.Push(Boolean(true))
+	default:
+		stack.Push(Boolean(false))
+	}
+	return nil
+}
+
+func FnIsInteger(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to integer?")
+	}
+
+	_, ok := toInt(stack.Pop())
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnIsEven(nargs int) error {
+	if nargs != 1 {
+		return errors.New("even? takes 1 argument")
+	}
+
+	n, ok := toInt(stack.Pop())
+	if !ok {
+		return errors.New("even? takes an integer as the argument")
+	}
+	stack.Push(Boolean(n.Bit(0) == 0))
+	return nil
+}
+
+func FnIsOdd(nargs int) error {
+	if nargs != 1 {
+		return errors.New("odd? takes 1 argument")
+	}
+
+	n, ok := toInt(stack.Pop())
+	if !ok {
+		return errors.New("odd? takes an integer as the argument")
+	}
+	stack.Push(Boolean(n.Bit(0) == 1))
+	return nil
+}
+
+// divide pops the two integer arguments of an integer division and returns
+// their quotient and remainder, rounding the quotient towards negative
+// infinity if floor is set and towards zero otherwise.
+func divide(name string, nargs int, floor bool) (*big.Int, *big.Int, error) {
+	if nargs != 2 {
+		return nil, nil, fmt.Errorf("Wrong arg count to %s", name)
+	}
+
+	n1, ok1 := toInt(stack.Pop())
+	n2, ok2 := toInt(stack.Pop())
+	if !ok1 || !ok2 {
+		return nil, nil, fmt.Errorf("%s takes only integers", name)
+	}
+	if n2.Sign() == 0 {
+		return nil, nil, errors.New("Division by zero")
+	}
+
+	q, r := new(big.Int).QuoRem(n1, n2, new(big.Int))
+	if floor && r.Sign() != 0 && r.Sign() != n2.Sign() {
+		q.Sub(q, big.NewInt(1))
+		r.Add(r, n2)
+	}
+	return q, r, nil
+}
+
+func FnQuotient(nargs int) error {
+	q, _, err := divide("quotient", nargs, false)
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*q))
+	return nil
+}
+
+func FnRemainder(nargs int) error {
+	_, r, err := divide("remainder", nargs, false)
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*r))
+	return nil
+}
+
+func FnModulo(nargs int) error {
+	_, r, err := divide("modulo", nargs, true)
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*r))
+	return nil
+}
+
+func FnFloorDiv(nargs int) error {
+	q, r, err := divide("floor/", nargs, true)
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*r))
+	stack.Push(Integer(*q))
+	stack.Push(MultipleValues(2))
+	return nil
+}
+
+func FnFloorQuotient(nargs int) error {
+	q, _, err := divide("floor-quotient", nargs, true)
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*q))
+	return nil
+}
+
+func FnTruncateDiv(nargs int) error {
+	q, r, err := divide("truncate/", nargs, false)
+	if err != nil {
+		return err
+	}
+	stack.Push(Integer(*r))
+	stack.Push(Integer(*q))
+	stack.Push(MultipleValues(2))
+	return nil
+}
+
+func FnGcd(nargs int) error {
+	res := new(big.Int)
+	for ; nargs > 0; nargs-- {
+		n, ok := toInt(stack.Pop())
+		if !ok {
+			return errors.New("gcd takes only integers")
+		}
+		res.GCD(nil, nil, res, n.Abs(n))
+	}
+	stack.Push(Integer(*res))
+	return nil
+}
+
+func FnLcm(nargs int) error {
+	res := big.NewInt(1)
+	for ; nargs > 0; nargs-- {
+		n, ok := toInt(stack.Pop())
+		if !ok {
+			return errors.New("lcm takes only integers")
+		}
+		if n.Sign() == 0 {
+			res.SetInt64(0)
+			continue
+		}
+		if res.Sign() == 0 {
+			continue
+		}
+		gcd := new(big.Int).GCD(nil, nil, res, n.Abs(n))
+		res.Mul(res, n.Quo(n, gcd))
+	}
+	stack.Push(Integer(*res))
+	return nil
+}
+
+func FnExactIntegerSqrt(nargs int) error {
+	if nargs != 1 {
+		return errors.New("exact-integer-sqrt takes 1 argument")
+	}
+
+	n, ok := toInt(stack.Pop())
+	if !ok || n.Sign() < 0 {
+		return errors.New(
+			"exact-integer-sqrt takes a non-negative integer as the argument",
+		)
+	}
+
+	s := new(big.Int).Sqrt(n)
+	r := new(big.Int).Sub(n, new(big.Int).Mul(s, s))
+	stack.Push(Integer(*r))
+	stack.Push(Integer(*s))
+	stack.Push(MultipleValues(2))
+	return nil
+}
+
+func FnNumerator(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to numerator")
+	}
+	n, ok := toRat(stack.Pop())
+	if !ok {
+		return errors.New("numerator only takes rationals")
+	}
+	stack.Push(Integer(*n.Num()))
+	return nil
+}
+
+func FnDenominator(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to denominator")
+	}
+	n, ok := toRat(stack.Pop())
+	if !ok {
+		return errors.New("denominator only takes rationals")
+	}
+	stack.Push(Integer(*n.Denom()))
+	return nil
+}
+
+// round pops the argument of one of the rounding procedures and passes its
+// floor, along with the remainder that was dropped, to adjust.
+func round(name string, nargs int, adjust func(q, m, den *big.Int)) error {
+	if nargs != 1 {
+		return fmt.Errorf("Wrong arg count to %s", name)
+	}
+	n, ok := toRat(stack.Pop())
+	if !ok {
+		return fmt.Errorf("%s only takes rationals", name)
+	}
+
+	// Denominators are always positive, so this division rounds down
+	q, m := new(big.Int).DivMod(n.Num(), n.Denom(), new(big.Int))
+	if m.Sign() != 0 {
+		adjust(q, m, n.Denom())
+	}
+	stack.Push(Integer(*q))
+	return nil
+}
+
+func FnFloor(nargs int) error {
+	return round("floor", nargs, func(q, m, den *big.Int) {})
+}
+
+func FnCeiling(nargs int) error {
+	return round("ceiling", nargs, func(q, m, den *big.Int) {
+		q.Add(q, big.NewInt(1))
+	})
+}
+
+func FnTruncate(nargs int) error {
+	return round("truncate", nargs, func(q, m, den *big.Int) {
+		if q.Sign() < 0 {
+			q.Add(q, big.NewInt(1))
+		}
+	})
+}
+
+// Halves round to even
+func FnRound(nargs int) error {
+	return round("round", nargs, func(q, m, den *big.Int) {
+		c := new(big.Int).Lsh(m, 1).Cmp(den)
+		if c > 0 || (c == 0 && q.Bit(0) == 1) {
+			q.Add(q, big.NewInt(1))
+		}
+	})
+}
+
+func FnChar2Integer(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to char->integer")
+	}
+
+	v := stack.Pop()
+	c, ok := v.(Char)
+	if !ok {
+		return fmt.Errorf("Got non-char to char->integer (%T)", v)
+	}
+	stack.Push(Integer(*big.NewInt(int64(c))))
+	return nil
+}
+
+func FnRExpt(nargs int) error {
+	if nargs != 2 {
+		return errors.New("rexpt takes 2 arguments")
+	}
+
+	var n float64
+	nv := stack.Pop()
+	nr, ok := nv.(Rational)
+	if ok {
+		nbr := big.Rat(nr)
+		n, _ = nbr.Float64()
+	} else {
+		ni, ok := nv.(Integer)
+		if !ok {
+			return errors.New("rexpt takes a number as the first argument")
+		}
+		nbi := big.Int(ni)
+		n = float64(nbi.Int64())
+	}
+
+	pr := stack.Pop().(Rational)
+	pbr := big.Rat(pr)
+	p, _ := pbr.Float64()
+
+	res := big.Rat{}
+	res.SetFloat64(math.Pow(n, p))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+func FnLog(nargs int) error {
+	if nargs != 1 {
+		return errors.New("log takes 1 argument")
+	}
+
+	var n float64
+	nv := stack.Pop()
+	nr, ok := nv.(Rational)
+	if ok {
+		nbr := big.Rat(nr)
+		n, _ = nbr.Float64()
+	} else {
+		ni, ok := nv.(Integer)
+		if !ok {
+			return errors.New("log takes a number as the argument")
+		}
+		nbi := big.Int(ni)
+		n = float64(nbi.Int64())
+	}
+
+	if n <= 0 {
+		return errors.New("logarithm of non-positive number")
+	}
+
+	res := big.Rat{}
+	res.SetFloat64(math.Log(n))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+func FnSin(nargs int) error {
+	if nargs != 1 {
+		return errors.New("sin takes 1 argument")
+	}
+
+	var n float64
+	nv := stack.Pop()
+	nr, ok := nv.(Rational)
+	if ok {
+		nbr := big.Rat(nr)
+		n, _ = nbr.Float64()
+	} else {
+		ni, ok := nv.(Integer)
+		if !ok {
+			return errors.New("sin takes a number as the argument")
+		}
+		nbi := big.Int(ni)
+		n = float64(nbi.Int64())
+	}
+
+	res := big.Rat{}
+	res.SetFloat64(math.Sin(n))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+
+func FnCos(nargs int) error {
+	if nargs != 1 {
+		return errors.New("cos takes 1 argument")
+	}
+
+	var n float64
+	nv := stack.Pop()
+	nr, ok := nv.(Rational)
+	if ok {
+		nbr := big.Rat(nr)
+		n, _ = nbr.Float64()
+	} else {
+		ni, ok := nv.(Integer)
+		if !ok {
+			return errors.New("cos takes a number as the argument")
+		}
+		nbi := big.Int(ni)
+		n = float64(nbi.Int64())
+	}
+
+	res := big.Rat{}
+	res.SetFloat64(math.Cos(n))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+func FnAsin(nargs int) error {
+	if nargs != 1 {
+		return errors.New("asin takes 1 argument")
+	}
+
+	var n float64
+	nv := stack.Pop()
+	nr, ok := nv.(Rational)
+	if ok {
+		nbr := big.Rat(nr)
+		n, _ = nbr.Float64()
+	} else {
+		ni, ok := nv.(Integer)
+		if !ok {
+			return errors.New("asin takes a number as the argument")
+		}
+		nbi := big.Int(ni)
+		n = float64(nbi.Int64())
+	}
+
+	if n < -1 || n > 1 {
+		return errors.New("asin argument out of range [-1, 1]")
+	}
+
+	res := big.Rat{}
+	res.SetFloat64(math.Asin(n))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+func FnAcos(nargs int) error {
+	if nargs != 1 {
+		return errors.New("acos takes 1 argument")
+	}
+
+	var n float64
+	nv := stack.Pop()
+	nr, ok := nv.(Rational)
+	if ok {
+		nbr := big.Rat(nr)
+		n, _ = nbr.Float64()
+	} else {
+		ni, ok := nv.(Integer)
+		if !ok {
+			return errors.New("acos takes a number as the argument")
+		}
+		nbi := big.Int(ni)
+		n = float64(nbi.Int64())
+	}
+
+	if n < -1 || n > 1 {
+		return errors.New("acos argument out of range [-1, 1]")
+	}
+
+	res := big.Rat{}
+	res.SetFloat64(math.Acos(n))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+func FnAtan(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("atan takes 1 or 2 arguments")
+	}
+
+	var y, x float64
+	nx := stack.Pop()
+	switch nr := nx.(type) {
+	case Rational:
+		nbr := big.Rat(nr)
+		x, _ = nbr.Float64()
+	case Integer:
+		nbi := big.Int(nr)
+		x = float64(nbi.Int64())
+	default:
+		return errors.New("atan takes numbers as arguments")
+	}
+	if nargs == 2 {
+		ny := stack.Pop()
+		switch nr := ny.(type) {
+		case Rational:
+			nbr := big.Rat(nr)
+			y, _ = nbr.Float64()
+		case Integer:
+			nbi := big.Int(nr)
+			y = float64(nbi.Int64())
+		default:
+			return errors.New("atan takes numbers as arguments")
+		}
+	} else {
+		x = 1.0
+	}
+
+	res := big.Rat{}
+	res.SetFloat64(math.Atan2(y, x))
+	stack.Push(ratValue(&res))
+	return nil
+}
+
+// popRadix pops the optional radix argument of number->string and
+// string->number.
+func popRadix(name string, nargs int) (int, error) {
+	if nargs != 2 {
+		return 10, nil
+	}
+	radix, ok := toIndex(stack.Pop())
+	if !ok || radix < 2 || radix > 36 {
+		return 0, fmt.Errorf("%s: invalid radix", name)
+	}
+	return radix, nil
+}
+
+func FnNumber2String(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("number->string takes 1 or 2 arguments")
+	}
+	v := stack.Pop()
+	radix, err := popRadix("number->string", nargs)
+	if err != nil {
+		return err
+	}
+
+	var s string
+	switch v := v.(type) {
+	case Integer:
+		n := big.Int(v)
+		s = n.Text(radix)
+	case Rational:
+		n := big.Rat(v)
+		s = n.Num().Text(radix) + "/" + n.Denom().Text(radix)
+	default:
+		return errors.New("number->string takes a numeric argument")
+	}
+	stack.Push(NewString(s))
+	return nil
+}
+
+var decimalRe = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// parseNumber parses the written form of an exact number, which may start
+// with radix and exactness prefixes.
+func parseNumber(str string, radix int) (Value, bool) {
+	for len(str) > 2 && str[0] == '#' {
+		switch str[1] {
+		case 'x', 'X':
+			radix = 16
+		case 'o', 'O':
+			radix = 8
+		case 'b', 'B':
+			radix = 2
+		case 'd', 'D':
+			radix = 10
+		case 'e', 'E', 'i', 'I':
+		default:
+			return nil, false
+		}
+		str = str[2:]
+	}
+
+	if parts := strings.SplitN(str, "/", 2); len(parts) == 2 {
+		num, ok := new(big.Int).SetString(parts[0], radix)
+		if !ok || parts[1] == "" || parts[1][0] == '+' || parts[1][0] == '-' {
+			return nil, false
+		}
+		den, ok := new(big.Int).SetString(parts[1], radix)
+		if !ok || den.Sign() == 0 {
+			return nil, false
+		}
+		return ratValue(new(big.Rat).SetFrac(num, den)), true
+	}
+
+	if i, ok := new(big.Int).SetString(str, radix); ok {
+		return Integer(*i), true
+	}
+	if radix == 10 && decimalRe.MatchString(str) {
+		if r, ok := new(big.Rat).SetString(str); ok {
+			return ratValue(r), true
+		}
+	}
+	return nil, false
+}
+
+func FnString2Number(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("string->number takes 1 or 2 arguments")
+	}
+
+	ns, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string->number takes a string as the first argument")
+	}
+	radix, err := popRadix("string->number", nargs)
+	if err != nil {
+		return err
+	}
+
+	if num, ok := parseNumber(ns.String(), radix); ok {
+		stack.Push(num)
+	} else {
+		stack.Push(Boolean(false))
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package scheme
+
+import (
+	"math/big"
+)
+
+// The optimiser works on expression trees rebuilt from the flat Ins stream
+// that Gen produces, using each instruction's effect on the stack to find its
+// operands.  The passes are:
+//
+//   - beta reduction: an immediately called lambda with no arguments (which is
+//     what begin expands to) is replaced by its body, as long as the body
+//     can't tell that it has lost its own scope
+//   - constant folding: calls to pure builtins with constant arguments are
+//     evaluated at compile time, provided the name still refers to the builtin.
+//     Code folded this way keeps its constant if the name is redefined later,
+//     so folding is only done when FoldConstants is set (by -O 2)
+//   - dead code removal: if with a constant test keeps only the arm that can
+//     run, and constants and lambdas whose value is discarded are dropped
+var Optimise = true
+
+// FoldConstants turns on constant folding, for programs that don't redefine
+// the builtins it folds calls to.
+var FoldConstants = false
+
+// Builtins that may be run at compile time.  They must not have side effects
+// and must return values that are safe to share between evaluations.
+var pureBuiltins = map[*Procedure]bool{}
+
+func init() {
+	for _, sym := range []Symbol{
+		SymAdd, SymSub, SymMul, SymDiv, SymGt, SymLt, SymEqu, SymLe, SymGe,
+		SymMin, SymMax, SymAbs, SymIsEven, SymIsOdd, SymGcd, SymLcm,
+		SymQuotient, SymRemainder, SymModulo, SymNumerator, SymDenominator,
+		SymFloor, SymCeiling, SymTruncate, SymRound,
+		SymIsNumber, SymIsComplex, SymIsReal, SymIsRational, SymIsInteger,
+		SymNot, SymChar2Integer, SymInteger2Char,
+		SymCharUpcase, SymCharDowncase, SymIsChar,
+	} {
+		pureBuiltins[TopScope.m[sym].(*Procedure)] = true
+	}
+}
+
+type node struct {
+	ins  Ins
+	kids []*node // Operands, in the order they are pushed
+
+	// For if nodes, whose instruction is JumpIfFalse, the arms.
+	then, els []*node
+
+	// Set on nodes that stand for a sequence of expressions, evaluated for
+	// the value of the last one.
+	seq []*node
+
+	// For lambda nodes, the body, or nil if it couldn't be built.
+	body []*node
+}
+
+// GenForm generates code for a top-level form and, if enabled, optimises it.
+func (p *Procedure) GenForm(v Value) error {
+	if err := p.Gen(v); err != nil {
+		return err
+	}
+	if Optimise {
+		p.Ins = Optimize(p.Ins, &p.Scope)
+	}
+	return nil
+}
+
+// Optimize returns an optimised copy of ins, which will run in scope.  Code
+// it does not understand is returned unchanged.
+func Optimize(ins []Ins, scope *Scope) []Ins {
+	body, ok := buildBody(ins)
+	if !ok {
+		return ins
+	}
+
+	o := optimizer{scope, map[Symbol]bool{}}
+	o.collectBindings(body)
+	return emitBody(o.body(body))
+}
+
+type optimizer struct {
+	scope *Scope
+
+	// Symbols that are bound by a lambda or define anywhere in the code being
+	// optimised, which therefore can't be assumed to refer to builtins.
+	shadowed map[Symbol]bool
+}
+
+// buildBody turns a sequence of instructions into the expressions that make
+// it up, or returns false if it contains anything the optimiser doesn't
+// handle.
+func buildBody(ins []Ins) ([]*node, bool) {
+	nodes := []*node{}
+	base := 0 // Expressions before this have had their values popped
+	take := func(n int) ([]*node, bool) {
+		if n < 0 || n > len(nodes)-base {
+			return nil, false
+		}
+		kids := append([]*node{}, nodes[len(nodes)-n:]...)
+		nodes = nodes[:len(nodes)-n]
+		return kids, true
+	}
+
+	for i := 0; i < len(ins); i++ {
+		in := ins[i]
+		n := &node{ins: in}
+		var ok bool
+		switch in.op {
+		case Imm, GetVar, SaveScope:
+			ok = true
+		case Lambda:
+			n.body, _ = buildBody(in.imm.(Procedure).Ins)
+			ok = true
+		case Call:
+			n.kids, ok = take(in.nargs + 1)
+		case Set, Define:
+			n.kids, ok = take(1)
+		case Pop:
+			if len(nodes) == base {
+				return nil, false
+			}
+			base = len(nodes)
+			continue
+		case JumpIfFalse:
+			// test JUMP-IF-FALSE(else) then JUMP(end) else
+			then := i + in.nargs
+			if then >= len(ins) || ins[then].op != Jump ||
+				then+ins[then].nargs >= len(ins) {
+				return nil, false
+			}
+			if n.kids, ok = take(1); !ok {
+				return nil, false
+			}
+			n.then, ok = buildExpr(ins[i+1 : then])
+			if !ok {
+				return nil, false
+			}
+			i = then + ins[then].nargs
+			n.els, ok = buildExpr(ins[then+1 : i+1])
+		}
+		if !ok {
+			return nil, false
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, base == 0 || len(nodes) > base
+}
+
+// buildExpr builds a sequence of instructions that leaves exactly one value,
+// such as an arm of an if.
+func buildExpr(ins []Ins) ([]*node, bool) {
+	nodes, ok := buildBody(ins)
+	return nodes, ok && len(nodes) == 1
+}
+
+func (o *optimizer) collectBindings(nodes []*node) {
+	for _, n := range nodes {
+		switch n.ins.op {
+		case Set, Define:
+			o.shadowed[Unscope(n.ins.imm).(Symbol)] = true
+		case Lambda:
+			args := n.ins.imm.(Procedure).Args
+			for {
+				if p, ok := args.(*Pair); ok && p != Empty {
+					if sym, ok := (*p.Car).(Symbol); ok {
+						o.shadowed[sym] = true
+					}
+					args = *p.Cdr
+					continue
+				}
+				if sym, ok := args.(Symbol); ok {
+					o.shadowed[sym] = true
+				}
+				break
+			}
+			o.collectBindings(n.body)
+		}
+		o.collectBindings(n.kids)
+		o.collectBindings(n.then)
+		o.collectBindings(n.els)
+	}
+}
+
+// body optimises a sequence of expressions whose values, apart from the last,
+// are discarded.
+func (o *optimizer) body(nodes []*node) []*node {
+	res := []*node{}
+	for i, n := range nodes {
+		n = o.expr(n)
+		if i != len(nodes)-1 && isPure(n) {
+			continue
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+func (o *optimizer) expr(n *node) *node {
+	for i := range n.kids {
+		n.kids[i] = o.expr(n.kids[i])
+	}
+
+	switch n.ins.op {
+	case Lambda:
+		// Lambdas are optimised along with the enclosing code, so that
+		// constant folding knows what they bind
+		if n.body != nil {
+			n.body = o.body(n.body)
+		}
+	case JumpIfFalse:
+		n.then = o.body(n.then)
+		n.els = o.body(n.els)
+
+		// With a constant test, only one arm can ever run.  Anything but #f
+		// counts as true.
+		if test := n.kids[0]; isConstant(test) {
+			if b, ok := test.ins.imm.(Boolean); ok && !bool(b) {
+				return &node{seq: n.els}
+			}
+			return &node{seq: n.then}
+		}
+	case Call:
+		if res := o.betaReduce(n); res != nil {
+			return res
+		}
+		if !FoldConstants {
+			break
+		}
+		if res := o.fold(n); res != nil {
+			return res
+		}
+	}
+	return n
+}
+
+// betaReduce inlines ((lambda () body ...)).  The body keeps running in the
+// caller's scope instead of a fresh one, which is only invisible if it never
+// binds or captures anything in that scope.
+func (o *optimizer) betaReduce(n *node) *node {
+	if n.ins.nargs != 0 || n.kids[0].ins.op != Lambda {
+		return nil
+	}
+	lambda := n.kids[0]
+	if lambda.ins.imm.(Procedure).Args != Empty {
+		return nil
+	}
+
+	if len(lambda.body) == 0 || !scopeSafe(lambda.body) {
+		return nil
+	}
+	return &node{seq: lambda.body}
+}
+
+func scopeSafe(nodes []*node) bool {
+	for _, n := range nodes {
+		switch n.ins.op {
+		case Set, Define, SaveScope:
+			return false
+		}
+		if !scopeSafe(n.kids) || !scopeSafe(n.then) || !scopeSafe(n.els) ||
+			!scopeSafe(n.seq) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *optimizer) fold(n *node) (res *node) {
+	callee := n.kids[len(n.kids)-1]
+	sym, ok := callee.ins.imm.(Symbol)
+	if callee.ins.op != GetVar || !ok || o.shadowed[sym] || o.scope == nil {
+		return nil
+	}
+
+	scope := o.scope.Lookup(sym)
+	if scope == nil {
+		return nil
+	}
+	proc, ok := scope.m[sym].(*Procedure)
+	if !ok || !pureBuiltins[proc] {
+		return nil
+	}
+
+	args := n.kids[:len(n.kids)-1]
+	for _, arg := range args {
+		if !isConstant(arg) {
+			return nil
+		}
+	}
+
+	stack_pos := len(stack)
+	defer func() {
+		// Leave anything that fails, even by panicking, to happen at runtime
+		if recover() != nil {
+			res = nil
+		}
+		stack = stack[:stack_pos]
+	}()
+
+	for _, arg := range args {
+		stack.Push(arg.ins.imm)
+	}
+	if err := proc.Builtin(len(args)); err != nil {
+		return nil
+	}
+	if len(stack) != stack_pos+1 {
+		return nil
+	}
+	return &node{ins: Ins{Imm, copyConstant(stack.Top()), 0}}
+}
+
+func isConstant(n *node) bool {
+	if n.ins.op != Imm || n.kids != nil || n.seq != nil {
+		return false
+	}
+	switch n.ins.imm.(type) {
+	case Boolean, Char, Integer, Rational:
+		return true
+	}
+	return false
+}
+
+// copyConstant gives folded numbers their own storage, since big values
+// share it when copied.
+func copyConstant(v Value) Value {
+	switch v := v.(type) {
+	case Integer:
+		i := big.Int(v)
+		var res big.Int
+		res.Set(&i)
+		return Integer(res)
+	case Rational:
+		r := big.Rat(v)
+		var res big.Rat
+		res.Set(&r)
+		return Rational(res)
+	}
+	return v
+}
+
+// isPure reports whether discarding the node's value also makes it safe to
+// not evaluate it at all.
+func isPure(n *node) bool {
+	if n.kids != nil || n.then != nil || n.els != nil {
+		return false
+	}
+	if n.seq != nil {
+		for _, k := range n.seq {
+			if !isPure(k) {
+				return false
+			}
+		}
+		return true
+	}
+	return n.ins.op == Imm || n.ins.op == Lambda
+}
+
+func emitBody(nodes []*node) []Ins {
+	res := []Ins{}
+	for i, n := range nodes {
+		res = append(res, emit(n)...)
+		if i != len(nodes)-1 {
+			res = append(res, Ins{Pop, nil, 0})
+		}
+	}
+	return res
+}
+
+func emit(n *node) []Ins {
+	if n.seq != nil {
+		return emitBody(n.seq)
+	}
+
+	res := []Ins{}
+	for _, k := range n.kids {
+		res = append(res, emit(k)...)
+	}
+
+	if n.ins.op == Lambda && n.body != nil {
+		lambda := n.ins.imm.(Procedure)
+		lambda.Ins = emitBody(n.body)
+		return append(res, Ins{Lambda, lambda, 0})
+	}
+	if n.ins.op != JumpIfFalse {
+		return append(res, n.ins)
+	}
+
+	then := emitBody(n.then)
+	els := emitBody(n.els)
+	res = append(res, Ins{JumpIfFalse, nil, len(then) + 1})
+	res = append(res, then...)
+	res = append(res, Ins{Jump, nil, len(els)})
+	return append(res, els...)
+}
@@ -0,0 +1,128 @@
+package scheme
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func expectInteger(t *testing.T, code string, expected int64) {
+	t.Helper()
+	Top.Run(code, true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Errorf("%s: expected integer, got %T", code, stack.Top())
+		return
+	}
+	if result := big.Int(result); result.Cmp(big.NewInt(expected)) != 0 {
+		t.Errorf("%s: expected %d, got %v", code, expected, result.String())
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	FoldConstants = true
+	defer func() { FoldConstants = false }()
+
+	ctx := &Procedure{Scope: Top.Scope, Macros: Top.Macros}
+	p := NewParser("(+ 1 (* 2 3))")
+	v, _ := p.GetValue()
+	if err := ctx.GenForm(v); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Ins) != 1 || ctx.Ins[0].op != Imm {
+		t.Errorf("Expected a single IMM, got %d instructions", len(ctx.Ins))
+	}
+
+	expectInteger(t, "(+ 1 (* 2 3))", 7)
+	expectInteger(t, "(let ((+ -)) (+ 3 1))", 2)
+	expectInteger(t, "((lambda (*) (* 3 4)) +)", 7)
+	expectInteger(t, "(if (> 2 1) 10 20)", 10)
+	expectInteger(t, "(if (< 2 1) 10 20)", 20)
+}
+
+func TestRedefinedBuiltin(t *testing.T) {
+	// Without folding, code compiled before a builtin is redefined sees the
+	// new definition
+	env := &Procedure{
+		Scope:  Scope{map[Symbol]Value{}, &Top.Scope},
+		Macros: Top.Macros,
+	}
+	if err := env.Exec(`(define (opt-three) (+ 1 2)) (define + -) (opt-three)`); err != nil {
+		t.Fatal(err)
+	}
+	if res := ValueString(stack.Top(), false); res != "-1" {
+		t.Errorf("Expected -1, got %s", res)
+	}
+}
+
+func TestBetaReduction(t *testing.T) {
+	expectInteger(t, "(+ 1 (begin 5 (+ 2 3)))", 6)
+	expectInteger(t, "(let () (define inner 4) (* inner 2))", 8)
+	expectInteger(t, `(define (opt-sum n acc)
+	                    (if (= n 0)
+	                      acc
+	                      (begin (opt-sum (- n 1) (+ acc n)))))
+	                  (opt-sum 100 0)`, 5050)
+}
+
+func benchmarkScheme(b *testing.B, def string, call string) {
+	for _, opt := range []bool{false, true} {
+		b.Run(fmt.Sprintf("optimise=%v", opt), func(b *testing.B) {
+			Optimise = opt
+			defer func() { Optimise = true }()
+
+			// Definitions go into a fresh child scope, so that each
+			// sub-benchmark gets code built with its own setting
+			env := &Procedure{
+				Scope:  Scope{map[Symbol]Value{}, &Top.Scope},
+				Macros: Top.Macros,
+			}
+			if err := env.Exec(def); err != nil {
+				b.Fatal(err)
+			}
+			img, err := env.Compile(call)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			stack_pos := len(stack)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := env.Replay(img); err != nil {
+					b.Fatal(err)
+				}
+				stack = stack[:stack_pos]
+			}
+		})
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkScheme(b,
+		"(define (fib n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))",
+		"(fib 15)")
+}
+
+func BenchmarkLoop(b *testing.B) {
+	benchmarkScheme(b,
+		"(define (loop n) (if (= n 0) 0 (loop (- n 1))))",
+		"(loop 10000)")
+}
+
+func BenchmarkBegin(b *testing.B) {
+	benchmarkScheme(b,
+		`(define (count n acc)
+		   (cond ((= n 0) acc)
+		         (else (begin (count (- n 1) (+ acc (* 2 3)))))))`,
+		"(count 5000 0)")
+}
+
+func BenchmarkDo(b *testing.B) {
+	benchmarkScheme(b,
+		`(define (sum-vector v)
+		   (do ((i 0 (+ i 1))
+		        (acc 0 (+ acc (vector-ref v i))))
+		       ((= i (vector-length v)) acc)))
+		 (define vec (make-vector 1000 1))`,
+		"(sum-vector vec)")
+}
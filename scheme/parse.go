@@ -0,0 +1,552 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var delim = map[rune]bool{
+	';':  true,
+	')':  true,
+	' ':  true,
+	' ':  true, // no-break space, for some reason the R5RS reference uses these
+	'\n': true,
+	'\r': true,
+	'\t': true,
+}
+
+// FoldCase makes new parsers fold symbols to lower case, as R5RS did.  Either
+// way, the #!fold-case and #!no-fold-case directives switch it for the rest of
+// the text being read.
+var FoldCase = false
+
+type Parser struct {
+	data     []rune
+	line     uint
+	foldCase bool
+	labels   map[string]Value // Datum labels, as in #0=(a . #0#)
+	mutable  bool             // Read strings as data rather than literals
+}
+
+func NewParser(code string) Parser {
+	return Parser{data: []rune(code), line: 1, foldCase: FoldCase}
+}
+
+// datumLabel stands in for a labelled datum while it is being read.
+type datumLabel struct {
+	name string
+}
+
+func (*datumLabel) isValue() {}
+
+// resolveLabel replaces each reference to label within v by v itself.
+func resolveLabel(v Value, label *datumLabel) {
+	seen := map[interface{}]bool{}
+	var resolve func(*Value)
+	resolve = func(x *Value) {
+		for {
+			switch datum := (*x).(type) {
+			case *datumLabel:
+				if datum == label {
+					*x = v
+				}
+			case *Pair:
+				if datum == Empty || seen[datum] {
+					return
+				}
+				seen[datum] = true
+				resolve(datum.Car)
+				x = datum.Cdr
+				continue
+			case Vector:
+				if seen[datum.v] {
+					return
+				}
+				seen[datum.v] = true
+				for i := range *datum.v {
+					resolve(&(*datum.v)[i])
+				}
+			}
+			return
+		}
+	}
+	resolve(&v)
+}
+
+// directive reports whether the text starts with the #! directive name, and
+// if so skips it.
+func (p *Parser) directive(name string) bool {
+	n := len([]rune(name)) + 2
+	if len(p.data) < n || string(p.data[:n]) != "#!"+name ||
+		(len(p.data) > n && !delim[p.data[n]]) {
+		return false
+	}
+	p.data = p.data[n:]
+	return true
+}
+
+func (p *Parser) skipWs() {
+	for len(p.data) > 0 {
+		switch {
+		case p.directive("fold-case"):
+			p.foldCase = true
+		case p.directive("no-fold-case"):
+			p.foldCase = false
+		case p.data[0] == ';':
+			for len(p.data) > 0 && p.data[0] != '\n' {
+				p.data = p.data[1:]
+			}
+		case len(p.data) > 1 && p.data[0] == '#' && p.data[1] == '|':
+			if !p.skipBlockComment() {
+				return
+			}
+		case len(p.data) > 1 && p.data[0] == '#' && p.data[1] == ';':
+			data, line := p.data, p.line
+			p.data = p.data[2:]
+			p.skipWs()
+			if _, err := p.GetValue(); err != nil {
+				// Leave the comment for GetValue to report
+				p.data, p.line = data, line
+				return
+			}
+		case unicode.IsSpace(p.data[0]):
+			if p.data[0] == '\n' {
+				p.line++
+			}
+			p.data = p.data[1:]
+		default:
+			return
+		}
+	}
+}
+
+// skipBlockComment skips a #| |# comment, which may contain others.  If the
+// comment is never closed it is left for GetValue to report, and
+// skipBlockComment returns false.
+func (p *Parser) skipBlockComment() bool {
+	data, line := p.data, p.line
+	depth := 0
+	for len(p.data) > 0 {
+		switch {
+		case len(p.data) > 1 && p.data[0] == '#' && p.data[1] == '|':
+			depth++
+			p.data = p.data[2:]
+		case len(p.data) > 1 && p.data[0] == '|' && p.data[1] == '#':
+			depth--
+			p.data = p.data[2:]
+			if depth == 0 {
+				return true
+			}
+		default:
+			if p.data[0] == '\n' {
+				p.line++
+			}
+			p.data = p.data[1:]
+		}
+	}
+	p.data, p.line = data, line
+	return false
+}
+
+// The names #\name may give a character, besides the character itself or its
+// #\xHH code.
+var charNames = map[string]rune{
+	"alarm":     '\a',
+	"backspace": '\b',
+	"delete":    0x7f,
+	"escape":    0x1b,
+	"newline":   '\n',
+	"null":      0,
+	"return":    '\r',
+	"cr":        '\r',
+	"space":     ' ',
+	"tab":       '\t',
+}
+
+// hexRune parses the hex code of a character, as in #\x41 and "\x41;".
+func hexRune(hex string) (rune, bool) {
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, false
+	}
+	return rune(code), true
+}
+
+// readQuoted reads the rest of a string or |symbol|, up to the closing quote,
+// replacing escape sequences with the characters they stand for.
+func (p *Parser) readQuoted(quote rune, what string) (string, error) {
+	var sb strings.Builder
+	for len(p.data) > 0 && p.data[0] != quote {
+		ch := p.data[0]
+		p.data = p.data[1:]
+		if ch == '\n' {
+			p.line++
+		}
+		if ch != '\\' || len(p.data) == 0 {
+			sb.WriteRune(ch)
+			continue
+		}
+
+		ch, p.data = p.data[0], p.data[1:]
+		switch ch {
+		case 'a':
+			sb.WriteRune('\a')
+		case 'b':
+			sb.WriteRune('\b')
+		case 't':
+			sb.WriteRune('\t')
+		case 'n':
+			sb.WriteRune('\n')
+		case 'r':
+			sb.WriteRune('\r')
+		case 'x':
+			end := 0
+			for end < len(p.data) && p.data[end] != ';' && p.data[end] != quote {
+				end++
+			}
+			r, ok := hexRune(string(p.data[:end]))
+			if !ok || end == len(p.data) || p.data[end] != ';' {
+				return "", errors.New(fmt.Sprintf(
+					"Line %d: Invalid \\x escape in %s", p.line, what))
+			}
+			sb.WriteRune(r)
+			p.data = p.data[end+1:]
+		case ' ', '\t', '\r', '\n':
+			// A line continuation, which drops the line ending and the
+			// whitespace around it
+			for ch == ' ' || ch == '\t' || ch == '\r' {
+				if len(p.data) == 0 {
+					break
+				}
+				ch, p.data = p.data[0], p.data[1:]
+			}
+			if ch != '\n' {
+				return "", errors.New(fmt.Sprintf(
+					"Line %d: Invalid escape in %s", p.line, what))
+			}
+			p.line++
+			for len(p.data) > 0 && (p.data[0] == ' ' || p.data[0] == '\t') {
+				p.data = p.data[1:]
+			}
+		default: // Including \" \\ and \|
+			sb.WriteRune(ch)
+		}
+	}
+	if len(p.data) == 0 {
+		return "", errors.New(fmt.Sprintf(
+			"Line %d: Early EOF, non-terminated %s", p.line, what))
+	}
+	p.data = p.data[1:] // End quote
+	return sb.String(), nil
+}
+
+func (p *Parser) GetValue() (Value, error) {
+	if len(p.data) == 0 {
+		return nil, errors.New(fmt.Sprintf("Line %d: Early EOF", p.line))
+	}
+
+	switch {
+	case unicode.IsDigit(p.data[0]) || (p.data[0] == '-' && len(p.data) > 1 &&
+		(unicode.IsDigit(p.data[1]) || p.data[1] == '.')):
+		digits := ""
+		if p.data[0] == '-' {
+			digits = "-"
+			p.data = p.data[1:]
+		}
+
+		for len(p.data) > 0 && unicode.IsDigit(p.data[0]) {
+			digits += string(p.data[0])
+			p.data = p.data[1:]
+		}
+
+		if len(p.data) > 1 && p.data[0] == '/' && unicode.IsDigit(p.data[1]) {
+			// Rational value
+			digits += "/"
+			p.data = p.data[1:]
+			for len(p.data) > 0 && unicode.IsDigit(p.data[0]) {
+				digits += string(p.data[0])
+				p.data = p.data[1:]
+			}
+
+			var r big.Rat
+			if _, ok := r.SetString(digits); !ok ||
+				(len(p.data) != 0 && !delim[p.data[0]]) {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Invalid rational (%s)", p.line, digits))
+			}
+			return ratValue(&r), nil
+		}
+
+		if len(p.data) == 0 || p.data[0] != '.' {
+			if len(p.data) != 0 &&
+				!unicode.IsSpace(p.data[0]) &&
+				p.data[0] != ')' {
+				return nil, errors.New(fmt.Sprintf("Line %d: Invalid number",
+					p.line))
+			}
+			var i big.Int
+			i.SetString(digits, 10)
+			return Integer(i), nil
+		}
+
+		// Decimal value
+		digits += string(p.data[0])
+		p.data = p.data[1:]
+		for len(p.data) > 0 && unicode.IsDigit(p.data[0]) {
+			digits += string(p.data[0])
+			p.data = p.data[1:]
+		}
+		rs := []rune(digits)
+		if rs[len(rs)-1] == '.' || (len(p.data) != 0 && !delim[p.data[0]]) {
+			return nil, errors.New(fmt.Sprintf("Line %d: Invalid real (%s)",
+				p.line, digits))
+		}
+
+		var r big.Rat
+		r.SetString(digits)
+		return ratValue(&r), nil
+
+	case p.data[0] == '"':
+		p.data = p.data[1:]
+		str, err := p.readQuoted('"', "string")
+		if err != nil {
+			return nil, err
+		}
+		s := NewString(str)
+		s.immutable = !p.mutable
+		return s, nil
+
+	case p.data[0] == ')':
+		return nil, errors.New(fmt.Sprintf("Line %d: Paren mismatch", p.line))
+
+	case p.data[0] == '(':
+		p.data = p.data[1:]
+		var res Value = Empty
+		var cur *Value = &res
+		p.skipWs()
+		for len(p.data) > 0 && p.data[0] != ')' {
+			car, err := p.GetValue()
+			if err != nil {
+				return nil, err
+			}
+			p.skipWs()
+			if len(p.data) == 0 {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Early EOF (list)", p.line))
+			}
+
+			if p.data[0] == '.' && (len(p.data) == 1 || delim[p.data[1]]) {
+				p.data = p.data[1:]
+				p.skipWs()
+				cdr, err := p.GetValue()
+				if err != nil {
+					return nil, err
+				}
+				p.skipWs()
+				if len(p.data) == 0 {
+					return nil, errors.New(fmt.Sprintf(
+						"Line %d: Early EOF (pair)", p.line))
+				}
+				if p.data[0] != ')' {
+					fmt.Println(p.data)
+					return nil, errors.New(fmt.Sprintf(
+						"Line %d: Expected closing paren (pair)", p.line))
+				}
+
+				*cur = &Pair{&car, &cdr}
+				p.data = p.data[1:]
+				return res, nil
+			}
+
+			var next Value = &Pair{}
+			*cur = &Pair{&car, &next}
+			cur = &next
+		}
+		if len(p.data) == 0 {
+			return nil, errors.New(fmt.Sprintf(
+				"Line %d: Early EOF (list)", p.line))
+		}
+		*cur = Empty
+		p.data = p.data[1:] // Ending paren
+		return res, nil
+
+	case p.data[0] == '#':
+		p.data = p.data[1:]
+		if len(p.data) == 0 {
+			return nil, errors.New(fmt.Sprintf(
+				"Line %d: Early EOF (#)", p.line))
+		}
+
+		if ch := p.data[0]; ch == 't' || ch == 'f' {
+			p.data = p.data[1:]
+			if len(p.data) > 0 && !delim[p.data[0]] {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Invalid # sequence", p.line))
+			}
+			return Boolean(ch == 't'), nil
+		} else if ch == '\\' {
+			p.data = p.data[1:]
+			if len(p.data) == 0 {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Early EOF (character)", p.line))
+			}
+
+			n := 1
+			for n < len(p.data) && !delim[p.data[n]] {
+				n++
+			}
+			name := string(p.data[:n])
+			if n == 1 {
+				ch, p.data = p.data[0], p.data[1:]
+				return Char(ch), nil
+			}
+
+			r, ok := charNames[strings.ToLower(name)]
+			if !ok && name[0] == 'x' {
+				r, ok = hexRune(name[1:])
+			}
+			if !ok {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Invalid character name (%s)", p.line, name))
+			}
+			p.data = p.data[n:]
+			return Char(r), nil
+		} else if unicode.IsDigit(ch) {
+			n := 0
+			for n < len(p.data) && unicode.IsDigit(p.data[n]) {
+				n++
+			}
+			if n == len(p.data) || (p.data[n] != '=' && p.data[n] != '#') {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Invalid # sequence", p.line))
+			}
+			name, mark := string(p.data[:n]), p.data[n]
+			p.data = p.data[n+1:]
+
+			if mark == '#' {
+				v, ok := p.labels[name]
+				if !ok {
+					return nil, errors.New(fmt.Sprintf(
+						"Line %d: Undefined datum label #%s#", p.line, name))
+				}
+				return v, nil
+			}
+
+			label := &datumLabel{name}
+			if p.labels == nil {
+				p.labels = map[string]Value{}
+			}
+			p.labels[name] = label
+			v, err := p.GetValue()
+			if err != nil {
+				return nil, err
+			}
+			if v == label {
+				return nil, errors.New(fmt.Sprintf(
+					"Line %d: Datum label #%s= labels only itself", p.line, name))
+			}
+			p.labels[name] = v
+			resolveLabel(v, label)
+			return v, nil
+		} else if ch == '|' { // A block comment skipWs couldn't skip
+			return nil, errors.New(fmt.Sprintf(
+				"Line %d: Early EOF, non-terminated block comment", p.line))
+		} else if ch == ';' { // A datum comment skipWs couldn't skip
+			p.data = p.data[1:]
+			p.skipWs()
+			if _, err := p.GetValue(); err != nil {
+				return nil, err
+			}
+			p.skipWs()
+			return p.GetValue()
+		} else if ch == '(' {
+			v, err := p.GetValue()
+			if err != nil {
+				return nil, err
+			}
+
+			vec, err := list2vec(v.(*Pair))
+			return Vector{&vec}, err
+		} else if ch == 'u' && strings.HasPrefix(string(p.data), "u8(") {
+			p.data = p.data[2:]
+			v, err := p.GetValue()
+			if err != nil {
+				return nil, err
+			}
+
+			vec, err := list2vec(v.(*Pair))
+			if err != nil {
+				return nil, err
+			}
+			b := []byte{}
+			for _, e := range vec {
+				octet, ok := toByte(e)
+				if !ok {
+					return nil, errors.New(fmt.Sprintf(
+						"Line %d: Invalid bytevector element", p.line))
+				}
+				b = append(b, octet)
+			}
+			return Bytevector{&b}, nil
+		} else {
+			return nil, errors.New(fmt.Sprintf(
+				"Line %d: Invalid # sequence", p.line))
+		}
+
+	case p.data[0] == '\'' || p.data[0] == '`' || p.data[0] == ',':
+		str := string(p.data[0])
+
+		p.data = p.data[1:]
+		if len(p.data) != 0 && p.data[0] == '@' {
+			str += "@"
+			p.data = p.data[1:]
+		}
+		val, err := p.GetValue()
+		if err != nil {
+			return nil, err
+		}
+
+		cdr := Empty
+		var tail Value = &Pair{&val, &cdr}
+
+		var res Value
+		switch str {
+		case "'":
+			res = Quote
+		case ",":
+			res = Unquote
+		case "`":
+			res = Quasiquote
+		case ",@":
+			res = UnquoteSplicing
+		default:
+			panic("Unreachable")
+		}
+		return &Pair{&res, &tail}, nil
+
+	case p.data[0] == '|':
+		p.data = p.data[1:]
+		str, err := p.readQuoted('|', "symbol")
+		if err != nil {
+			return nil, err
+		}
+		return Str2Sym(str), nil
+
+	default: // Symbol
+		str := ""
+		for len(p.data) > 0 && !delim[p.data[0]] {
+			str += string(p.data[0])
+			p.data = p.data[1:]
+		}
+
+		if p.foldCase {
+			str = strings.Map(foldRune, str)
+		}
+		return Str2Sym(str), nil
+	}
+}
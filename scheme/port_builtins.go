@@ -0,0 +1,834 @@
+package scheme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// inputPort pops the optional port argument that follows the first k
+// arguments of an input procedure, defaulting to the current input port.
+func inputPort(name string, nargs, k int) (InputPort, error) {
+	port := InputPortStack[len(InputPortStack)-1]
+	if nargs > k {
+		var ok bool
+		port, ok = stack.Pop().(InputPort)
+		if !ok {
+			return port, fmt.Errorf("%s takes an input port as the argument", name)
+		}
+	}
+	if port.Closed {
+		return port, fmt.Errorf("%s: port is closed", name)
+	}
+	return port, nil
+}
+
+// outputPort is the output counterpart of inputPort.
+func outputPort(name string, nargs, k int) (OutputPort, error) {
+	port := OutputPortStack[len(OutputPortStack)-1]
+	if nargs > k {
+		var ok bool
+		port, ok = stack.Pop().(OutputPort)
+		if !ok {
+			return port, fmt.Errorf("%s takes an output port as the argument", name)
+		}
+	}
+	if port.Closed {
+		return port, fmt.Errorf("%s: port is closed", name)
+	}
+	return port, nil
+}
+
+func FnIsPort(nargs int) error {
+	if nargs != 1 {
+		return errors.New("port? takes 1 argument")
+	}
+
+	v := stack.Pop()
+	_, ok1 := v.(InputPort)
+	_, ok2 := v.(OutputPort)
+	stack.Push(Boolean(ok1 || ok2))
+	return nil
+}
+
+func FnIsInputPort(nargs int) error {
+	if nargs != 1 {
+		return errors.New("input-port? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(InputPort)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnIsOutputPort(nargs int) error {
+	if nargs != 1 {
+		return errors.New("output-port? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(OutputPort)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnIsInputPortOpen(nargs int) error {
+	if nargs != 1 {
+		return errors.New("input-port-open? takes 1 argument")
+	}
+
+	port, ok := stack.Pop().(InputPort)
+	if !ok {
+		return errors.New("input-port-open? takes an input port as the argument")
+	}
+	stack.Push(Boolean(!port.Closed))
+	return nil
+}
+
+func FnIsOutputPortOpen(nargs int) error {
+	if nargs != 1 {
+		return errors.New("output-port-open? takes 1 argument")
+	}
+
+	port, ok := stack.Pop().(OutputPort)
+	if !ok {
+		return errors.New(
+			"output-port-open? takes an output port as the argument",
+		)
+	}
+	stack.Push(Boolean(!port.Closed))
+	return nil
+}
+
+func FnCurrentInputPort(nargs int) error {
+	if nargs != 0 {
+		return errors.New("current-input-port takes no arguments")
+	}
+	stack.Push(InputPortStack[len(InputPortStack)-1])
+	return nil
+}
+
+func FnCurrentOutputPort(nargs int) error {
+	if nargs != 0 {
+		return errors.New("current-output-port takes no arguments")
+	}
+	stack.Push(OutputPortStack[len(OutputPortStack)-1])
+	return nil
+}
+
+func FnCurrentErrorPort(nargs int) error {
+	if nargs != 0 {
+		return errors.New("current-error-port takes no arguments")
+	}
+	stack.Push(ErrorPort)
+	return nil
+}
+
+func FnCallWithPort(nargs int) error {
+	if nargs != 2 {
+		return errors.New("call-with-port takes 2 arguments")
+	}
+
+	port := stack.Pop()
+	proc := stack.Pop()
+	var state *PortState
+	switch port := port.(type) {
+	case InputPort:
+		state = port.PortState
+	case OutputPort:
+		state = port.PortState
+	default:
+		return errors.New("call-with-port takes a port as the first argument")
+	}
+
+	res, err := Apply(proc, port)
+	if err != nil {
+		return err
+	}
+	state.Close()
+	stack.Push(res)
+	return nil
+}
+
+func FnCallWithInputFile(nargs int) error {
+	if nargs != 2 {
+		return errors.New("call-with-input-file takes 2 arguments")
+	}
+	if err := FnOpenInputFile(1); err != nil {
+		return err
+	}
+	return FnCallWithPort(2)
+}
+
+func FnCallWithOutputFile(nargs int) error {
+	if nargs != 2 {
+		return errors.New("call-with-output-file takes 2 arguments")
+	}
+	if err := FnOpenOutputFile(1); err != nil {
+		return err
+	}
+	return FnCallWithPort(2)
+}
+
+func FnWithInputFromFile(nargs int) error {
+	if nargs != 2 {
+		return errors.New("with-input-from-file takes 2 arguments")
+	}
+	if err := FnOpenInputFile(1); err != nil {
+		return err
+	}
+	port := stack.Pop().(InputPort)
+	thunk := stack.Pop()
+
+	InputPortStack = append(InputPortStack, port)
+	res, err := Apply(thunk)
+	InputPortStack = InputPortStack[:len(InputPortStack)-1]
+	port.Close()
+	if err != nil {
+		return err
+	}
+	stack.Push(res)
+	return nil
+}
+
+func FnWithOutputToFile(nargs int) error {
+	if nargs != 2 {
+		return errors.New("with-output-to-file takes 2 arguments")
+	}
+	if err := FnOpenOutputFile(1); err != nil {
+		return err
+	}
+	port := stack.Pop().(OutputPort)
+	thunk := stack.Pop()
+
+	OutputPortStack = append(OutputPortStack, port)
+	res, err := Apply(thunk)
+	OutputPortStack = OutputPortStack[:len(OutputPortStack)-1]
+	port.Close()
+	if err != nil {
+		return err
+	}
+	stack.Push(res)
+	return nil
+}
+
+func FnOpenInputFile(nargs int) error {
+	if nargs != 1 {
+		return errors.New("open-input-file takes 1 argument")
+	}
+
+	fname, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("open-input-file takes a string")
+	}
+
+	f, err := os.Open(fname.String())
+	if err != nil {
+		return fileError(err)
+	}
+
+	stack.Push(NewInputPort(f, f, fname.String()))
+	return nil
+}
+
+func FnOpenOutputFile(nargs int) error {
+	if nargs != 1 {
+		return errors.New("open-output-file takes 1 argument")
+	}
+
+	fname, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("open-output-file takes a string")
+	}
+
+	f, err := os.Create(fname.String())
+	if err != nil {
+		return fileError(err)
+	}
+
+	stack.Push(NewOutputPort(f, f, fname.String()))
+	return nil
+}
+
+func FnOpenInputString(nargs int) error {
+	if nargs != 1 {
+		return errors.New("open-input-string takes 1 argument")
+	}
+
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("open-input-string takes a string as the argument")
+	}
+
+	stack.Push(NewInputPort(strings.NewReader(str.String()), nil, "string"))
+	return nil
+}
+
+func FnOpenOutputString(nargs int) error {
+	if nargs != 0 {
+		return errors.New("open-output-string takes no arguments")
+	}
+
+	stack.Push(NewOutputPort(&strings.Builder{}, nil, "string"))
+	return nil
+}
+
+func FnGetOutputString(nargs int) error {
+	if nargs != 1 {
+		return errors.New("get-output-string takes 1 argument")
+	}
+
+	port, ok := stack.Pop().(OutputPort)
+	if !ok {
+		return errors.New("get-output-string takes an output port")
+	}
+	sb, ok := port.Writer.(*strings.Builder)
+	if !ok {
+		return errors.New("get-output-string takes a string port")
+	}
+
+	s := sb.String()
+	stack.Push(NewString(s))
+	return nil
+}
+
+func FnOpenInputBytevector(nargs int) error {
+	if nargs != 1 {
+		return errors.New("open-input-bytevector takes 1 argument")
+	}
+
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"open-input-bytevector takes a bytevector as the argument",
+		)
+	}
+
+	b := append([]byte{}, *bv.b...)
+	stack.Push(NewInputPort(bytes.NewReader(b), nil, "bytevector"))
+	return nil
+}
+
+func FnOpenOutputBytevector(nargs int) error {
+	if nargs != 0 {
+		return errors.New("open-output-bytevector takes no arguments")
+	}
+
+	stack.Push(NewOutputPort(&bytes.Buffer{}, nil, "bytevector"))
+	return nil
+}
+
+func FnGetOutputBytevector(nargs int) error {
+	if nargs != 1 {
+		return errors.New("get-output-bytevector takes 1 argument")
+	}
+
+	port, ok := stack.Pop().(OutputPort)
+	if !ok {
+		return errors.New("get-output-bytevector takes an output port")
+	}
+	buf, ok := port.Writer.(*bytes.Buffer)
+	if !ok {
+		return errors.New("get-output-bytevector takes a bytevector port")
+	}
+
+	b := append([]byte{}, buf.Bytes()...)
+	stack.Push(Bytevector{&b})
+	return nil
+}
+
+func FnClosePort(nargs int) error {
+	if nargs != 1 {
+		return errors.New("close-port takes 1 argument")
+	}
+
+	switch port := stack.Pop().(type) {
+	case InputPort:
+		port.Close()
+	case OutputPort:
+		port.Close()
+	default:
+		return errors.New("close-port takes a port as the argument")
+	}
+	stack.Push(Boolean(true))
+	return nil
+}
+
+func FnCloseInputPort(nargs int) error {
+	if nargs != 1 {
+		return errors.New("close-input-port takes 1 argument")
+	}
+
+	port, ok := stack.Pop().(InputPort)
+	if !ok {
+		return errors.New(
+			"close-input-port takes an input port as the argument",
+		)
+	}
+
+	port.Close()
+	stack.Push(Boolean(true))
+	return nil
+}
+
+func FnCloseOutputPort(nargs int) error {
+	if nargs != 1 {
+		return errors.New("close-output-port takes 1 argument")
+	}
+
+	port, ok := stack.Pop().(OutputPort)
+	if !ok {
+		return errors.New(
+			"close-output-port takes an output port as the argument",
+		)
+	}
+
+	port.Close()
+	stack.Push(Boolean(true))
+	return nil
+}
+
+func FnFlushOutputPort(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to flush-output-port")
+	}
+	port, err := outputPort("flush-output-port", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	if f, ok := port.Writer.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	stack.Push(Boolean(true))
+	return nil
+}
+
+func FnRead(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to read")
+	}
+	port, err := inputPort("read", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	// Read until the text so far parses as a complete datum that ends at a
+	// delimiter, which leaves the rest of the port for later reads
+	s := ""
+	for {
+		r, _, err := port.ReadRune()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		eof := err == io.EOF
+		if !eof {
+			s += string(r)
+		}
+
+		if eof || Validate(s) {
+			next, _, err := port.ReadRune()
+			if err == nil {
+				port.UnreadRune()
+			}
+			if eof || err != nil || delim[next] || next == '(' ||
+				strings.HasSuffix(s, ")") {
+				p := NewParser(s)
+				p.mutable = true
+				if port.FoldCase != nil {
+					p.foldCase = *port.FoldCase
+				}
+				p.skipWs()
+				if p.foldCase != FoldCase || port.FoldCase != nil {
+					foldCase := p.foldCase
+					port.FoldCase = &foldCase
+				}
+				if len(p.data) == 0 {
+					if eof {
+						stack.Push(Eof{})
+						return nil
+					}
+					continue
+				}
+
+				v, err := p.GetValue()
+				if err == nil {
+					stack.Push(v)
+					return nil
+				}
+				if eof {
+					return &ErrorObject{err.Error(), Empty, ReadError}
+				}
+			}
+		}
+	}
+}
+
+func FnReadChar(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to read-char")
+	}
+	port, err := inputPort("read-char", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	r, _, err := port.ReadRune()
+	if err == io.EOF {
+		stack.Push(Eof{})
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	stack.Push(Char(r))
+	return nil
+}
+
+func FnPeekChar(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to peek-char")
+	}
+	port, err := inputPort("peek-char", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	r, _, err := port.ReadRune()
+	if err == io.EOF {
+		stack.Push(Eof{})
+		return nil
+	} else if err != nil {
+		return err
+	}
+	port.UnreadRune()
+
+	stack.Push(Char(r))
+	return nil
+}
+
+func FnReadLine(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to read-line")
+	}
+	port, err := inputPort("read-line", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	line, err := port.ReadString('\n')
+	if err == io.EOF && line == "" {
+		stack.Push(Eof{})
+		return nil
+	} else if err != nil && err != io.EOF {
+		return err
+	}
+
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	stack.Push(NewString(line))
+	return nil
+}
+
+func FnReadString(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("read-string takes 1 or 2 arguments")
+	}
+	k, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("read-string takes a length as the first argument")
+	}
+	port, err := inputPort("read-string", nargs, 1)
+	if err != nil {
+		return err
+	}
+
+	rs := []rune{}
+	for len(rs) < k {
+		r, _, err := port.ReadRune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		rs = append(rs, r)
+	}
+
+	if len(rs) == 0 && k > 0 {
+		stack.Push(Eof{})
+		return nil
+	}
+	s := string(rs)
+	stack.Push(NewString(s))
+	return nil
+}
+
+func FnReadU8(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to read-u8")
+	}
+	port, err := inputPort("read-u8", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := port.ReadByte()
+	if err == io.EOF {
+		stack.Push(Eof{})
+		return nil
+	} else if err != nil {
+		return err
+	}
+	stack.Push(Integer(*big.NewInt(int64(b))))
+	return nil
+}
+
+func FnPeekU8(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to peek-u8")
+	}
+	port, err := inputPort("peek-u8", nargs, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := port.Peek(1)
+	if err == io.EOF {
+		stack.Push(Eof{})
+		return nil
+	} else if err != nil {
+		return err
+	}
+	stack.Push(Integer(*big.NewInt(int64(b[0]))))
+	return nil
+}
+
+func FnReadBytevector(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("read-bytevector takes 1 or 2 arguments")
+	}
+	k, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New(
+			"read-bytevector takes a length as the first argument",
+		)
+	}
+	port, err := inputPort("read-bytevector", nargs, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := reserve(k, 1); err != nil {
+		return err
+	}
+	b := make([]byte, k)
+	n, err := io.ReadFull(port, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if n == 0 && k > 0 {
+		stack.Push(Eof{})
+		return nil
+	}
+	b = b[:n]
+	stack.Push(Bytevector{&b})
+	return nil
+}
+
+func FnReadBytevectorInto(nargs int) error {
+	if nargs < 1 || nargs > 4 {
+		return errors.New("read-bytevector! takes 1 to 4 arguments")
+	}
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"read-bytevector! takes a bytevector as the first argument",
+		)
+	}
+	port, err := inputPort("read-bytevector!", nargs, 1)
+	if err != nil {
+		return err
+	}
+	start, end, err := popRange("read-bytevector!", nargs, 2, len(*bv.b))
+	if err != nil {
+		return err
+	}
+
+	n, err := io.ReadFull(port, (*bv.b)[start:end])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if n == 0 && end > start {
+		stack.Push(Eof{})
+		return nil
+	}
+	stack.Push(Integer(*big.NewInt(int64(n))))
+	return nil
+}
+
+func FnIsEofObject(nargs int) error {
+	if nargs != 1 {
+		return errors.New("eof-object? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(Eof)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnEofObject(nargs int) error {
+	if nargs != 0 {
+		return errors.New("eof-object takes no arguments")
+	}
+	stack.Push(Eof{})
+	return nil
+}
+
+// isReady reports whether reading from port won't block.  Only terminals
+// can be waiting for input; files and in-memory ports are always ready, even
+// at EOF.
+func isReady(port InputPort) bool {
+	if port.Buffered() > 0 {
+		return true
+	}
+	if f, ok := port.Closer.(*os.File); ok {
+		if stat, err := f.Stat(); err == nil {
+			return stat.Mode()&os.ModeCharDevice == 0
+		}
+	}
+	return true
+}
+
+func FnIsCharReady(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to char-ready?")
+	}
+	port, err := inputPort("char-ready?", nargs, 0)
+	if err != nil {
+		return err
+	}
+	stack.Push(Boolean(isReady(port)))
+	return nil
+}
+
+func FnIsU8Ready(nargs int) error {
+	if nargs > 1 {
+		return errors.New("Too many args to u8-ready?")
+	}
+	port, err := inputPort("u8-ready?", nargs, 0)
+	if err != nil {
+		return err
+	}
+	stack.Push(Boolean(isReady(port)))
+	return nil
+}
+
+// writeTo pops a value and an optional port, and writes the value to the port
+// as name does.
+func writeTo(name string, nargs int, display bool, mode LabelMode) error {
+	if nargs != 1 && nargs != 2 {
+		return fmt.Errorf("Wrong arg count to %s", name)
+	}
+	v := stack.Pop()
+	port, err := outputPort(name, nargs, 1)
+	if err != nil {
+		return err
+	}
+
+	OutputPortStack = append(OutputPortStack, port)
+	WriteLabelled(v, display, mode)
+	OutputPortStack = OutputPortStack[:len(OutputPortStack)-1]
+	stack.Push(v)
+	return nil
+}
+
+func FnWrite(nargs int) error {
+	return writeTo("write", nargs, false, LabelCycles)
+}
+
+func FnWriteShared(nargs int) error {
+	return writeTo("write-shared", nargs, false, LabelShared)
+}
+
+func FnWriteSimple(nargs int) error {
+	return writeTo("write-simple", nargs, false, LabelNone)
+}
+
+func FnDisplay(nargs int) error {
+	return writeTo("display", nargs, true, LabelCycles)
+}
+
+func FnWriteString(nargs int) error {
+	if nargs < 1 || nargs > 4 {
+		return errors.New("write-string takes 1 to 4 arguments")
+	}
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("write-string takes a string as the first argument")
+	}
+	port, err := outputPort("write-string", nargs, 1)
+	if err != nil {
+		return err
+	}
+	rs := []rune(str.String())
+	start, end, err := popRange("write-string", nargs, 2, len(rs))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(port, string(rs[start:end])); err != nil {
+		return err
+	}
+	stack.Push(str)
+	return nil
+}
+
+func FnWriteU8(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("write-u8 takes 1 or 2 arguments")
+	}
+	b, ok := toByte(stack.Pop())
+	if !ok {
+		return errors.New("write-u8 takes a byte as the first argument")
+	}
+	port, err := outputPort("write-u8", nargs, 1)
+	if err != nil {
+		return err
+	}
+
+	if _, err := port.Write([]byte{b}); err != nil {
+		return err
+	}
+	stack.Push(Integer(*big.NewInt(int64(b))))
+	return nil
+}
+
+func FnWriteBytevector(nargs int) error {
+	if nargs < 1 || nargs > 4 {
+		return errors.New("write-bytevector takes 1 to 4 arguments")
+	}
+	bv, ok := stack.Pop().(Bytevector)
+	if !ok {
+		return errors.New(
+			"write-bytevector takes a bytevector as the first argument",
+		)
+	}
+	port, err := outputPort("write-bytevector", nargs, 1)
+	if err != nil {
+		return err
+	}
+	start, end, err := popRange("write-bytevector", nargs, 2, len(*bv.b))
+	if err != nil {
+		return err
+	}
+
+	if _, err := port.Write((*bv.b)[start:end]); err != nil {
+		return err
+	}
+	stack.Push(bv)
+	return nil
+}
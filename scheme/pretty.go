@@ -0,0 +1,267 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrettyWidth is the line width that pretty-print and the REPL fill, unless
+// pretty-print is given another.
+var PrettyWidth = 79
+
+// PrettyIndents gives, for forms whose last arguments are a body, how many
+// arguments stay on the first line.  The body then goes on the lines below,
+// indented by two.  Other lists have their elements lined up under the first
+// argument, or under the first element when that is not a symbol.
+var PrettyIndents = map[string]int{
+	"begin":              0,
+	"case":               1,
+	"case-lambda":        0,
+	"cond":               0,
+	"define":             1,
+	"define-record-type": 3,
+	"define-syntax":      1,
+	"define-values":      1,
+	"do":                 2,
+	"guard":              1,
+	"lambda":             1,
+	"let":                1, // Or 2 for a named let
+	"let*":               1,
+	"let*-values":        1,
+	"let-syntax":         1,
+	"let-values":         1,
+	"letrec":             1,
+	"letrec*":            1,
+	"letrec-syntax":      1,
+	"parameterize":       1,
+	"syntax-rules":       1,
+	"unless":             1,
+	"when":               1,
+}
+
+type prettyPrinter struct {
+	sb    strings.Builder
+	width int
+	p     *printer            // Writes the flat parts, and labels cycles
+	sizes map[interface{}]int // The flat width of each list and vector
+}
+
+// PrettyPrint writes v like write does, but broken over lines and indented
+// so that it fits within width columns where it can.  It leaves the last line
+// unfinished.
+func PrettyPrint(v Value, width int) {
+	port := OutputPortStack[len(OutputPortStack)-1]
+
+	pp := &prettyPrinter{width: width, sizes: map[interface{}]int{}}
+	pp.p = &printer{
+		port:   NewOutputPort(&pp.sb, nil, "string"),
+		labels: map[interface{}]int{},
+	}
+	pp.p.scan(v, LabelCycles, map[interface{}]bool{})
+	pp.print(v, 0)
+	fmt.Fprint(port, pp.sb.String())
+}
+
+// size returns how wide v is written on one line.  Each list and vector is
+// only measured once, and a label is counted as three columns wide.
+func (pp *prettyPrinter) size(v Value) int {
+	var key interface{}
+	switch v := v.(type) {
+	case *Pair:
+		if v != Empty {
+			key = v
+		}
+	case Vector:
+		if len(*v.v) != 0 {
+			key = v.v
+		}
+	}
+	if key == nil {
+		return utf8.RuneCountInString(ValueString(v, false))
+	}
+	if n, ok := pp.sizes[key]; ok {
+		return n
+	}
+	pp.sizes[key] = 3 // Where v is reached again within itself
+
+	items, tail := pp.items(v)
+	n := 1 + len(items)
+	if _, ok := v.(Vector); ok {
+		n++
+	}
+	for _, item := range items {
+		n += pp.size(item)
+	}
+	if tail != nil {
+		n += 3 + pp.size(tail)
+	}
+	if _, ok := pp.p.labels[key]; ok {
+		n += 3
+	}
+	pp.sizes[key] = n
+	return n
+}
+
+// items returns the elements of a list or vector, and for a list not ending
+// in (), what it ends in.  As with write, a list is cut short at a pair that
+// has a label.
+func (pp *prettyPrinter) items(v Value) ([]Value, Value) {
+	switch v := v.(type) {
+	case Vector:
+		return *v.v, nil
+	case *Pair:
+		items := []Value{*v.Car}
+		for cur := *v.Cdr; cur != Empty; {
+			pair, ok := cur.(*Pair)
+			if _, labelled := pp.p.labels[pair]; !ok || labelled {
+				return items, cur
+			}
+			items = append(items, *pair.Car)
+			cur = *pair.Cdr
+		}
+		return items, nil
+	}
+	return nil, nil
+}
+
+// write writes v on one line, and returns the column it ends at.
+func (pp *prettyPrinter) write(v Value, col int) int {
+	start := pp.sb.Len()
+	pp.p.write(v)
+	return col + utf8.RuneCountInString(pp.sb.String()[start:])
+}
+
+// print writes v starting at column col, and returns the column it ends at.
+func (pp *prettyPrinter) print(v Value, col int) int {
+	if col+pp.size(v) <= pp.width {
+		return pp.write(v, col)
+	}
+
+	var key interface{}
+	switch v := v.(type) {
+	case *Pair:
+		if v == Empty {
+			return pp.write(v, col)
+		}
+		key = v
+	case Vector:
+		if len(*v.v) == 0 {
+			return pp.write(v, col)
+		}
+		key = v.v
+	default:
+		return pp.write(v, col)
+	}
+
+	start := pp.sb.Len()
+	written := pp.p.label(key)
+	col += pp.sb.Len() - start
+	if written {
+		return col
+	}
+	items, tail := pp.items(v)
+	if _, ok := v.(Vector); ok {
+		pp.sb.WriteString("#")
+		col++
+	}
+	return pp.list(items, tail, col)
+}
+
+func (pp *prettyPrinter) newline(col int) {
+	pp.sb.WriteString("\n" + strings.Repeat(" ", col))
+}
+
+// list writes the parenthesised items, with tail after a dot if it isn't nil.
+func (pp *prettyPrinter) list(items []Value, tail Value, col int) int {
+	pp.sb.WriteString("(")
+	indent := col + 1 // For the items on lines of their own
+	next := 0         // The first of those
+	end := col + 1
+	body := false
+
+	if sym, ok := items[0].(Symbol); ok {
+		name := SymbolNames[sym]
+		var n int
+		n, body = PrettyIndents[name]
+		if name == "let" && len(items) > 1 {
+			if _, ok := items[1].(Symbol); ok {
+				n = 2
+			}
+		}
+
+		end = pp.print(items[0], end)
+		next = 1
+		if body {
+			indent = col + 2
+			for ; next <= n && next < len(items); next++ {
+				pp.sb.WriteString(" ")
+				end = pp.print(items[next], end+1)
+			}
+		} else if len(items) > 1 && end+1 < pp.width/2 {
+			indent = end + 1
+			pp.sb.WriteString(" ")
+			end = pp.print(items[1], end+1)
+			next = 2
+		}
+	}
+
+	// Lists with nothing nested in them fill each line
+	fill := !body
+	for _, item := range items {
+		if pair, ok := item.(*Pair); ok && pair != Empty {
+			fill = false
+		} else if vec, ok := item.(Vector); ok && len(*vec.v) != 0 {
+			fill = false
+		}
+	}
+
+	for i, item := range items[next:] {
+		if next == 0 && i == 0 {
+			end = pp.print(item, end)
+			continue
+		}
+		if fill && end+1+pp.size(item) <= pp.width {
+			pp.sb.WriteString(" ")
+			end = pp.print(item, end+1)
+			continue
+		}
+		pp.newline(indent)
+		end = pp.print(item, indent)
+	}
+	if tail != nil {
+		pp.sb.WriteString(" . ")
+		end = pp.print(tail, end+3)
+	}
+
+	pp.sb.WriteString(")")
+	return end + 1
+}
+
+// FnPrettyPrint takes the object, and optionally a port and the line width.
+func FnPrettyPrint(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("pretty-print takes 1 to 3 arguments")
+	}
+
+	v := stack.Pop()
+	port, err := outputPort("pretty-print", nargs, 1)
+	if err != nil {
+		return err
+	}
+	width := PrettyWidth
+	if nargs == 3 {
+		var ok bool
+		if width, ok = toIndex(stack.Pop()); !ok {
+			return errors.New("pretty-print takes a width as the third argument")
+		}
+	}
+
+	OutputPortStack = append(OutputPortStack, port)
+	PrettyPrint(v, width)
+	fmt.Fprintln(port)
+	OutputPortStack = OutputPortStack[:len(OutputPortStack)-1]
+	stack.Push(v)
+	return nil
+}
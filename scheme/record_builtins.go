@@ -0,0 +1,272 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TypeName returns the name records of this type print with, which drops the
+// angle brackets conventionally put around record type names.
+func (t *RecordType) TypeName() string {
+	name := SymbolNames[t.Name]
+	if len(name) > 2 && strings.HasPrefix(name, "<") &&
+		strings.HasSuffix(name, ">") {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+func (t *RecordType) fieldIndex(field Value) (int, error) {
+	for i, f := range t.Fields {
+		if f == field {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("Record type %s has no field %s",
+		t.TypeName(), ValueString(field, false))
+}
+
+// checkRecord returns v if it is a record of type t.
+func (t *RecordType) checkRecord(v Value, what string) (*Record, error) {
+	r, ok := v.(*Record)
+	if !ok || r.Type != t {
+		return nil, fmt.Errorf("%s expects a %s record, got %s",
+			what, t.TypeName(), ValueString(v, false))
+	}
+	return r, nil
+}
+
+func popRecordType(name string) (*RecordType, error) {
+	t, ok := stack.Pop().(*RecordType)
+	if !ok {
+		return nil, fmt.Errorf("%s takes a record type as the first argument",
+			name)
+	}
+	return t, nil
+}
+
+func FnMakeRecordType(nargs int) error {
+	if nargs != 2 {
+		return errors.New("make-record-type takes 2 arguments")
+	}
+
+	name, ok := stack.Pop().(Symbol)
+	if !ok {
+		return errors.New("make-record-type takes a symbol as the type name")
+	}
+	fields, ok := stack.Pop().(*Pair)
+	if !ok {
+		return errors.New("make-record-type takes a list of field names")
+	}
+	vec, err := list2vec(fields)
+	if err != nil {
+		return err
+	}
+
+	t := &RecordType{Name: name}
+	for _, field := range vec {
+		sym, ok := field.(Symbol)
+		if !ok {
+			return errors.New("Record field names must be symbols")
+		}
+		if _, err := t.fieldIndex(sym); err == nil {
+			return fmt.Errorf("Duplicate field %s in record type %s",
+				SymbolNames[sym], t.TypeName())
+		}
+		t.Fields = append(t.Fields, sym)
+	}
+	stack.Push(t)
+	return nil
+}
+
+// FnRecordConstructor returns a procedure taking the initial values of the
+// given fields, or of every field if none are given.  Other fields start as #f.
+func FnRecordConstructor(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("record-constructor takes 1 or 2 arguments")
+	}
+
+	t, err := popRecordType("record-constructor")
+	if err != nil {
+		return err
+	}
+	indices := make([]int, len(t.Fields))
+	for i := range indices {
+		indices[i] = i
+	}
+	if nargs == 2 {
+		fields, ok := stack.Pop().(*Pair)
+		if !ok {
+			return errors.New("record-constructor takes a list of field names")
+		}
+		vec, err := list2vec(fields)
+		if err != nil {
+			return err
+		}
+		indices = indices[:0]
+		for _, field := range vec {
+			i, err := t.fieldIndex(field)
+			if err != nil {
+				return err
+			}
+			indices = append(indices, i)
+		}
+	}
+
+	stack.Push(&Procedure{Builtin: func(nargs int) error {
+		if nargs != len(indices) {
+			return fmt.Errorf("Constructor for %s takes %d arguments",
+				t.TypeName(), len(indices))
+		}
+
+		r := &Record{Type: t, Fields: make([]Value, len(t.Fields))}
+		for i := range r.Fields {
+			r.Fields[i] = Boolean(false)
+		}
+		for _, i := range indices {
+			r.Fields[i] = stack.Pop()
+		}
+		stack.Push(r)
+		return nil
+	}})
+	return nil
+}
+
+func FnRecordPredicate(nargs int) error {
+	if nargs != 1 {
+		return errors.New("record-predicate takes 1 argument")
+	}
+
+	t, err := popRecordType("record-predicate")
+	if err != nil {
+		return err
+	}
+	stack.Push(&Procedure{Builtin: func(nargs int) error {
+		if nargs != 1 {
+			return fmt.Errorf("Predicate for %s takes 1 argument",
+				t.TypeName())
+		}
+
+		r, ok := stack.Pop().(*Record)
+		stack.Push(Boolean(ok && r.Type == t))
+		return nil
+	}})
+	return nil
+}
+
+func FnRecordAccessor(nargs int) error {
+	if nargs != 2 {
+		return errors.New("record-accessor takes 2 arguments")
+	}
+
+	t, err := popRecordType("record-accessor")
+	if err != nil {
+		return err
+	}
+	field := stack.Pop()
+	i, err := t.fieldIndex(field)
+	if err != nil {
+		return err
+	}
+
+	what := fmt.Sprintf("Accessor for field %s", SymbolNames[t.Fields[i]])
+	stack.Push(&Procedure{Builtin: func(nargs int) error {
+		if nargs != 1 {
+			return fmt.Errorf("%s takes 1 argument", what)
+		}
+
+		r, err := t.checkRecord(stack.Pop(), what)
+		if err != nil {
+			return err
+		}
+		stack.Push(r.Fields[i])
+		return nil
+	}})
+	return nil
+}
+
+func FnRecordModifier(nargs int) error {
+	if nargs != 2 {
+		return errors.New("record-modifier takes 2 arguments")
+	}
+
+	t, err := popRecordType("record-modifier")
+	if err != nil {
+		return err
+	}
+	field := stack.Pop()
+	i, err := t.fieldIndex(field)
+	if err != nil {
+		return err
+	}
+
+	what := fmt.Sprintf("Modifier for field %s", SymbolNames[t.Fields[i]])
+	stack.Push(&Procedure{Builtin: func(nargs int) error {
+		if nargs != 2 {
+			return fmt.Errorf("%s takes 2 arguments", what)
+		}
+
+		r, err := t.checkRecord(stack.Pop(), what)
+		if err != nil {
+			return err
+		}
+		r.Fields[i] = stack.Pop()
+		stack.Push(r)
+		return nil
+	}})
+	return nil
+}
+
+func FnIsRecord(nargs int) error {
+	if nargs != 1 {
+		return errors.New("record? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(*Record)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnRecordTypeDescriptor(nargs int) error {
+	if nargs != 1 {
+		return errors.New("record-type-descriptor takes 1 argument")
+	}
+
+	r, ok := stack.Pop().(*Record)
+	if !ok {
+		return errors.New("record-type-descriptor takes a record")
+	}
+	stack.Push(r.Type)
+	return nil
+}
+
+func FnRecordTypeName(nargs int) error {
+	if nargs != 1 {
+		return errors.New("record-type-name takes 1 argument")
+	}
+
+	t, err := popRecordType("record-type-name")
+	if err != nil {
+		return err
+	}
+	stack.Push(t.Name)
+	return nil
+}
+
+func FnRecordTypeFieldNames(nargs int) error {
+	if nargs != 1 {
+		return errors.New("record-type-field-names takes 1 argument")
+	}
+
+	t, err := popRecordType("record-type-field-names")
+	if err != nil {
+		return err
+	}
+	fields := make([]Value, len(t.Fields))
+	for i, field := range t.Fields {
+		fields[i] = field
+	}
+	stack.Push(vec2list(fields))
+	return nil
+}
@@ -0,0 +1,544 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The regexp procedures take a regexp, or a string or SRE to compile into
+// one.  Strings are in Go's syntax, and SREs are the s-expressions of SRFI
+// 115, which are translated into it.  Positions are indices of chars.
+
+// sreClasses has the char sets SREs name, as the contents of a bracketed
+// class.
+var sreClasses = map[string]string{
+	"any":          `\x00-\x{10FFFF}`,
+	"ascii":        `\x00-\x7F`,
+	"alpha":        `\p{L}`,
+	"alphabetic":   `\p{L}`,
+	"digit":        `\p{Nd}`,
+	"numeric":      `\p{Nd}`,
+	"num":          `\p{Nd}`,
+	"alnum":        `\p{L}\p{Nd}`,
+	"alphanumeric": `\p{L}\p{Nd}`,
+	"alphanum":     `\p{L}\p{Nd}`,
+	"space":        `\s\p{Z}`,
+	"white":        `\s\p{Z}`,
+	"whitespace":   `\s\p{Z}`,
+	"upper":        `\p{Lu}`,
+	"upper-case":   `\p{Lu}`,
+	"lower":        `\p{Ll}`,
+	"lower-case":   `\p{Ll}`,
+	"punct":        `\p{P}`,
+	"punctuation":  `\p{P}`,
+	"symbol":       `\p{S}`,
+	"graph":        `\p{L}\p{M}\p{N}\p{P}\p{S}`,
+	"graphic":      `\p{L}\p{M}\p{N}\p{P}\p{S}`,
+	"print":        `\p{L}\p{M}\p{N}\p{P}\p{S}\p{Zs}`,
+	"printing":     `\p{L}\p{M}\p{N}\p{P}\p{S}\p{Zs}`,
+	"cntrl":        `\p{Cc}`,
+	"control":      `\p{Cc}`,
+	"xdigit":       `0-9A-Fa-f`,
+	"hex-digit":    `0-9A-Fa-f`,
+}
+
+// sreAnchors has the other SREs that are symbols.
+var sreAnchors = map[string]string{
+	"nonl":    `[^\n]`,
+	"bos":     `\A`,
+	"eos":     `\z`,
+	"bol":     `(?m:^)`,
+	"eol":     `(?m:$)`,
+	"bow":     `\b`,
+	"eow":     `\b`,
+	"nwb":     `\B`,
+	"epsilon": `(?:)`,
+}
+
+func classRune(r rune) string {
+	if strings.ContainsRune(`\]-^[`, r) {
+		return `\` + string(r)
+	}
+	return regexp.QuoteMeta(string(r))
+}
+
+// sreClass returns the contents of a bracketed class matching the char set
+// sre, and whether sre is a char set at all.
+func sreClass(sre Value) (string, bool, error) {
+	switch v := sre.(type) {
+	case Symbol:
+		class, ok := sreClasses[SymbolNames[v]]
+		return class, ok, nil
+	case Char:
+		return classRune(rune(v)), true, nil
+	case *Pair:
+		if v == Empty {
+			return "", false, nil
+		}
+		args, err := list2vec(v)
+		if err != nil {
+			return "", false, err
+		}
+		if s, ok := args[0].(String); ok && len(args) == 1 { // ("abc")
+			return sreChars(*s.r), true, nil
+		}
+		head, ok := args[0].(Symbol)
+		if !ok {
+			return "", false, nil
+		}
+
+		var sb strings.Builder
+		switch SymbolNames[head] {
+		case "char-set":
+			for _, arg := range args[1:] {
+				s, ok := arg.(String)
+				if !ok {
+					return "", false, errors.New("SRE char-set takes strings")
+				}
+				sb.WriteString(sreChars(*s.r))
+			}
+		case "/", "char-range":
+			rs := []rune{}
+			for _, arg := range args[1:] {
+				switch arg := arg.(type) {
+				case String:
+					rs = append(rs, *arg.r...)
+				case Char:
+					rs = append(rs, rune(arg))
+				default:
+					return "", false, errors.New("SRE ranges take strings and chars")
+				}
+			}
+			if len(rs)%2 != 0 {
+				return "", false, errors.New("SRE ranges need pairs of chars")
+			}
+			for i := 0; i < len(rs); i += 2 {
+				sb.WriteString(classRune(rs[i]) + "-" + classRune(rs[i+1]))
+			}
+		case "or", "|":
+			if len(args) == 1 {
+				return "", false, nil
+			}
+			for _, arg := range args[1:] {
+				class, ok, err := sreClass(arg)
+				if !ok || err != nil {
+					return "", false, err
+				}
+				sb.WriteString(class)
+			}
+		default:
+			return "", false, nil
+		}
+		return sb.String(), true, nil
+	}
+	return "", false, nil
+}
+
+func sreChars(rs []rune) string {
+	var sb strings.Builder
+	for _, r := range rs {
+		sb.WriteString(classRune(r))
+	}
+	return sb.String()
+}
+
+// sreSeq translates each of a list of SREs and joins the results.
+func sreSeq(sres []Value) (string, error) {
+	var sb strings.Builder
+	for _, sre := range sres {
+		s, err := sreString(sre)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(s)
+	}
+	return sb.String(), nil
+}
+
+func sreCount(v Value) (string, error) {
+	n, ok := toIndex(v)
+	if !ok {
+		return "", errors.New("SRE repetitions take counts")
+	}
+	return strconv.Itoa(n), nil
+}
+
+// sreString translates sre into Go's syntax.
+func sreString(sre Value) (string, error) {
+	switch v := sre.(type) {
+	case String:
+		return regexp.QuoteMeta(v.String()), nil
+	case Char:
+		return regexp.QuoteMeta(string(v)), nil
+	case Symbol:
+		if class, ok := sreClasses[SymbolNames[v]]; ok {
+			return "[" + class + "]", nil
+		}
+		if anchor, ok := sreAnchors[SymbolNames[v]]; ok {
+			return anchor, nil
+		}
+		return "", fmt.Errorf("Unknown SRE %s", SymbolNames[v])
+	case *Pair:
+		if class, ok, err := sreClass(v); err != nil {
+			return "", err
+		} else if ok {
+			return "[" + class + "]", nil
+		}
+		if v == Empty {
+			break
+		}
+		args, err := list2vec(v)
+		if err != nil {
+			return "", err
+		}
+		head, ok := args[0].(Symbol)
+		if !ok {
+			break
+		}
+
+		name := SymbolNames[head]
+		switch name {
+		case ":", "seq":
+			return sreSeq(args[1:])
+		case "or", "|":
+			alts := make([]string, len(args)-1)
+			for i, arg := range args[1:] {
+				if alts[i], err = sreString(arg); err != nil {
+					return "", err
+				}
+			}
+			if len(alts) == 0 {
+				return `[^\x00-\x{10FFFF}]`, nil
+			}
+			return "(?:" + strings.Join(alts, "|") + ")", nil
+		case "*", "+", "?", "*?", "??":
+			s, err := sreSeq(args[1:])
+			return "(?:" + s + ")" + name, err
+		case "=", ">=", "**", "**?":
+			if len(args) < 2 || (strings.HasPrefix(name, "**") && len(args) < 3) {
+				return "", fmt.Errorf("SRE %s takes counts", name)
+			}
+			n, err := sreCount(args[1])
+			if err != nil {
+				return "", err
+			}
+			rest := args[2:]
+			switch name {
+			case "=":
+				n = "{" + n + "}"
+			case ">=":
+				n = "{" + n + ",}"
+			default:
+				m, err := sreCount(args[2])
+				if err != nil {
+					return "", err
+				}
+				n = "{" + n + "," + m + "}" + strings.TrimPrefix(name, "**")
+				rest = args[3:]
+			}
+			s, err := sreSeq(rest)
+			return "(?:" + s + ")" + n, err
+		case "submatch", "$":
+			s, err := sreSeq(args[1:])
+			return "(" + s + ")", err
+		case "submatch-named", "->":
+			if len(args) < 2 {
+				return "", fmt.Errorf("SRE %s takes a name", name)
+			}
+			sym, ok := args[1].(Symbol)
+			if !ok {
+				return "", fmt.Errorf("SRE %s takes a symbol as the name", name)
+			}
+			s, err := sreSeq(args[2:])
+			return "(?P<" + SymbolNames[sym] + ">" + s + ")", err
+		case "w/nocase":
+			s, err := sreSeq(args[1:])
+			return "(?i:" + s + ")", err
+		case "w/case":
+			s, err := sreSeq(args[1:])
+			return "(?-i:" + s + ")", err
+		case "~", "complement":
+			var sb strings.Builder
+			for _, arg := range args[1:] {
+				class, ok, err := sreClass(arg)
+				if err != nil {
+					return "", err
+				} else if !ok {
+					return "", fmt.Errorf("SRE %s takes char sets", name)
+				}
+				sb.WriteString(class)
+			}
+			return "[^" + sb.String() + "]", nil
+		case "look-ahead", "look-behind", "neg-look-ahead", "neg-look-behind",
+			"backref", "-", "&", "difference", "and":
+			return "", fmt.Errorf("SRE %s isn't supported", name)
+		}
+	}
+	return "", fmt.Errorf("Invalid SRE %s", ValueString(sre, false))
+}
+
+// compileRegexp compiles a string in Go's syntax, or an SRE.
+func compileRegexp(v Value) (*Regexp, error) {
+	var src string
+	if s, ok := v.(String); ok {
+		src = s.String()
+	} else {
+		var err error
+		if src, err = sreString(v); err != nil {
+			return nil, err
+		}
+	}
+	re, err := regexp.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Regexp{Regexp: re, Source: v}, nil
+}
+
+func popRegexp() (*Regexp, error) {
+	v := stack.Pop()
+	if re, ok := v.(*Regexp); ok {
+		return re, nil
+	}
+	return compileRegexp(v)
+}
+
+// matchArgs pops the regexp, the string and its optional range.  It returns
+// the part of the string in range, and the index it starts at.
+func matchArgs(name string, nargs int) (*Regexp, string, int, error) {
+	if nargs < 2 || nargs > 4 {
+		return nil, "", 0, fmt.Errorf("%s takes 2 to 4 arguments", name)
+	}
+
+	re, err := popRegexp()
+	if err != nil {
+		return nil, "", 0, err
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return nil, "", 0, fmt.Errorf("%s takes a string as the second argument", name)
+	}
+	start, end, err := popRange(name, nargs, 2, len(*s.r))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return re, string((*s.r)[start:end]), start, nil
+}
+
+// submatches returns the value of each submatch given its byte offsets in s,
+// or #f for those that didn't match.
+func submatches(s string, loc []int, value func(start, end int) Value) []Value {
+	res := make([]Value, len(loc)/2)
+	for i := range res {
+		if loc[2*i] < 0 {
+			res[i] = Boolean(false)
+		} else {
+			res[i] = value(loc[2*i], loc[2*i+1])
+		}
+	}
+	return res
+}
+
+func FnRegexp(nargs int) error {
+	if nargs != 1 {
+		return errors.New("regexp takes 1 argument")
+	}
+
+	re, err := popRegexp()
+	if err != nil {
+		return err
+	}
+	stack.Push(re)
+	return nil
+}
+
+func FnIsRegexp(nargs int) error {
+	if nargs != 1 {
+		return errors.New("regexp? takes 1 argument")
+	}
+
+	_, ok := stack.Pop().(*Regexp)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnRegexpQuote(nargs int) error {
+	if nargs != 1 {
+		return errors.New("regexp-quote takes 1 argument")
+	}
+
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("regexp-quote takes a string as the argument")
+	}
+	stack.Push(NewString(regexp.QuoteMeta(s.String())))
+	return nil
+}
+
+// FnRegexpMatch returns a list of the first match in the string and each of
+// its submatches, or #f if there's no match.
+func FnRegexpMatch(nargs int) error {
+	re, s, _, err := matchArgs("regexp-match", nargs)
+	if err != nil {
+		return err
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		stack.Push(Boolean(false))
+		return nil
+	}
+	stack.Push(vec2list(submatches(s, loc, func(start, end int) Value {
+		return NewString(s[start:end])
+	})))
+	return nil
+}
+
+// FnRegexpMatchPositions is like regexp-match, but gives the start and end
+// of each match as a pair.
+func FnRegexpMatchPositions(nargs int) error {
+	re, s, offset, err := matchArgs("regexp-match-positions", nargs)
+	if err != nil {
+		return err
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		stack.Push(Boolean(false))
+		return nil
+	}
+	stack.Push(vec2list(submatches(s, loc, func(start, end int) Value {
+		start, end = runeIndex(s, start)+offset, runeIndex(s, end)+offset
+		var car, cdr Value = Integer(*big.NewInt(int64(start))),
+			Integer(*big.NewInt(int64(end)))
+		return &Pair{&car, &cdr}
+	})))
+	return nil
+}
+
+// FnRegexpSearch returns an alist of the first match and its submatches, each
+// under its name as a symbol if it has one, or its number if not.  The whole
+// match is number 0.
+func FnRegexpSearch(nargs int) error {
+	re, s, _, err := matchArgs("regexp-search", nargs)
+	if err != nil {
+		return err
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		stack.Push(Boolean(false))
+		return nil
+	}
+	names := re.SubexpNames()
+	matches := submatches(s, loc, func(start, end int) Value {
+		return NewString(s[start:end])
+	})
+	for i, match := range matches {
+		match := match
+		var key Value = Integer(*big.NewInt(int64(i)))
+		if names[i] != "" {
+			key = Str2Sym(names[i])
+		}
+		matches[i] = &Pair{&key, &match}
+	}
+	stack.Push(vec2list(matches))
+	return nil
+}
+
+// FnIsRegexpMatches says whether the regexp matches the whole string.
+func FnIsRegexpMatches(nargs int) error {
+	re, s, _, err := matchArgs("regexp-matches?", nargs)
+	if err != nil {
+		return err
+	}
+	if re.whole == nil {
+		re.whole = regexp.MustCompile(`\A(?:` + re.String() + `)\z`)
+	}
+	stack.Push(Boolean(re.whole.MatchString(s)))
+	return nil
+}
+
+// regexpReplace replaces up to n matches in the string, or all of them if n
+// is negative.  The replacement is either a string, in which $1 or ${name}
+// stand for submatches, or a procedure, which is called with the match and
+// each submatch and returns the string to replace them with.
+func regexpReplace(name string, nargs int, n int) error {
+	if nargs != 3 {
+		return fmt.Errorf("%s takes 3 arguments", name)
+	}
+
+	re, err := popRegexp()
+	if err != nil {
+		return err
+	}
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return fmt.Errorf("%s takes a string as the second argument", name)
+	}
+	replacement := stack.Pop()
+	template, isTemplate := replacement.(String)
+	proc, isProc := replacement.(*Procedure)
+	if !isTemplate && !isProc {
+		return fmt.Errorf("%s takes a string or procedure as the replacement", name)
+	}
+
+	s := str.String()
+	var res []byte
+	last, reserved := 0, 0
+	for _, loc := range re.FindAllStringSubmatchIndex(s, n) {
+		// Each replacement can be as long as s, so reserve as it grows
+		if err := reserve(len(res)-reserved, runeSize); err != nil {
+			return err
+		}
+		reserved = len(res)
+		res = append(res, s[last:loc[0]]...)
+		if isTemplate {
+			res = re.ExpandString(res, template.String(), s, loc)
+		} else {
+			args := submatches(s, loc, func(start, end int) Value {
+				return NewString(s[start:end])
+			})
+			v, err := Apply(proc, args...)
+			if err != nil {
+				return err
+			}
+			rep, ok := v.(String)
+			if !ok {
+				return fmt.Errorf("%s: replacement procedure must return a string", name)
+			}
+			res = append(res, rep.String()...)
+		}
+		last = loc[1]
+	}
+	res = append(res, s[last:]...)
+	if err := reserve(len(res)-reserved, runeSize); err != nil {
+		return err
+	}
+	stack.Push(NewString(string(res)))
+	return nil
+}
+
+func FnRegexpReplace(nargs int) error {
+	return regexpReplace("regexp-replace", nargs, 1)
+}
+
+func FnRegexpReplaceAll(nargs int) error {
+	return regexpReplace("regexp-replace-all", nargs, -1)
+}
+
+// FnRegexpSplit returns the parts of the string between matches.
+func FnRegexpSplit(nargs int) error {
+	re, s, _, err := matchArgs("regexp-split", nargs)
+	if err != nil {
+		return err
+	}
+	parts := re.Split(s, -1)
+	res := make([]Value, len(parts))
+	for i, part := range parts {
+		res[i] = NewString(part)
+	}
+	stack.Push(vec2list(res))
+	return nil
+}
@@ -0,0 +1,351 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A sandbox is an environment for code that isn't trusted with everything the
+// base environment can do.  It has the bindings of the base environment, but
+// the procedures its profile doesn't allow are replaced by ones that raise an
+// error, so that code can catch a denial and carry on.  Inside a sandbox,
+// eval, load and the environment procedures only work with sandboxes, so
+// there is no way back out to the top level.
+
+// A Profile says what a sandbox may do.  Deny names capabilities, as in
+// Capabilities, that it doesn't have, and if Allow isn't nil, no base
+// procedures but those it names can be used at all.  Grant names the
+// privileged capabilities it has, which no profile has otherwise.  Only the
+// host can grant them, as profiles made in Scheme never do.
+type Profile struct {
+	Deny  []string
+	Allow []string
+	Grant []string
+}
+
+// Capabilities groups the base procedures that reach outside the interpreter
+// by what they reach.  The console capability is separate, as its procedures
+// still work with string and bytevector ports when it is denied.
+var Capabilities = map[string][]string{
+	"process":     {"exit"},
+	"environment": {"get-environment-variables", "get-environment-variable"},
+	"file-read": {
+		"open-input-file", "call-with-input-file", "with-input-from-file",
+		"load",
+	},
+	"file-write": {
+		"open-output-file", "call-with-output-file", "with-output-to-file",
+	},
+	"foreign": {"go-call", "go-field", "go-methods"},
+}
+
+// Privileged capabilities are denied to every sandbox not granted them, so
+// that the host has to opt in.  Calling Go methods reaches whatever the
+// values passed in can reach, which the host knows and a profile can't.
+var Privileged = []string{"foreign"}
+
+// consolePorts has, for each procedure that reads, writes or closes a port,
+// which argument is the port, or -1 if it always uses the current port.
+var consolePorts = map[string]struct {
+	arg   int
+	input bool
+}{
+	"read": {0, true}, "read-char": {0, true}, "peek-char": {0, true},
+	"char-ready?": {0, true}, "read-line": {0, true}, "read-u8": {0, true},
+	"peek-u8": {0, true}, "u8-ready?": {0, true}, "read-string": {1, true},
+	"read-bytevector": {1, true}, "read-bytevector!": {1, true},
+	"json-read": {0, true},
+
+	"write": {1, false}, "write-shared": {1, false}, "write-simple": {1, false},
+	"display": {1, false}, "write-char": {1, false}, "newline": {0, false},
+	"write-string": {1, false}, "write-u8": {1, false},
+	"write-bytevector": {1, false}, "flush-output-port": {0, false},
+	"pretty-print": {1, false}, "pp": {1, false}, "json-write": {1, false},
+	"print": {-1, false}, "disassemble": {-1, false},
+
+	"close-port": {0, false}, "close-input-port": {0, true},
+	"close-output-port": {0, false},
+}
+
+// Profiles are the profiles sandbox-environment knows by name.
+var Profiles = map[string]Profile{
+	"full":      {},
+	"read-only": {Deny: []string{"file-write", "environment", "process"}},
+	"no-io": {Deny: []string{
+		"file-read", "file-write", "console", "environment", "process",
+	}},
+	"pure": {Deny: []string{
+		"file-read", "file-write", "console", "environment", "process",
+		"foreign",
+	}},
+}
+
+// sandboxKey is bound in the outermost scope of every sandbox, once the
+// first has been made.  It is uninterned, so code inside can't see or change
+// it.
+var sandboxKey Symbol
+
+func markSandbox(scope map[Symbol]Value) {
+	if sandboxKey == 0 {
+		sandboxKey = Uninterned("sandbox")
+	}
+	scope[sandboxKey] = Boolean(true)
+}
+
+// within returns a profile allowing only what both p and q allow.
+func (p Profile) within(q Profile) Profile {
+	res := Profile{
+		Deny:  append(append([]string{}, p.Deny...), q.Deny...),
+		Grant: []string{},
+	}
+	for _, capability := range p.Grant {
+		if contains(q.Grant, capability) {
+			res.Grant = append(res.Grant, capability)
+		}
+	}
+	switch {
+	case p.Allow == nil:
+		res.Allow = q.Allow
+	case q.Allow == nil:
+		res.Allow = p.Allow
+	default:
+		res.Allow = []string{}
+		for _, name := range p.Allow {
+			if contains(q.Allow, name) {
+				res.Allow = append(res.Allow, name)
+			}
+		}
+	}
+	return res
+}
+
+func contains(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// denied returns the capability of p's that name needs but p doesn't have, or
+// "" if p allows it.
+func (p Profile) denied(name string) string {
+	if p.Allow != nil && !contains(p.Allow, name) {
+		return name
+	}
+	deny := p.Deny
+	for _, capability := range Privileged {
+		if !contains(p.Grant, capability) {
+			deny = append(deny[:len(deny):len(deny)], capability)
+		}
+	}
+	for _, capability := range deny {
+		if capability == "console" {
+			if _, ok := consolePorts[name]; ok {
+				return capability
+			}
+		}
+		if contains(Capabilities[capability], name) {
+			return capability
+		}
+	}
+	return ""
+}
+
+// NewSandbox returns an environment with the base bindings that profile
+// allows.
+func NewSandbox(profile Profile) *Procedure {
+	scope := map[Symbol]Value{}
+	markSandbox(scope)
+	env := &Procedure{
+		Scope:  Scope{scope, nil},
+		Args:   Empty,
+		Macros: map[Symbol]SyntaxRules{},
+	}
+	for k, v := range Top.Macros {
+		env.Macros[k] = v
+	}
+
+	for sym, v := range BaseScope {
+		proc, ok := v.(*Procedure)
+		name := SymbolNames[sym]
+		if !ok {
+			scope[sym] = v
+			continue
+		}
+
+		switch capability := profile.denied(name); capability {
+		case "":
+			scope[sym] = proc
+		case "console":
+			scope[sym] = consoleOnly(name, proc)
+		default:
+			scope[sym] = &Procedure{Name: name, Builtin: func(int) error {
+				return &ErrorObject{
+					fmt.Sprintf("%s is not allowed in this sandbox", name),
+					Empty, GeneralError,
+				}
+			}}
+		}
+	}
+	env.bindSandboxed(profile)
+	return env
+}
+
+// bindSandboxed replaces the procedures that take or make environments with
+// ones that keep to sandboxes with profile.
+func (env *Procedure) bindSandboxed(profile Profile) {
+	bind := func(name string, fn func(int) error) {
+		sym := Str2Sym(name)
+		if _, ok := env.Scope.m[sym].(*Procedure); ok && profile.denied(name) == "" {
+			env.Scope.m[sym] = &Procedure{Name: name, Builtin: fn}
+		}
+	}
+
+	bind("eval", func(nargs int) error {
+		if nargs == 2 && !isSandbox(stack[len(stack)-2]) {
+			return errors.New("eval in a sandbox takes a sandbox environment")
+		}
+		return FnEval(nargs)
+	})
+	bind("load", func(nargs int) error {
+		if nargs == 1 {
+			fname := stack.Pop()
+			stack.Push(env)
+			stack.Push(fname)
+			nargs++
+		} else if nargs == 2 && !isSandbox(stack[len(stack)-2]) {
+			return errors.New("load in a sandbox takes a sandbox environment")
+		}
+		return FnLoad(nargs)
+	})
+	bind("scheme-report-environment", func(nargs int) error {
+		if err := FnSchemeReportEnvironment(nargs); err != nil {
+			return err
+		}
+		stack.Pop()
+		stack.Push(NewSandbox(profile))
+		return nil
+	})
+	bind("null-environment", func(nargs int) error {
+		if err := FnNullEnvironment(nargs); err != nil {
+			return err
+		}
+		markSandbox(stack.Top().(*Procedure).Scope.m)
+		return nil
+	})
+	bind("sandbox-environment", func(nargs int) error {
+		inner, err := popProfile(nargs)
+		if err != nil {
+			return err
+		}
+		stack.Push(NewSandbox(inner.within(profile)))
+		return nil
+	})
+}
+
+// isSandbox says whether v is an environment within a sandbox.
+func isSandbox(v Value) bool {
+	env, ok := v.(*Procedure)
+	if !ok {
+		return false
+	}
+	scope := &env.Scope
+	for scope.super != nil {
+		scope = scope.super
+	}
+	_, ok = scope.m[sandboxKey]
+	return ok
+}
+
+// consoleOnly returns a procedure calling proc, unless the port it would use
+// is a file or the console rather than a string or bytevector port.
+func consoleOnly(name string, proc *Procedure) *Procedure {
+	ports := consolePorts[name]
+	return &Procedure{Name: name, Builtin: func(nargs int) error {
+		var port Value
+		switch {
+		case ports.arg >= 0 && nargs > ports.arg:
+			port = stack[len(stack)-1-ports.arg]
+		case ports.input:
+			port = InputPortStack[len(InputPortStack)-1]
+		default:
+			port = OutputPortStack[len(OutputPortStack)-1]
+		}
+
+		var state *PortState
+		switch port := port.(type) {
+		case InputPort:
+			state = port.PortState
+		case OutputPort:
+			state = port.PortState
+		}
+		if state != nil && state.Closer != nil {
+			return &ErrorObject{
+				fmt.Sprintf("%s: port %s is not allowed in this sandbox",
+					name, state.Name),
+				Empty, GeneralError,
+			}
+		}
+
+		if proc.Builtin != nil {
+			return proc.Builtin(nargs)
+		}
+		args := make([]Value, nargs)
+		for i := range args {
+			args[i] = stack.Pop()
+		}
+		res, err := Apply(proc, args...)
+		if err != nil {
+			return err
+		}
+		stack.Push(res)
+		return nil
+	}}
+}
+
+// popProfile pops the argument to sandbox-environment, which is either the
+// name of one of Profiles or a list of the names of the procedures allowed.
+func popProfile(nargs int) (Profile, error) {
+	if nargs != 1 {
+		return Profile{}, errors.New("sandbox-environment takes 1 argument")
+	}
+
+	switch v := stack.Pop().(type) {
+	case Symbol:
+		profile, ok := Profiles[SymbolNames[v]]
+		if !ok {
+			return profile, fmt.Errorf(
+				"sandbox-environment: unknown profile %s", SymbolNames[v])
+		}
+		return profile, nil
+	case *Pair:
+		items, err := list2vec(v)
+		if err != nil {
+			return Profile{}, err
+		}
+		profile := Profile{Allow: []string{}}
+		for _, item := range items {
+			sym, ok := item.(Symbol)
+			if !ok {
+				return profile, errors.New(
+					"sandbox-environment takes a list of symbols")
+			}
+			profile.Allow = append(profile.Allow, SymbolNames[sym])
+		}
+		return profile, nil
+	}
+	return Profile{}, errors.New(
+		"sandbox-environment takes a profile name or a list of symbols")
+}
+
+// FnSandboxEnvironment returns a new sandbox with the profile it is given.
+func FnSandboxEnvironment(nargs int) error {
+	profile, err := popProfile(nargs)
+	if err != nil {
+		return err
+	}
+	stack.Push(NewSandbox(profile))
+	return nil
+}
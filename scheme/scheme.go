@@ -0,0 +1,91 @@
+// Package scheme is the g5 interpreter.  A program embedding it calls Start
+// once, then evaluates code in Top with Exec or LoadFile, or in a sandbox from
+// NewSandbox, and passes values between Go and Scheme with Register, Lookup
+// and Call.
+package scheme
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"unicode"
+)
+
+//go:embed init.scm
+var Init string
+
+//go:embed srfi/case-lambda.scm
+var CaseLambdaSRFI string
+
+//go:embed srfi/lists.scm
+var ListsSRFI string
+
+// Start puts the builtins into the top-level environment and loads the
+// prelude.  Options such as Optimise and FoldCase should be set before it is
+// called, as the prelude is compiled with them.
+func Start() error {
+	Top.Scope = TopScope // Put builtins into top-level scope
+
+	if int(SymLast) != len(SymbolNames) {
+		panic("Symbol table length mismatch")
+	}
+
+	if err := Top.LoadPrelude(); err != nil {
+		return err
+	}
+
+	for k, v := range TopScope.m { // Copy unmodified scope into basescope
+		BaseScope[k] = v
+	}
+	return nil
+}
+
+// Run evaluates each form in code, exiting the program on an error.  Unless
+// quiet is set, the value of each form is printed, as the REPL does.
+func (ctx *Procedure) Run(code string, quiet bool) {
+	p := NewParser(code)
+	p.skipWs()
+
+	for len(p.data) > 0 {
+		v, err := p.GetValue()
+		p.skipWs()
+		if err != nil {
+			log.Fatalf("Error (parse): %v\n", err)
+		}
+
+		ctx.Ins = []Ins{}
+		if err := ctx.GenForm(v); err != nil {
+			log.Fatalf("Error (gen): %v\n", err)
+		}
+
+		if err := ctx.Eval(); err != nil {
+			log.Fatalf("Error (eval): %v\n", err)
+		}
+
+		if !quiet {
+			fmt.Println()
+			if len(stack) > 0 {
+				PrettyPrint(stack.Top(), PrettyWidth)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// Validate reports whether code has something other than whitespace in it and
+// its parentheses balance, so that it can be read as a whole.
+func Validate(code string) bool {
+	nonws := 0
+	count := 0
+	for _, r := range []rune(code) {
+		if !unicode.IsSpace(r) {
+			nonws++
+		}
+		if r == '(' {
+			count++
+		} else if r == ')' {
+			count--
+		}
+	}
+	return count == 0 && nonws != 0
+}
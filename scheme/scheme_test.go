@@ -0,0 +1,766 @@
+package scheme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("G5_CACHE", "") // Keep tests from writing to the user's cache
+	Top.Scope = TopScope // Put builtins into top-level scope
+
+	if int(SymLast) != len(SymbolNames) {
+		panic("Symbol table length mismatch")
+	}
+
+	Top.Run(Init, true)
+	Top.Run(CaseLambdaSRFI, true)
+	Top.Run(ListsSRFI, true)
+
+	for k, v := range TopScope.m { // Copy unmodified scope into basescope
+		BaseScope[k] = v
+	}
+	os.Exit(m.Run())
+}
+
+func TestLambdas(t *testing.T) {
+	Top.Run("(define add (lambda (x y) (+ x y)))", true)
+	result := stack.Top()
+	if _, ok := result.(*Procedure); !ok {
+		t.Errorf("Expected procedure, got %T", result)
+	}
+
+	Top.Run("(add 2 3)", true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Errorf("Expected integer, got %T", result)
+	}
+
+	if result := big.Int(result.(Integer)); result.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Expected integer, got %v", result.String())
+	}
+}
+
+func TestAdder(t *testing.T) {
+	Top.Run("(define make-adder (lambda (x) (lambda (y) (+ x y))))", true)
+	result := stack.Top()
+	if _, ok := result.(*Procedure); !ok {
+		t.Errorf("Expected procedure, got %T", result)
+	}
+
+	Top.Run("(define add-2 (make-adder 2))", true)
+	result = stack.Top()
+	if _, ok := result.(*Procedure); !ok {
+		t.Errorf("Expected procedure, got %T", result)
+	}
+
+	Top.Run("(add-2 3)", true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Errorf("Expected integer, got %T", result)
+	}
+
+	if result := big.Int(result.(Integer)); result.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Expected integer, got %v", result.String())
+	}
+}
+
+func TestCounter(t *testing.T) {
+	Top.Run("(define (make-ctr) (set! count 0)"+
+		"(lambda (ctr) (set! count (+ count 1)) count))", true)
+	result := stack.Top()
+	if _, ok := result.(*Procedure); !ok {
+		t.Errorf("Expected procedure, got %T", result)
+	}
+
+	Top.Run("(define ctr (make-ctr))", true)
+	result = stack.Top()
+	if _, ok := result.(*Procedure); !ok {
+		t.Errorf("Expected procedure, got %T", result)
+	}
+
+	Top.Run("(ctr)", true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Errorf("Expected integer, got %T", result)
+	}
+
+	if result := big.Int(result.(Integer)); result.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Expected 1, got %v", result.String())
+	}
+
+	Top.Run("(ctr)", true)
+	result, ok = stack.Top().(Integer)
+	if !ok {
+		t.Errorf("Expected integer, got %T", result)
+	}
+
+	if result := big.Int(result.(Integer)); result.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("Expected 2, got %v", result.String())
+	}
+}
+
+func TestLet(t *testing.T) {
+	Top.Run("(let ((a 0) (b 1)) b)", true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Errorf("Expected integer, got %T", result)
+	}
+
+	if result := big.Int(result); result.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Expected integer, got %v", result.String())
+	}
+}
+
+func TestAnd(t *testing.T) {
+	Top.Run("(and #t #t)", true)
+	result, ok := stack.Top().(Boolean)
+	if !ok {
+		t.Errorf("Expected boolean, got %T", result)
+	}
+
+	if result != true {
+		t.Errorf("Expected #t, got #f")
+	}
+}
+
+func TestOr(t *testing.T) {
+	Top.Run("(or #t #f)", true)
+	result, ok := stack.Top().(Boolean)
+	if !ok {
+		t.Errorf("Expected boolean, got %T", result)
+	}
+
+	if result != true {
+		t.Errorf("Expected true, got false")
+	}
+}
+
+func TestLetrec(t *testing.T) {
+	Top.Run("(letrec ((a #t)) #t)", true)
+	result, ok := stack.Top().(Boolean)
+	if !ok {
+		t.Errorf("Expected boolean, got %T", result)
+	}
+
+	if result != true {
+		t.Errorf("Expected true, got false")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	lib := t.TempDir()
+	os.WriteFile(dir+"/main.scm",
+		[]byte(`(load "helper.scm") (define loaded-main (+ helper 1))`), 0644)
+	os.WriteFile(dir+"/helper.scm", []byte(`(define helper 41)`), 0644)
+	os.WriteFile(lib+"/lib.scm", []byte(`(define from-lib 7)`), 0644)
+	t.Setenv("G5_PATH", lib)
+
+	Top.Run(`(load "`+dir+`/main.scm")`, true)
+	if result, ok := stack.Top().(Boolean); !ok || !bool(result) {
+		t.Errorf("Expected #t from first load, got %v", stack.Top())
+	}
+
+	Top.Run("loaded-main", true)
+	result, ok := stack.Top().(Integer)
+	if !ok {
+		t.Fatalf("Expected integer, got %T", stack.Top())
+	}
+	if result := big.Int(result); result.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("Expected 42, got %v", result.String())
+	}
+
+	Top.Run(`(load "`+dir+`/main.scm")`, true)
+	if result, ok := stack.Top().(Boolean); !ok || bool(result) {
+		t.Errorf("Expected #f from repeated load, got %v", stack.Top())
+	}
+
+	Top.Run(`(load "lib.scm")`, true)
+	Top.Run("from-lib", true)
+	result, ok = stack.Top().(Integer)
+	if !ok {
+		t.Fatalf("Expected integer, got %T", stack.Top())
+	}
+	if result := big.Int(result); result.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("Expected 7, got %v", result.String())
+	}
+
+	// Loading into another environment evaluates the file again there
+	Top.Run(`(define report-env (scheme-report-environment 5))
+		(define report-loaded (load "lib.scm" report-env))
+		(list report-loaded (eval 'from-lib report-env))`, true)
+	if got := ValueString(stack.Top(), true); got != "(#t 7)" {
+		t.Errorf("Expected (#t 7) from load into another environment, got %s", got)
+	}
+
+	// A load that fails can be retried once the file is fixed
+	os.WriteFile(dir+"/broken.scm", []byte(`(define fixed no-such-variable)`), 0644)
+	if _, err := Top.LoadFile(dir + "/broken.scm"); err == nil {
+		t.Errorf("Expected an error loading broken.scm")
+	}
+	os.WriteFile(dir+"/broken.scm", []byte(`(define fixed 'yes)`), 0644)
+	if loaded, err := Top.LoadFile(dir + "/broken.scm"); err != nil || !loaded {
+		t.Errorf("Expected broken.scm to load once fixed, got %v, %v", loaded, err)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	buf := &bytes.Buffer{}
+	OutputPortStack = append(OutputPortStack, NewOutputPort(buf, nil, "buffer"))
+	Top.Run("(define (disasm-me x) (+ x 1)) (disassemble disasm-me)", true)
+	OutputPortStack = OutputPortStack[:len(OutputPortStack)-1]
+
+	expected := "0000 LAMBDA (x)\n" +
+		"    0000 IMM 1\n" +
+		"    0001 GETVAR x\n" +
+		"    0002 GETVAR +\n" +
+		"    0003 CALL 2\n"
+	if buf.String() != expected {
+		t.Errorf("Expected listing:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestFoldCase(t *testing.T) {
+	read := func(code string) []Value {
+		p := NewParser(code)
+		res := []Value{}
+		for p.skipWs(); len(p.data) > 0; p.skipWs() {
+			v, err := p.GetValue()
+			if err != nil {
+				t.Fatalf("%s: %v", code, err)
+			}
+			res = append(res, v)
+		}
+		return res
+	}
+
+	if syms := read("Foo foo"); syms[0] == syms[1] {
+		t.Errorf("Expected Foo and foo to be different symbols")
+	}
+	syms := read("Foo #!fold-case Foo #!no-fold-case Foo")
+	if syms[0] != Str2Sym("Foo") || syms[1] != Str2Sym("foo") ||
+		syms[2] != Str2Sym("Foo") {
+		t.Errorf("Expected Foo foo Foo, got %v", syms)
+	}
+
+	FoldCase = true
+	defer func() { FoldCase = false }()
+	if syms := read("Foo #!no-fold-case Foo"); syms[0] != Str2Sym("foo") ||
+		syms[1] != Str2Sym("Foo") {
+		t.Errorf("Expected foo Foo, got %v", syms)
+	}
+}
+
+func TestUninterned(t *testing.T) {
+	if Str2Sym("interned") != Str2Sym("interned") {
+		t.Errorf("Expected symbols with the same name to be interned")
+	}
+
+	sym := Uninterned("interned")
+	if sym == Str2Sym("interned") || IsInterned(sym) {
+		t.Errorf("Expected an uninterned symbol to be distinct")
+	}
+	if SymbolNames[sym] != "interned" {
+		t.Errorf("Expected uninterned symbol to keep its name")
+	}
+
+	Top.Run("(let ((g (gensym))) (list (eq? g g) (symbol-interned? g)"+
+		" (eq? (string->uninterned-symbol \"a\") 'a)))", true)
+	if res := ValueString(stack.Top(), false); res != "(#t #f #f)" {
+		t.Errorf("Expected (#t #f #f), got %s", res)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// Each loop runs far deeper than calls may nest unless every iteration
+	// is a proper tail call
+	defer func(depth int) { MaxCallDepth = depth }(MaxCallDepth)
+	MaxCallDepth = 1000
+	defer func() { Optimise = true }()
+
+	for _, opt := range []bool{false, true} {
+		Optimise = opt
+		env := &Procedure{
+			Scope:  Scope{map[Symbol]Value{}, &Top.Scope},
+			Macros: Top.Macros,
+		}
+		err := env.Exec(`
+			(define (tail-if n) (if (= n 0) 'done (tail-if (- n 1))))
+			(define (tail-cond n)
+			  (cond ((= n 0) 'done)
+			        ((= (remainder n 2) 1) (tail-cond (- n 1)))
+			        (else (tail-cond (- n 1)))))
+			(define (tail-case n)
+			  (case (remainder n 2)
+			    ((0) (if (= n 0) 'done (tail-case (- n 1))))
+			    (else (tail-case (- n 1)))))
+			(define (tail-and n) (and #t (if (= n 0) 'done (tail-and (- n 1)))))
+			(define (tail-or n) (or (and (= n 0) 'done) (tail-or (- n 1))))
+			(define (tail-when n) (if (= n 0) 'done (when #t (tail-when (- n 1)))))
+			(define (tail-unless n) (if (= n 0) 'done (unless #f (tail-unless (- n 1)))))
+			(define (tail-begin n) (if (= n 0) 'done (begin n (tail-begin (- n 1)))))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{
+			"tail-if", "tail-cond", "tail-case", "tail-and", "tail-or",
+			"tail-when", "tail-unless", "tail-begin",
+		} {
+			if err := env.Exec("(" + name + " 20000)"); err != nil {
+				t.Errorf("%s: %v", name, err)
+			} else if result := stack.Top(); result != Str2Sym("done") {
+				t.Errorf("%s: expected done, got %v", name, result)
+			}
+		}
+	}
+}
+
+// evalTest is an expression and the written form of the value it should
+// evaluate to.
+type evalTest struct {
+	expr, expected string
+}
+
+// newTestEnv returns an environment within the top level, so that what a test
+// defines there doesn't outlast it.
+func newTestEnv() *Procedure {
+	return &Procedure{
+		Scope:  Scope{map[Symbol]Value{}, &Top.Scope},
+		Macros: Top.Macros,
+	}
+}
+
+// checkEval evaluates each test's expression in env and compares the written
+// form of its value with what was expected.
+func checkEval(t *testing.T, env *Procedure, tests []evalTest) {
+	t.Helper()
+	for _, test := range tests {
+		if err := env.Exec(test.expr); err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+		} else if res := ValueString(stack.Top(), false); res != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.expr, test.expected, res)
+		}
+	}
+}
+
+// checkErrors evaluates each expression in env and expects an error from it.
+func checkErrors(t *testing.T, env *Procedure, exprs []string) {
+	t.Helper()
+	for _, expr := range exprs {
+		if err := env.Exec(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	env := newTestEnv()
+	for name, fn := range map[string]interface{}{
+		"go-add":   func(a, b int) int { return a + b },
+		"go-upper": strings.ToUpper,
+		"go-words": strings.Fields,
+		"go-join":  func(sep string, words ...string) string { return strings.Join(words, sep) },
+		"go-count": func(words []string) map[string]int {
+			counts := map[string]int{}
+			for _, w := range words {
+				counts[w]++
+			}
+			return counts
+		},
+		"go-div": func(a, b int64) (int64, int64, error) {
+			if b == 0 {
+				return 0, 0, errors.New("division by zero")
+			}
+			return a / b, a % b, nil
+		},
+		"go-half":  func(x float64) float64 { return x / 2 },
+		"go-big":   func(x *big.Int) *big.Int { return x.Mul(x, x) },
+		"go-apply": func(f func(int) int, x int) int { return f(x) },
+		"go-any":   func(x interface{}) string { return fmt.Sprintf("%T", x) },
+		"go-index": func(xs []int, i int) int { return xs[i] },
+	} {
+		if err := env.Register(name, fn); err != nil {
+			t.Fatalf("Register %s: %v", name, err)
+		}
+	}
+
+	checkEval(t, env, []evalTest{
+		{`(go-add 2 3)`, "5"},
+		{`(go-upper "héllo")`, `"HÉLLO"`},
+		{`(go-words " a b  c ")`, `("a" "b" "c")`},
+		{`(go-join "-" "a" "b" "c")`, `"a-b-c"`},
+		{`(go-count (vector "a" "b" "a"))`, `(("a" . 2) ("b" . 1))`},
+		{`(call-with-values (lambda () (go-div 7 2)) list)`, "(3 1)"},
+		{`(go-half 3)`, "3/2"},
+		{`(go-big (expt 10 20))`, "10000000000000000000000000000000000000000"},
+		{`(go-apply (lambda (x) (* x 10)) 4)`, "40"},
+		{`(list (go-any 1) (go-any "s") (go-any '(1 "a")) (go-any 'sym))`,
+			`("int" "string" "[]interface {}" "scheme.Symbol")`},
+		{`(guard (e ((error-object? e) 'caught)) (go-index (list 1 2) 5))`, "caught"},
+	})
+	checkErrors(t, env, []string{
+		`(go-div 1 0)`,
+		`(go-add 1)`,
+		`(go-add 1 "2")`,
+		`(go-add (expt 2 70) 1)`,
+		`(go-index (list 1 2) 5)`,
+	})
+}
+
+func TestCall(t *testing.T) {
+	env := newTestEnv()
+	if err := env.Exec(`
+		(define (add . xs) (apply + xs))
+		(define (adult? person) (>= (cdr (assoc "age" person)) 18))
+		(define (fail x) (error "failed with" x))
+		(define (both x) (values x x))`); err != nil {
+		t.Fatal(err)
+	}
+	adult, err := env.Lookup("adult?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	depth := len(stack)
+
+	for _, test := range []struct {
+		proc     interface{}
+		args     []interface{}
+		expected interface{}
+	}{
+		{"add", []interface{}{1, 2, 3}, 6},
+		{"add", nil, 0},
+		{"string-upcase", []interface{}{"abc"}, "ABC"},
+		{"list", []interface{}{1, "a", []int{2}}, []interface{}{1, "a", []interface{}{2}}},
+		{adult, []interface{}{map[string]int{"age": 20}}, true},
+		{adult, []interface{}{map[string]int{"age": 9}}, false},
+		{func(a, b int) int { return a * b }, []interface{}{6, 7}, 42},
+		{"expt", []interface{}{2, 70}, new(big.Int).Lsh(big.NewInt(1), 70)},
+	} {
+		res, err := env.Call(test.proc, test.args...)
+		if err != nil {
+			t.Errorf("%T %v: %v", test.proc, test.args, err)
+		} else if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("%T %v: expected %#v, got %#v", test.proc, test.args, test.expected, res)
+		}
+	}
+
+	for _, proc := range []interface{}{"fail", "both", "no-such-procedure", 5} {
+		if _, err := env.Call(proc, 1); err == nil {
+			t.Errorf("%v: expected an error", proc)
+		}
+	}
+	// Both keys would become 1, and one would be lost
+	if _, err := env.Call("list", map[interface{}]int{1: 1, int64(1): 2}); err == nil {
+		t.Errorf("Expected an error for keys that convert alike")
+	}
+	if _, err := FromValue(nil, reflect.TypeOf(0)); err == nil {
+		t.Errorf("Expected an error converting no value")
+	}
+	if len(stack) != depth {
+		t.Errorf("Call left %d values on the stack", len(stack)-depth)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	env := NewSandbox(Profiles["no-io"])
+	if err := env.Register("host-double", func(x int) int { return 2 * x }); err != nil {
+		t.Fatal(err)
+	}
+
+	checkEval(t, env, []evalTest{
+		{`(map (lambda (x) (* x x)) '(1 2 3))`, "(1 4 9)"},
+		{`(host-double 21)`, "42"},
+		{`(guard (e ((error-object? e) (error-object-message e)))
+		    (open-output-file "/tmp/g5-sandbox"))`,
+			`"open-output-file is not allowed in this sandbox"`},
+		{`(guard (e (#t 'denied)) (exit 1))`, "denied"},
+		{`(guard (e (#t 'denied)) (display "hi"))`, "denied"},
+		{`(guard (e (#t 'denied)) (get-environment-variable "HOME"))`, "denied"},
+		{`(guard (e ((error-object? e) (error-object-message e))) (go-methods 1))`,
+			`"go-methods is not allowed in this sandbox"`},
+		{`(let ((port (open-output-string)))
+		    (write '(a "b") port)
+		    (newline port)
+		    (get-output-string port))`, `"(a \"b\")\n"`},
+		{`(read (open-input-string "(1 2)"))`, "(1 2)"},
+		{`(guard (e (#t 'denied)) (eval '(exit 1) map))`, "denied"},
+		{`(guard (e (#t 'denied))
+		    (eval '(exit 1) (scheme-report-environment 5)))`, "denied"},
+		{`(eval '(+ 1 2) (scheme-report-environment 5))`, "3"},
+		{`(let ((inner (sandbox-environment 'full)))
+		    (guard (e (#t 'denied)) (eval '(exit 1) inner)))`, "denied"},
+		{`(let ((inner (sandbox-environment '(car cons))))
+		    (list (eval '(car (cons 1 2)) inner)
+		          (guard (e (#t 'denied)) (eval '(cdr (cons 1 2)) inner))))`,
+			"(1 denied)"},
+	})
+
+	if err := Top.Exec(`(define outside 1)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Exec(`outside`); err == nil {
+		t.Errorf("a sandbox can see bindings made at the top level")
+	}
+
+	pure := NewSandbox(Profiles["pure"])
+	checkEval(t, pure, []evalTest{
+		{`(guard (e (#t 'denied)) (close-port (current-error-port)))`, "denied"},
+		{`(guard (e (#t 'denied)) (close-output-port (current-output-port)))`, "denied"},
+		{`(guard (e (#t 'denied)) (close-input-port (current-input-port)))`, "denied"},
+		{`(let ((port (open-input-string "x")))
+		    (close-port port)
+		    (guard (e (#t 'closed)) (read-char port)))`, "closed"},
+	})
+	checkErrors(t, pure, []string{`(go-methods 1)`, `(sandbox-environment 'unknown)`})
+	if err := NewSandbox(Profiles["read-only"]).Exec(`(display "")`); err != nil {
+		t.Errorf("read-only sandbox can't write to the console: %v", err)
+	}
+
+	// Only the host can grant the foreign procedures
+	granted := NewSandbox(Profile{Grant: []string{"foreign"}})
+	granted.Register("new-account", func(owner string) *testAccount {
+		return &testAccount{Owner: owner}
+	})
+	if err := granted.Exec(`(go-methods (new-account "ann"))`); err != nil {
+		t.Errorf("granted sandbox can't call go-methods: %v", err)
+	}
+	if err := granted.Exec(`(eval '(go-methods 1) (sandbox-environment 'full))`); err == nil ||
+		!strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("sandbox-environment passed on a grant: %v", err)
+	}
+
+	// Each sandbox has its own record of what it has loaded
+	dir := t.TempDir()
+	os.WriteFile(dir+"/sandboxed.scm", []byte(`(define sandboxed 1)`), 0644)
+	for i := 0; i < 2; i++ {
+		box := NewSandbox(Profiles["read-only"])
+		if err := box.Exec(`(load "` + dir + `/sandboxed.scm")`); err != nil {
+			t.Fatal(err)
+		}
+		if res := ValueString(stack.Top(), false); res != "#t" {
+			t.Errorf("Expected #t loading into sandbox %d, got %s", i, res)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	Top.Exec(`(define (limits-deep n) (+ (limits-deep n) 1))`)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// Stops the allocation tests, should their limit not hold, well before
+	// they would run out of memory
+	backstop, cancelBackstop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelBackstop()
+
+	for _, test := range []struct {
+		limits Limits
+		expr   string
+		limit  string
+	}{
+		{Limits{Instructions: 10000}, `(let loop () (loop))`, "instructions"},
+		{Limits{Instructions: 10000},
+			`(let loop () (guard (e (#t (loop))) (loop)))`, "instructions"},
+		{Limits{Instructions: 10000},
+			`(with-exception-handler (lambda (e) 0) (lambda () (let loop () (loop))))`,
+			"instructions"},
+		{Limits{Context: ctx}, `(let loop () (loop))`, "context"},
+		{Limits{StackDepth: 1000}, `(limits-deep 0)`, "stack depth"},
+		{Limits{CallDepth: 100}, `(limits-deep 0)`, "call depth"},
+		{Limits{Allocation: 1 << 20},
+			`(let loop ((l '())) (loop (cons (make-vector 100 0) l)))`,
+			"allocation"},
+		{Limits{Allocation: 1 << 20}, `(make-string 2000000000 #\a)`, "allocation"},
+		{Limits{Allocation: 1 << 20}, `(make-vector 100000000000 0)`, "allocation"},
+		{Limits{Allocation: 1 << 20}, `(make-list 100000000)`, "allocation"},
+		{Limits{Allocation: 1 << 20}, `(make-bytevector 100000000)`, "allocation"},
+		{Limits{Allocation: 1 << 20}, `(string-pad "x" 100000000)`, "allocation"},
+		{Limits{Allocation: 1 << 20, Context: backstop},
+			`(let loop ((s "x")) (loop (string-append s s)))`, "allocation"},
+		{Limits{Allocation: 1 << 20, Context: backstop},
+			`(let loop ((l '(1))) (loop (append l l)))`, "allocation"},
+		{Limits{Allocation: 1 << 20, Context: backstop},
+			`(let loop ((v (vector 1))) (loop (vector-append v v)))`, "allocation"},
+		{Limits{Allocation: 1 << 20, Context: backstop},
+			`(let loop ((s "x")) (loop (regexp-replace-all "x" s "xx")))`, "allocation"},
+		{Limits{Allocation: 1 << 20, Context: backstop},
+			`(let loop ((s "1")) (loop (json-write-string (json-read-string
+			    (string-append "[" s "," s "]")))))`, "allocation"},
+		{Limits{Context: ctx},
+			`(let ((l (list 1))) (set-cdr! l l) (list->vector l))`, "context"},
+	} {
+		depth := len(stack)
+		err := test.limits.Run(func() error { return Top.Exec(test.expr) })
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("%s: expected a limit error, got %v", test.expr, err)
+		} else if limitErr.Limit != test.limit {
+			t.Errorf("%s: expected the %s limit, got %v", test.expr, test.limit, err)
+		}
+		if len(stack) != depth {
+			t.Errorf("%s: left %d values on the stack", test.expr, len(stack)-depth)
+		}
+	}
+	if err := (Limits{Context: ctx}).Run(func() error { return nil }); err != nil {
+		t.Errorf("Run without evaluating anything: %v", err)
+	}
+	if !errors.Is(Limits{Context: ctx}.Run(func() error {
+		return Top.Exec(`(let loop () (loop))`)
+	}), context.DeadlineExceeded) {
+		t.Errorf("Context limit error doesn't wrap the context's error")
+	}
+
+	err := Limits{Instructions: 1000, CallDepth: 100}.Run(func() error {
+		return Top.Exec(`(+ 1 2)`)
+	})
+	if err != nil {
+		t.Errorf("(+ 1 2): %v", err)
+	} else if res := ValueString(stack.Top(), false); res != "3" {
+		t.Errorf("(+ 1 2): expected 3, got %s", res)
+	}
+	if limits != nil {
+		t.Errorf("Limits still in force after Run")
+	}
+}
+
+func TestDeepRecursion(t *testing.T) {
+	// Far deeper than the Go stack allows if each call recursed on it
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+	defer func(depth int) { MaxCallDepth = depth }(MaxCallDepth)
+
+	env := newTestEnv()
+	env.Exec(`(define (deep-count n) (if (= n 0) 0 (+ 1 (deep-count (- n 1)))))`)
+	MaxCallDepth = 0
+	checkEval(t, env, []evalTest{
+		{`(deep-count 100000)`, "100000"},
+		{`(length (let build ((n 100000)) (if (= n 0) '() (cons n (build (- n 1))))))`,
+			"100000"},
+	})
+	MaxCallDepth = 100000
+	checkEval(t, env, []evalTest{{`(deep-count 50000)`, "50000"}})
+	MaxCallDepth = 1000
+	checkEval(t, env, []evalTest{
+		{`(guard (e ((error-object? e) (error-object-message e))) (deep-count 5000))`,
+			`"Maximum call depth (1000) exceeded"`},
+		{`(deep-count 500)`, "500"},
+	})
+	if callDepth != 0 {
+		t.Errorf("Call depth is %d after returning", callDepth)
+	}
+}
+
+type testAccount struct {
+	Owner   string
+	balance int
+}
+
+func (a *testAccount) Deposit(n int) int {
+	a.balance += n
+	return a.balance
+}
+
+func (a *testAccount) String() string { return a.Owner }
+
+func TestGoValues(t *testing.T) {
+	env := newTestEnv()
+	for name, fn := range map[string]interface{}{
+		"new-account":     func(owner string) *testAccount { return &testAccount{Owner: owner} },
+		"account-balance": func(a *testAccount) int { return a.balance },
+		"same":            func(x interface{}) interface{} { return x },
+		"make-chan":       func() chan int { return make(chan int, 1) },
+		"send":            func(c chan int, x int) { c <- x },
+		"recv":            func(c chan int) int { return <-c },
+	} {
+		if err := env.Register(name, fn); err != nil {
+			t.Fatalf("Register %s: %v", name, err)
+		}
+	}
+	GoValuePrinters["chan int"] = func(x interface{}) string {
+		return fmt.Sprintf("cap %d", cap(x.(chan int)))
+	}
+	defer delete(GoValuePrinters, "chan int")
+
+	checkEval(t, env, []evalTest{
+		{`(define acct (new-account "ann"))`, "#<*scheme.testAccount ann>"},
+		{`(list (go-value? acct) (go-value? acct "*scheme.testAccount")
+		        (go-value? acct "other") (go-value? 1) (go-value-tag acct))`,
+			`(#t #t #f #f "*scheme.testAccount")`},
+		{`(let* ((a (go-call acct 'Deposit 5)) (b (go-call acct "Deposit" 2)))
+		    (list a b (account-balance acct) (go-field acct 'Owner)))`,
+			`(5 7 7 "ann")`},
+		{`(go-methods acct)`, `("Deposit" "String")`},
+		{`(let ((h (make-hash-table eqv?)))
+		    (hash-table-set! h acct 'found)
+		    (list (eqv? acct (same acct)) (equal? acct (new-account "ann"))
+		          (hash-table-ref/default h (same acct) #f)))`,
+			"(#t #f found)"},
+		{`(let ((c (make-chan))) (send c 4) (list c (recv c)))`, "(#<chan int cap 1> 4)"},
+	})
+	checkErrors(t, env, []string{
+		`(go-call acct 'Withdraw 1)`,
+		`(go-field acct 'balance)`,
+		`(account-balance (make-chan))`,
+	})
+}
+
+func TestPrettyPrint(t *testing.T) {
+	for _, test := range []struct {
+		code     string
+		width    int
+		expected string
+	}{
+		{"(define (f x) (if (= x 0) 1 (* x (f (- x 1)))))", 30,
+			"(define (f x)\n" +
+				"  (if (= x 0)\n" +
+				"      1\n" +
+				"      (* x (f (- x 1)))))"},
+		{"(let loop ((i 0)) (if (< i 10) (loop (+ i 1)) i))", 30,
+			"(let loop ((i 0))\n" +
+				"  (if (< i 10)\n" +
+				"      (loop (+ i 1))\n" +
+				"      i))"},
+		{"((a . 1) (b . (1 2 3 4 5 6 7 8 9 10 11 12)))", 20,
+			"((a . 1)\n" +
+				" (b 1 2 3 4 5 6 7 8\n" +
+				"    9 10 11 12))"},
+		{"(short list)", 30, "(short list)"},
+		{`(cond ((< x 0) (- x)) ((= x 0) "zero") (else x))`, 20,
+			"(cond\n" +
+				"  ((< x 0) (- x))\n" +
+				"  ((= x 0) \"zero\")\n" +
+				"  (else x))"},
+		{"(case (car x) ((a e i o u) vowel) (else consonant))", 25,
+			"(case (car x)\n" +
+				"  ((a e i o u) vowel)\n" +
+				"  (else consonant))"},
+		{"#0=(alpha (beta #0#) gamma delta . #0#)", 20,
+			"#0=(alpha\n" +
+				"    (beta #0#)\n" +
+				"    gamma\n" +
+				"    delta . #0#)"},
+	} {
+		p := NewParser(test.code)
+		v, err := p.GetValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sb strings.Builder
+		OutputPortStack = append(OutputPortStack, NewOutputPort(&sb, nil, "string"))
+		PrettyPrint(v, test.width)
+		OutputPortStack = OutputPortStack[:len(OutputPortStack)-1]
+		if sb.String() != test.expected {
+			t.Errorf("Expected\n%s\ngot\n%s", test.expected, sb.String())
+		}
+	}
+}
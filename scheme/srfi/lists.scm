@@ -1609,4 +1609,4 @@
 			  lis1))))
 
 
-(define list? proper-list?)
+;(define list? proper-list?) ;;;; list? is a builtin
@@ -0,0 +1,578 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// checkMutable returns an error if s is a literal, which name can't change.
+func checkMutable(name string, s String) error {
+	if s.immutable {
+		return fmt.Errorf("%s: cannot change a string literal", name)
+	}
+	return nil
+}
+
+func equalRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// compareRunes compares a and b by code point, as strings.Compare does.
+func compareRunes(a, b []rune) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+func FnIsString(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string? takes 1 argument")
+	}
+	_, ok := stack.Pop().(String)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnMakeString(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("make-string takes 1 or 2 arguments")
+	}
+	ch := 'X'
+	k, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("make-string takes a length as the first argument")
+	}
+
+	if nargs == 2 {
+		ch_v, ok := stack.Pop().(Char)
+		if !ok {
+			return errors.New("Second argument to make-string must be a char")
+		}
+		ch = rune(ch_v)
+	}
+
+	if err := reserve(k, runeSize); err != nil {
+		return err
+	}
+	rs := make([]rune, k)
+	for i := range rs {
+		if i%limitInterval == 0 {
+			if err := pollLimits(); err != nil {
+				return err
+			}
+		}
+		rs[i] = ch
+	}
+	stack.Push(String{r: &rs})
+	return nil
+}
+
+func FnString(nargs int) error {
+	rs := make([]rune, nargs)
+	for i := range rs {
+		ch, ok := stack.Pop().(Char)
+		if !ok {
+			return errors.New("string takes chars as arguments")
+		}
+		rs[i] = rune(ch)
+	}
+	stack.Push(String{r: &rs})
+	return nil
+}
+
+func FnStringLength(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-length takes 1 argument")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-length takes a string as the argument")
+	}
+	n := len(*s.r)
+	stack.Push(Integer(*big.NewInt(int64(n))))
+	return nil
+}
+
+func FnStringRef(nargs int) error {
+	if nargs != 2 {
+		return errors.New("string-ref takes 2 arguments")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-ref takes a string as the first argument")
+	}
+	idx_v, ok := stack.Pop().(Integer)
+	if !ok {
+		return errors.New(
+			"string-ref takes an integer k as the second argument",
+		)
+	}
+	idx_bi := big.Int(idx_v)
+	rs := *s.r
+	idx := int(idx_bi.Int64())
+	if !idx_bi.IsInt64() || idx < 0 || idx >= len(rs) {
+		return errors.New("string-ref: idx out of range")
+	}
+	stack.Push(Char(rs[idx]))
+	return nil
+}
+
+func FnStringSet(nargs int) error {
+	if nargs != 3 {
+		return errors.New("string-set! takes 3 arguments")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-set! takes a string as the first argument")
+	}
+	v := stack.Pop()
+	idx_v, ok := v.(Integer)
+	if !ok {
+		return errors.New(
+			"string-set! takes an integer k as the second argument",
+		)
+	}
+	ch, ok := stack.Pop().(Char)
+	if !ok {
+		return errors.New("string-set! takes a char as the third argument")
+	}
+	if err := checkMutable("string-set!", s); err != nil {
+		return err
+	}
+	idx_bi := big.Int(idx_v)
+	rs := *s.r
+	idx := int(idx_bi.Int64())
+	if !idx_bi.IsInt64() || idx < 0 || idx >= len(rs) {
+		return errors.New("string-set!: idx out of range")
+	}
+	rs[idx] = rune(ch)
+	stack.Push(s)
+	return nil
+}
+
+func FnStringDowncase(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-downcase takes 1 argument")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-downcase takes a string as the argument")
+	}
+	stack.Push(NewString(downcaseString(*s.r)))
+	return nil
+}
+
+func FnStringFoldcase(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-foldcase takes 1 argument")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-foldcase takes a string as the argument")
+	}
+	stack.Push(NewString(foldString(*s.r)))
+	return nil
+}
+
+func FnSubstring(nargs int) error {
+	if nargs != 3 {
+		return errors.New("substring takes 3 arguments")
+	}
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("substring takes a string as the first argument")
+	}
+
+	start_v := stack.Pop().(Integer)
+	start_bi := big.Int(start_v)
+	start := int(start_bi.Int64())
+
+	end_v := stack.Pop().(Integer)
+	end_bi := big.Int(end_v)
+	end := int(end_bi.Int64())
+
+	rs := *str.r
+
+	if start < 0 || end < 0 || end < start || end > len(rs) {
+		return errors.New("Invalid indices for substring")
+	}
+	if err := reserve(end-start, runeSize); err != nil {
+		return err
+	}
+	substr := append([]rune{}, rs[start:end]...)
+	stack.Push(String{r: &substr})
+	return nil
+}
+
+func FnStringAppend(nargs int) error {
+	n := 0
+	for _, v := range stack[len(stack)-nargs:] {
+		if str, ok := v.(String); ok {
+			n += len(*str.r)
+		}
+	}
+	if err := reserve(n, runeSize); err != nil {
+		return err
+	}
+
+	rs := make([]rune, 0, n)
+	for i := 0; i < nargs; i++ {
+		str, ok := stack.Pop().(String)
+		if !ok {
+			return errors.New("string-append takes strings as arguments")
+		}
+		rs = append(rs, *str.r...)
+	}
+	stack.Push(String{r: &rs})
+	return nil
+}
+
+func FnStringUpcase(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-upcase takes 1 argument")
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-upcase takes a string as the argument")
+	}
+	stack.Push(NewString(upcaseString(*s.r)))
+	return nil
+}
+
+// compareStrings implements the string comparisons, which hold if accept is
+// true of the result of comparing each argument with the next.  The -ci
+// variants compare the arguments' case folded forms.
+func compareStrings(
+	name string,
+	nargs int,
+	ci bool,
+	accept func(int) bool,
+) error {
+	res := true
+	var last []rune
+	for i := 0; i < nargs; i++ {
+		str, ok := stack.Pop().(String)
+		if !ok {
+			return fmt.Errorf("%s takes strings as arguments", name)
+		}
+		rs := *str.r
+		if ci {
+			rs = []rune(foldString(rs))
+		}
+		if i != 0 && !accept(compareRunes(last, rs)) {
+			res = false
+		}
+		last = rs
+	}
+
+	stack.Push(Boolean(res))
+	return nil
+}
+
+func FnStringEq(nargs int) error {
+	return compareStrings("string=?", nargs, false,
+		func(c int) bool { return c == 0 })
+}
+
+func FnStringLt(nargs int) error {
+	return compareStrings("string<?", nargs, false,
+		func(c int) bool { return c < 0 })
+}
+
+func FnStringGt(nargs int) error {
+	return compareStrings("string>?", nargs, false,
+		func(c int) bool { return c > 0 })
+}
+
+func FnStringLe(nargs int) error {
+	return compareStrings("string<=?", nargs, false,
+		func(c int) bool { return c <= 0 })
+}
+
+func FnStringGe(nargs int) error {
+	return compareStrings("string>=?", nargs, false,
+		func(c int) bool { return c >= 0 })
+}
+
+func FnStringCiEq(nargs int) error {
+	return compareStrings("string-ci=?", nargs, true,
+		func(c int) bool { return c == 0 })
+}
+
+func FnStringCiLt(nargs int) error {
+	return compareStrings("string-ci<?", nargs, true,
+		func(c int) bool { return c < 0 })
+}
+
+func FnStringCiGt(nargs int) error {
+	return compareStrings("string-ci>?", nargs, true,
+		func(c int) bool { return c > 0 })
+}
+
+func FnStringCiLe(nargs int) error {
+	return compareStrings("string-ci<=?", nargs, true,
+		func(c int) bool { return c <= 0 })
+}
+
+func FnStringCiGe(nargs int) error {
+	return compareStrings("string-ci>=?", nargs, true,
+		func(c int) bool { return c >= 0 })
+}
+
+func FnSymbol2String(nargs int) error {
+	if nargs != 1 {
+		return errors.New("symbol->string takes 1 argument")
+	}
+	sym, ok := stack.Pop().(Symbol)
+	if !ok {
+		return errors.New("symbol->string takes a symbol as the argument")
+	}
+	stack.Push(NewString(SymbolNames[sym]))
+	return nil
+}
+
+func FnString2Symbol(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string->symbol takes 1 argument")
+	}
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string->symbol takes a string as the argument")
+	}
+	stack.Push(Str2Sym(str.String()))
+	return nil
+}
+
+func FnList2String(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string->list takes 1 argument")
+	}
+	l, ok := stack.Pop().(*Pair)
+	if !ok {
+		return errors.New("list->string takes a pair as the argument")
+	}
+
+	v, err := list2vec(l)
+	if err != nil {
+		return errors.New("list->string takes a proper list as the argument")
+	}
+
+	if err := reserve(len(v), runeSize); err != nil {
+		return err
+	}
+	rs := make([]rune, len(v))
+	for i := range v {
+		r, ok := v[i].(Char)
+		if !ok {
+			return errors.New("list->string takes a list of chars as the arg")
+		}
+		rs[i] = rune(r)
+	}
+	stack.Push(String{r: &rs})
+	return nil
+}
+
+func FnStringCopy(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("string-copy takes 1 to 3 arguments")
+	}
+
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-copy takes a string as the first argument")
+	}
+	rs := *str.r
+	start, end, err := popRange("string-copy", nargs, 1, len(rs))
+	if err != nil {
+		return err
+	}
+	if err := reserve(end-start, runeSize); err != nil {
+		return err
+	}
+
+	dst := append([]rune{}, rs[start:end]...)
+	stack.Push(String{r: &dst})
+	return nil
+}
+
+func FnStringCopyInto(nargs int) error {
+	if nargs < 3 || nargs > 5 {
+		return errors.New("string-copy! takes 3 to 5 arguments")
+	}
+
+	to, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-copy! takes a string as the first argument")
+	}
+	at, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("string-copy! takes an index as the second argument")
+	}
+	from, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-copy! takes a string as the third argument")
+	}
+	src := *from.r
+	start, end, err := popRange("string-copy!", nargs, 3, len(src))
+	if err != nil {
+		return err
+	}
+	if err := checkMutable("string-copy!", to); err != nil {
+		return err
+	}
+
+	dst := *to.r
+	if at+end-start > len(dst) {
+		return errors.New("string-copy!: index out of range")
+	}
+	copy(dst[at:], src[start:end]) // Which allows the two to overlap
+	stack.Push(to)
+	return nil
+}
+
+func FnStringFill(nargs int) error {
+	if nargs < 2 || nargs > 4 {
+		return errors.New("string-fill! takes 2 to 4 arguments")
+	}
+
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-fill! takes a string as the first argument")
+	}
+	ch, ok := stack.Pop().(Char)
+	if !ok {
+		return errors.New("string-fill! takes a char as the second argument")
+	}
+	rs := *str.r
+	start, end, err := popRange("string-fill!", nargs, 2, len(rs))
+	if err != nil {
+		return err
+	}
+	if err := checkMutable("string-fill!", str); err != nil {
+		return err
+	}
+
+	for i := start; i < end; i++ {
+		rs[i] = rune(ch)
+	}
+	stack.Push(str)
+	return nil
+}
+
+func FnString2Vector(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("string->vector takes 1 to 3 arguments")
+	}
+
+	str, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string->vector takes a string as the first argument")
+	}
+	rs := *str.r
+	start, end, err := popRange("string->vector", nargs, 1, len(rs))
+	if err != nil {
+		return err
+	}
+	if err := reserve(end-start, valueSize); err != nil {
+		return err
+	}
+
+	vec := []Value{}
+	for _, r := range rs[start:end] {
+		vec = append(vec, Char(r))
+	}
+	stack.Push(Vector{&vec})
+	return nil
+}
+
+// stringArgs pops the procedure and strings that string-map and
+// string-for-each take, as runes.
+func stringArgs(name string, nargs int) (Value, [][]rune, int, error) {
+	if nargs < 2 {
+		return nil, nil, 0, fmt.Errorf("%s takes at least 2 arguments", name)
+	}
+
+	proc := stack.Pop()
+	strs := [][]rune{}
+	shortest := -1
+	for i := 1; i < nargs; i++ {
+		str, ok := stack.Pop().(String)
+		if !ok {
+			return nil, nil, 0, fmt.Errorf("%s takes strings", name)
+		}
+		rs := *str.r
+		if shortest == -1 || len(rs) < shortest {
+			shortest = len(rs)
+		}
+		strs = append(strs, rs)
+	}
+	return proc, strs, shortest, nil
+}
+
+func FnStringMap(nargs int) error {
+	proc, strs, n, err := stringArgs("string-map", nargs)
+	if err != nil {
+		return err
+	}
+
+	res := []rune{}
+	for i := 0; i < n; i++ {
+		args := []Value{}
+		for _, rs := range strs {
+			args = append(args, Char(rs[i]))
+		}
+		v, err := Apply(proc, args...)
+		if err != nil {
+			return err
+		}
+		ch, ok := v.(Char)
+		if !ok {
+			return errors.New("string-map: procedure must return a char")
+		}
+		res = append(res, rune(ch))
+	}
+
+	stack.Push(String{r: &res})
+	return nil
+}
+
+func FnStringForEach(nargs int) error {
+	proc, strs, n, err := stringArgs("string-for-each", nargs)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		args := []Value{}
+		for _, rs := range strs {
+			args = append(args, Char(rs[i]))
+		}
+		if _, err := Apply(proc, args...); err != nil {
+			return err
+		}
+	}
+
+	stack.Push(Boolean(true))
+	return nil
+}
@@ -0,0 +1,839 @@
+package scheme
+
+// The string library of SRFI 13 and SRFI 130, along with MIT Scheme's string
+// search procedures.  Strings hold runes, so cursors are just indices: they
+// take constant time to move and can be compared with the numeric procedures.
+// Searches return #f when nothing matches, as in SRFI 13.
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func popString(name string) ([]rune, error) {
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return nil, fmt.Errorf("%s takes a string as the first argument", name)
+	}
+	return *s.r, nil
+}
+
+func pushIndex(i int) {
+	stack.Push(Integer(*big.NewInt(int64(i))))
+}
+
+func pushIndexOrFalse(i int) {
+	if i < 0 {
+		stack.Push(Boolean(false))
+	} else {
+		pushIndex(i)
+	}
+}
+
+// charPred turns a char, or a predicate on chars, into a test on runes.
+func charPred(name string, v Value) (func(rune) (bool, error), error) {
+	switch v := v.(type) {
+	case Char:
+		return func(r rune) (bool, error) { return r == rune(v), nil }, nil
+	case *Procedure:
+		return func(r rune) (bool, error) {
+			res, err := Apply(v, Char(r))
+			return res != Boolean(false), err
+		}, nil
+	}
+	return nil, fmt.Errorf("%s takes a char or a predicate", name)
+}
+
+// predArgs pops the arguments of procedures taking a string, a char or
+// predicate, and the optional start and end.  Without the predicate, the
+// test is def.
+func predArgs(name string, nargs int,
+	def func(rune) (bool, error)) ([]rune, func(rune) (bool, error), int, int, error) {
+	if nargs < 1 || nargs > 4 {
+		return nil, nil, 0, 0, fmt.Errorf("%s takes 1 to 4 arguments", name)
+	}
+
+	rs, err := popString(name)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	pred := def
+	if nargs > 1 {
+		if pred, err = charPred(name, stack.Pop()); err != nil {
+			return nil, nil, 0, 0, err
+		}
+	} else if pred == nil {
+		return nil, nil, 0, 0, fmt.Errorf("%s takes 2 to 4 arguments", name)
+	}
+	start, end, err := popRange(name, nargs, 2, len(rs))
+	return rs, pred, start, end, err
+}
+
+func isSpace(r rune) (bool, error) {
+	return unicode.IsSpace(r), nil
+}
+
+func isNotSpace(r rune) (bool, error) {
+	return !unicode.IsSpace(r), nil
+}
+
+// find returns the first index from start to end, or the last if backwards,
+// of a rune that pred gives want for.
+func find(rs []rune, pred func(rune) (bool, error), start, end int,
+	want, backwards bool) (int, error) {
+	for i := start; i < end; i++ {
+		j := i
+		if backwards {
+			j = start + end - 1 - i
+		}
+		ok, err := pred(rs[j])
+		if err != nil {
+			return -1, err
+		}
+		if ok == want {
+			return j, nil
+		}
+	}
+	return -1, nil
+}
+
+func stringFind(name string, nargs int, want, backwards bool) error {
+	rs, pred, start, end, err := predArgs(name, nargs, nil)
+	if err != nil {
+		return err
+	}
+	i, err := find(rs, pred, start, end, want, backwards)
+	if err != nil {
+		return err
+	}
+	pushIndexOrFalse(i)
+	return nil
+}
+
+func FnStringIndex(nargs int) error {
+	return stringFind("string-index", nargs, true, false)
+}
+
+func FnStringIndexRight(nargs int) error {
+	return stringFind("string-index-right", nargs, true, true)
+}
+
+func FnStringSkip(nargs int) error {
+	return stringFind("string-skip", nargs, false, false)
+}
+
+func FnStringSkipRight(nargs int) error {
+	return stringFind("string-skip-right", nargs, false, true)
+}
+
+func FnStringCount(nargs int) error {
+	rs, pred, start, end, err := predArgs("string-count", nargs, nil)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, r := range rs[start:end] {
+		ok, err := pred(r)
+		if err != nil {
+			return err
+		}
+		if ok {
+			count++
+		}
+	}
+	pushIndex(count)
+	return nil
+}
+
+func FnIsStringNull(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-null? takes 1 argument")
+	}
+	rs, err := popString("string-null?")
+	if err != nil {
+		return err
+	}
+	stack.Push(Boolean(len(rs) == 0))
+	return nil
+}
+
+// twoStrings pops two strings, each followed by its optional range, as in
+// (string-prefix? s1 s2 [start1 end1 start2 end2]).  It returns the ranges
+// of each, and where the first starts.
+func twoStrings(name string, nargs int) ([]rune, []rune, int, error) {
+	if nargs < 2 || nargs > 6 {
+		return nil, nil, 0, fmt.Errorf("%s takes 2 to 6 arguments", name)
+	}
+
+	s1, err := popString(name)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	s2, ok := stack.Pop().(String)
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%s takes a string as the second argument", name)
+	}
+	start1, end1, err := popRange(name, nargs, 2, len(s1))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	start2, end2, err := popRange(name, nargs, 4, len(*s2.r))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return s1[start1:end1], (*s2.r)[start2:end2], start1, nil
+}
+
+// runeIndex converts a byte offset into s, as strings returns, to a rune one.
+func runeIndex(s string, i int) int {
+	if i < 0 {
+		return i
+	}
+	return utf8.RuneCountInString(s[:i])
+}
+
+func FnStringContains(nargs int) error {
+	s1, s2, start, err := twoStrings("string-contains", nargs)
+	if err != nil {
+		return err
+	}
+	s := string(s1)
+	i := runeIndex(s, strings.Index(s, string(s2)))
+	if i >= 0 {
+		i += start
+	}
+	pushIndexOrFalse(i)
+	return nil
+}
+
+func FnStringContainsRight(nargs int) error {
+	s1, s2, start, err := twoStrings("string-contains-right", nargs)
+	if err != nil {
+		return err
+	}
+	s := string(s1)
+	i := runeIndex(s, strings.LastIndex(s, string(s2)))
+	if i >= 0 {
+		i += start
+	}
+	pushIndexOrFalse(i)
+	return nil
+}
+
+func stringAffix(name string, nargs int, suffix, ci bool) error {
+	s1, s2, _, err := twoStrings(name, nargs)
+	if err != nil {
+		return err
+	}
+	affix, s := string(s1), string(s2)
+	if ci {
+		affix = foldString(s1)
+		s = foldString(s2)
+	}
+	if suffix {
+		stack.Push(Boolean(strings.HasSuffix(s, affix)))
+	} else {
+		stack.Push(Boolean(strings.HasPrefix(s, affix)))
+	}
+	return nil
+}
+
+func FnIsStringPrefix(nargs int) error {
+	return stringAffix("string-prefix?", nargs, false, false)
+}
+
+func FnIsStringSuffix(nargs int) error {
+	return stringAffix("string-suffix?", nargs, true, false)
+}
+
+func FnIsStringPrefixCi(nargs int) error {
+	return stringAffix("string-prefix-ci?", nargs, false, true)
+}
+
+func FnIsStringSuffixCi(nargs int) error {
+	return stringAffix("string-suffix-ci?", nargs, true, true)
+}
+
+// searchArgs pops the pattern, the string and the optional index of MIT
+// Scheme's string-search procedures.
+func searchArgs(name string, nargs int, def func([]rune) int) (string, []rune, int, error) {
+	if nargs != 2 && nargs != 3 {
+		return "", nil, 0, fmt.Errorf("%s takes 2 or 3 arguments", name)
+	}
+
+	pattern, err := popString(name)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return "", nil, 0, fmt.Errorf("%s takes a string as the second argument", name)
+	}
+	i := def(*s.r)
+	if nargs == 3 {
+		if i, ok = toIndex(stack.Pop()); !ok || i > len(*s.r) {
+			return "", nil, 0, fmt.Errorf("%s: index out of range", name)
+		}
+	}
+	return string(pattern), *s.r, i, nil
+}
+
+// FnStringSearchForward returns the index in the string of the first match of
+// the pattern after start.
+func FnStringSearchForward(nargs int) error {
+	pattern, rs, start, err := searchArgs("string-search-forward", nargs,
+		func([]rune) int { return 0 })
+	if err != nil {
+		return err
+	}
+	s := string(rs[start:])
+	i := runeIndex(s, strings.Index(s, pattern))
+	if i >= 0 {
+		i += start
+	}
+	pushIndexOrFalse(i)
+	return nil
+}
+
+// FnStringSearchBackward returns the index in the string of the end of the
+// last match of the pattern before end.
+func FnStringSearchBackward(nargs int) error {
+	pattern, rs, end, err := searchArgs("string-search-backward", nargs,
+		func(rs []rune) int { return len(rs) })
+	if err != nil {
+		return err
+	}
+	s := string(rs[:end])
+	i := runeIndex(s, strings.LastIndex(s, pattern))
+	if i >= 0 {
+		i += utf8.RuneCountInString(pattern)
+	}
+	pushIndexOrFalse(i)
+	return nil
+}
+
+func FnStringSearchAll(nargs int) error {
+	pattern, rs, _, err := searchArgs("string-search-all", nargs,
+		func([]rune) int { return 0 })
+	if err != nil {
+		return err
+	}
+	matches := []Value{}
+	s := string(rs)
+	for i, offset := 0, 0; ; {
+		j := strings.Index(s[offset:], pattern)
+		if j < 0 {
+			break
+		}
+		i += runeIndex(s[offset:], j)
+		matches = append(matches, Integer(*big.NewInt(int64(i))))
+		// Step on by one rune, so that overlapping matches are found
+		_, size := utf8.DecodeRuneInString(s[offset+j:])
+		if size == 0 {
+			break
+		}
+		offset += j + size
+		i++
+	}
+	stack.Push(vec2list(matches))
+	return nil
+}
+
+// stringPart pops the string and count of the take and drop procedures.
+func stringPart(name string, nargs int) ([]rune, int, error) {
+	if nargs != 2 {
+		return nil, 0, fmt.Errorf("%s takes 2 arguments", name)
+	}
+	rs, err := popString(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, ok := toIndex(stack.Pop())
+	if !ok || n > len(rs) {
+		return nil, 0, fmt.Errorf("%s: count out of range", name)
+	}
+	return rs, n, nil
+}
+
+func pushRunes(rs []rune) {
+	cp := append([]rune{}, rs...)
+	stack.Push(String{r: &cp})
+}
+
+func FnStringTake(nargs int) error {
+	rs, n, err := stringPart("string-take", nargs)
+	if err != nil {
+		return err
+	}
+	pushRunes(rs[:n])
+	return nil
+}
+
+func FnStringDrop(nargs int) error {
+	rs, n, err := stringPart("string-drop", nargs)
+	if err != nil {
+		return err
+	}
+	pushRunes(rs[n:])
+	return nil
+}
+
+func FnStringTakeRight(nargs int) error {
+	rs, n, err := stringPart("string-take-right", nargs)
+	if err != nil {
+		return err
+	}
+	pushRunes(rs[len(rs)-n:])
+	return nil
+}
+
+func FnStringDropRight(nargs int) error {
+	rs, n, err := stringPart("string-drop-right", nargs)
+	if err != nil {
+		return err
+	}
+	pushRunes(rs[:len(rs)-n])
+	return nil
+}
+
+// stringPad takes the string, the length, and optionally the char to pad with
+// and the range of the string.  Longer strings lose chars from the side
+// they'd be padded on.
+func stringPad(name string, nargs int, right bool) error {
+	if nargs < 2 || nargs > 5 {
+		return fmt.Errorf("%s takes 2 to 5 arguments", name)
+	}
+
+	rs, err := popString(name)
+	if err != nil {
+		return err
+	}
+	n, ok := toIndex(stack.Pop())
+	if !ok {
+		return fmt.Errorf("%s takes a length as the second argument", name)
+	}
+	pad := ' '
+	if nargs > 2 {
+		ch, ok := stack.Pop().(Char)
+		if !ok {
+			return fmt.Errorf("%s takes a char to pad with", name)
+		}
+		pad = rune(ch)
+	}
+	start, end, err := popRange(name, nargs, 3, len(rs))
+	if err != nil {
+		return err
+	}
+
+	rs = rs[start:end]
+	if err := reserve(n, runeSize); err != nil {
+		return err
+	}
+	res := make([]rune, n)
+	for i := range res {
+		if i%limitInterval == 0 {
+			if err := pollLimits(); err != nil {
+				return err
+			}
+		}
+		res[i] = pad
+	}
+	if right {
+		copy(res, rs)
+	} else {
+		if len(rs) > n {
+			rs = rs[len(rs)-n:]
+		}
+		copy(res[n-len(rs):], rs)
+	}
+	stack.Push(String{r: &res})
+	return nil
+}
+
+func FnStringPad(nargs int) error {
+	return stringPad("string-pad", nargs, false)
+}
+
+func FnStringPadRight(nargs int) error {
+	return stringPad("string-pad-right", nargs, true)
+}
+
+// stringTrim drops the chars at either end that satisfy the predicate, or
+// whitespace by default.
+func stringTrim(name string, nargs int, left, right bool) error {
+	rs, pred, start, end, err := predArgs(name, nargs, isSpace)
+	if err != nil {
+		return err
+	}
+	if left {
+		if start, err = find(rs, pred, start, end, false, false); err != nil {
+			return err
+		} else if start < 0 {
+			start = end
+		}
+	}
+	if right && start < end {
+		last, err := find(rs, pred, start, end, false, true)
+		if err != nil {
+			return err
+		}
+		end = last + 1
+	}
+	pushRunes(rs[start:end])
+	return nil
+}
+
+func FnStringTrim(nargs int) error {
+	return stringTrim("string-trim", nargs, true, false)
+}
+
+func FnStringTrimRight(nargs int) error {
+	return stringTrim("string-trim-right", nargs, false, true)
+}
+
+func FnStringTrimBoth(nargs int) error {
+	return stringTrim("string-trim-both", nargs, true, true)
+}
+
+// popGrammar pops the optional grammar of string-join and string-split, which
+// says where the delimiters go: between the strings (infix, the default, or
+// strict-infix, which refuses no strings), or before or after each.
+func popGrammar(name string, nargs, k int) (string, error) {
+	if nargs <= k {
+		return "infix", nil
+	}
+	sym, ok := stack.Pop().(Symbol)
+	if ok {
+		switch grammar := SymbolNames[sym]; grammar {
+		case "infix", "strict-infix", "prefix", "suffix":
+			return grammar, nil
+		}
+	}
+	return "", fmt.Errorf("%s: grammar must be infix, strict-infix, prefix or suffix", name)
+}
+
+// FnStringJoin takes a list of strings, and optionally the delimiter, which is
+// a space by default, and the grammar.
+func FnStringJoin(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("string-join takes 1 to 3 arguments")
+	}
+
+	list, ok := stack.Pop().(*Pair)
+	if !ok {
+		return errors.New("string-join takes a list of strings")
+	}
+	items, err := list2vec(list)
+	if err != nil {
+		return err
+	}
+	delim := " "
+	if nargs > 1 {
+		s, ok := stack.Pop().(String)
+		if !ok {
+			return errors.New("string-join takes a string as the delimiter")
+		}
+		delim = s.String()
+	}
+	grammar, err := popGrammar("string-join", nargs, 2)
+	if err != nil {
+		return err
+	}
+
+	strs := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(String)
+		if !ok {
+			return errors.New("string-join takes a list of strings")
+		}
+		strs[i] = s.String()
+	}
+	res := strings.Join(strs, delim)
+	switch {
+	case len(strs) == 0 && grammar == "strict-infix":
+		return errors.New("string-join: strict-infix needs at least one string")
+	case len(strs) == 0:
+	case grammar == "prefix":
+		res = delim + res
+	case grammar == "suffix":
+		res += delim
+	}
+	stack.Push(NewString(res))
+	return nil
+}
+
+// FnStringSplit takes the string, the delimiter, which is a string or a char,
+// and optionally the grammar, the most splits to make, and the range.  The
+// prefix and suffix grammars drop the empty string before the first or after
+// the last delimiter.
+func FnStringSplit(nargs int) error {
+	if nargs < 2 || nargs > 6 {
+		return errors.New("string-split takes 2 to 6 arguments")
+	}
+
+	rs, err := popString("string-split")
+	if err != nil {
+		return err
+	}
+	var delim string
+	switch v := stack.Pop().(type) {
+	case String:
+		delim = v.String()
+	case Char:
+		delim = string(v)
+	default:
+		return errors.New("string-split takes a string or char as the delimiter")
+	}
+	grammar, err := popGrammar("string-split", nargs, 2)
+	if err != nil {
+		return err
+	}
+	limit := -1
+	if nargs > 3 {
+		switch v := stack.Pop().(type) {
+		case Boolean:
+			if v {
+				return errors.New("string-split takes #f or a count as the limit")
+			}
+		default:
+			n, ok := toIndex(v)
+			if !ok {
+				return errors.New("string-split takes #f or a count as the limit")
+			}
+			limit = n + 1
+		}
+	}
+	start, end, err := popRange("string-split", nargs, 4, len(rs))
+	if err != nil {
+		return err
+	}
+
+	s := string(rs[start:end])
+	if s == "" {
+		if grammar == "strict-infix" {
+			return errors.New("string-split: strict-infix can't split an empty string")
+		}
+		stack.Push(Empty)
+		return nil
+	}
+	parts := strings.SplitN(s, delim, limit)
+	if grammar == "prefix" && len(parts) > 1 && parts[0] == "" {
+		parts = parts[1:]
+	}
+	if grammar == "suffix" && len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	res := make([]Value, len(parts))
+	for i, part := range parts {
+		res[i] = NewString(part)
+	}
+	stack.Push(vec2list(res))
+	return nil
+}
+
+// FnStringTokenize returns the runs of chars that satisfy the predicate, which
+// by default accepts everything but whitespace.
+func FnStringTokenize(nargs int) error {
+	rs, pred, start, end, err := predArgs("string-tokenize", nargs, isNotSpace)
+	if err != nil {
+		return err
+	}
+
+	tokens := []Value{}
+	for i := start; i < end; {
+		first, err := find(rs, pred, i, end, true, false)
+		if err != nil {
+			return err
+		} else if first < 0 {
+			break
+		}
+		last, err := find(rs, pred, first, end, false, false)
+		if err != nil {
+			return err
+		} else if last < 0 {
+			last = end
+		}
+		token := append([]rune{}, rs[first:last]...)
+		tokens = append(tokens, String{r: &token})
+		i = last
+	}
+	stack.Push(vec2list(tokens))
+	return nil
+}
+
+// FnStringReplace returns the first string with the part between start1 and
+// end1 replaced by the second, or the part of it between start2 and end2.
+func FnStringReplace(nargs int) error {
+	if nargs < 4 || nargs > 6 {
+		return errors.New("string-replace takes 4 to 6 arguments")
+	}
+
+	s1, err := popString("string-replace")
+	if err != nil {
+		return err
+	}
+	s2, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-replace takes a string as the second argument")
+	}
+	start1, end1, err := popRange("string-replace", 4, 2, len(s1))
+	if err != nil {
+		return err
+	}
+	start2, end2, err := popRange("string-replace", nargs, 4, len(*s2.r))
+	if err != nil {
+		return err
+	}
+
+	res := make([]rune, 0, len(s1)-(end1-start1)+(end2-start2))
+	res = append(res, s1[:start1]...)
+	res = append(res, (*s2.r)[start2:end2]...)
+	res = append(res, s1[end1:]...)
+	stack.Push(String{r: &res})
+	return nil
+}
+
+func FnStringReverse(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("string-reverse takes 1 to 3 arguments")
+	}
+
+	rs, err := popString("string-reverse")
+	if err != nil {
+		return err
+	}
+	start, end, err := popRange("string-reverse", nargs, 1, len(rs))
+	if err != nil {
+		return err
+	}
+	res := make([]rune, end-start)
+	for i := range res {
+		res[i] = rs[end-1-i]
+	}
+	stack.Push(String{r: &res})
+	return nil
+}
+
+func FnStringCursorStart(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-cursor-start takes 1 argument")
+	}
+	if _, err := popString("string-cursor-start"); err != nil {
+		return err
+	}
+	pushIndex(0)
+	return nil
+}
+
+func FnStringCursorEnd(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-cursor-end takes 1 argument")
+	}
+	rs, err := popString("string-cursor-end")
+	if err != nil {
+		return err
+	}
+	pushIndex(len(rs))
+	return nil
+}
+
+// cursorArgs pops the string and cursor, and count if n is, that most of the
+// cursor procedures take.
+func cursorArgs(name string, nargs int, n bool) ([]rune, int, int, error) {
+	want := 2
+	if n {
+		want = 3
+	}
+	if nargs != want {
+		return nil, 0, 0, fmt.Errorf("%s takes %d arguments", name, want)
+	}
+
+	rs, err := popString(name)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	cursor, ok := toIndex(stack.Pop())
+	if !ok || cursor > len(rs) {
+		return nil, 0, 0, fmt.Errorf("%s: cursor out of range", name)
+	}
+	count := 1
+	if n {
+		if count, ok = toIndex(stack.Pop()); !ok {
+			return nil, 0, 0, fmt.Errorf("%s takes a count as the third argument", name)
+		}
+	}
+	return rs, cursor, count, nil
+}
+
+func moveCursor(name string, nargs int, n bool, dir int) error {
+	rs, cursor, count, err := cursorArgs(name, nargs, n)
+	if err != nil {
+		return err
+	}
+	cursor += dir * count
+	if cursor < 0 || cursor > len(rs) {
+		return fmt.Errorf("%s: cursor out of range", name)
+	}
+	pushIndex(cursor)
+	return nil
+}
+
+func FnStringCursorNext(nargs int) error {
+	return moveCursor("string-cursor-next", nargs, false, 1)
+}
+
+func FnStringCursorPrev(nargs int) error {
+	return moveCursor("string-cursor-prev", nargs, false, -1)
+}
+
+func FnStringCursorForward(nargs int) error {
+	return moveCursor("string-cursor-forward", nargs, true, 1)
+}
+
+func FnStringCursorBack(nargs int) error {
+	return moveCursor("string-cursor-back", nargs, true, -1)
+}
+
+func FnStringCursorRef(nargs int) error {
+	rs, cursor, _, err := cursorArgs("string-cursor-ref", nargs, false)
+	if err != nil {
+		return err
+	}
+	if cursor == len(rs) {
+		return errors.New("string-cursor-ref: cursor is at the end")
+	}
+	stack.Push(Char(rs[cursor]))
+	return nil
+}
+
+// FnStringCursor2Index serves for string-index->cursor too, since each is an
+// index already.
+func FnStringCursor2Index(nargs int) error {
+	_, cursor, _, err := cursorArgs("string-cursor->index", nargs, false)
+	if err != nil {
+		return err
+	}
+	pushIndex(cursor)
+	return nil
+}
+
+func FnStringCursorDiff(nargs int) error {
+	if nargs != 3 {
+		return errors.New("string-cursor-diff takes 3 arguments")
+	}
+	rs, err := popString("string-cursor-diff")
+	if err != nil {
+		return err
+	}
+	start, ok1 := toIndex(stack.Pop())
+	end, ok2 := toIndex(stack.Pop())
+	if !ok1 || !ok2 || start > len(rs) || end > len(rs) {
+		return errors.New("string-cursor-diff: cursor out of range")
+	}
+	pushIndex(end - start)
+	return nil
+}
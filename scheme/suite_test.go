@@ -0,0 +1,68 @@
+package scheme
+
+import (
+	"math/big"
+	"os"
+	"testing"
+)
+
+// runSuite runs the tests in tests/name, written with the forms in
+// tests/test.scm, in an environment of their own.  Each failing test is
+// reported rather than stopping at the first.
+func runSuite(t *testing.T, name string) {
+	env := &Procedure{
+		Scope:  Scope{map[Symbol]Value{}, &Top.Scope},
+		Macros: map[Symbol]SyntaxRules{},
+	}
+	for k, v := range Top.Macros {
+		env.Macros[k] = v
+	}
+	for _, file := range []string{"tests/test.scm", "tests/" + name} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := env.Exec(string(src)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	run, ok := env.Scope.m[Str2Sym("*tests-run*")].(Integer)
+	if n := big.Int(run); !ok || n.Sign() == 0 {
+		t.Fatalf("No tests were run")
+	}
+
+	failures, ok := env.Scope.m[Str2Sym("*failures*")].(*Pair)
+	if !ok {
+		t.Fatalf("Expected a list of failures")
+	}
+	for failures != Empty {
+		failure, _ := list2vec((*failures.Car).(*Pair))
+		t.Errorf("%s: expected %s, got %s",
+			ValueString(failure[0], false),
+			ValueString(failure[1], false),
+			ValueString(failure[2], false))
+		failures = (*failures.Cdr).(*Pair)
+	}
+}
+
+// TestR7RS runs the conformance suite for (scheme base).
+func TestR7RS(t *testing.T) {
+	runSuite(t, "r7rs.scm")
+}
+
+func TestHashTables(t *testing.T) {
+	runSuite(t, "hash-tables.scm")
+}
+
+func TestStringLibrary(t *testing.T) {
+	runSuite(t, "strings.scm")
+}
+
+func TestRegexp(t *testing.T) {
+	runSuite(t, "regexp.scm")
+}
+
+func TestJSON(t *testing.T) {
+	runSuite(t, "json.scm")
+}
@@ -0,0 +1,52 @@
+;;; Hash tables, as in SRFI 69 and SRFI 125.
+
+(test '(list big none)
+      (let ((h (make-hash-table)))
+        (hash-table-set! h (list 1 "a") 'list (expt 10 30) 'big)
+        (list (hash-table-ref h (list 1 (string #\a)))
+              (hash-table-ref h (* (expt 10 15) (expt 10 15)))
+              (hash-table-ref/default h 'missing 'none))))
+(test '(big #f)
+      (let ((h (make-hash-table eqv?)))
+        (hash-table-set! h (expt 2 100) 'big (string #\a) 'str)
+        (list (hash-table-ref/default h (expt 2 100) #f)
+              (hash-table-ref/default h (string #\a) #f))))
+(test '(2 11 2)
+      (let ((h (make-hash-table string-ci=?)))
+        (hash-table-set! h "Key" 1)
+        (hash-table-update! h "KEY" (lambda (v) (+ v 1)))
+        (hash-table-update!/default h "other" (lambda (v) (+ v 1)) 10)
+        (list (hash-table-ref h "key") (hash-table-ref h "OTHER")
+              (hash-table-size h))))
+(test '(twenty-one 1 1)
+      (let ((h (make-hash-table (lambda (a b) (= (modulo a 10) (modulo b 10)))
+                                (lambda (a) (modulo a 10)))))
+        (hash-table-set! h 1 'one 21 'twenty-one 2 'two)
+        (hash-table-delete! h 12)
+        (list (hash-table-ref h 11) (hash-table-size h)
+              (hash-table-fold h (lambda (k v acc) (+ k acc)) 0))))
+(test 1 (hash-table-ref (alist->hash-table '((a . 1) (a . 2)) eq?) 'a))
+(test 'failed (hash-table-ref (make-hash-table) 'a (lambda () 'failed)))
+(test-error (hash-table-ref (make-hash-table) 'a))
+
+;;; Keys that are cyclic or very long
+
+(test '(cycle #f #t)
+      (let ((a (list 1 2)) (b (list 1 2)) (c (list 1 3)) (h (make-hash-table)))
+        (set-cdr! (cdr a) a)
+        (set-cdr! (cdr b) b)
+        (set-cdr! (cdr c) c)
+        (hash-table-set! h a 'cycle)
+        (list (hash-table-ref/default h b #f) (hash-table-ref/default h c #f)
+              (= (hash a) (hash b)))))
+(test #t (let ((v (vector 1 #f)))
+           (vector-set! v 1 v)
+           (exact-integer? (hash v))))
+(test '(long #f)
+      (let loop ((i 0) (long '()))
+        (if (< i 20000)
+            (loop (+ i 1) (cons i long))
+            (let ((h (make-hash-table)))
+              (hash-table-set! h long 'long)
+              (list (hash-table-ref/default h (list-copy long) #f)
+                    (hash-table-ref/default h (append long '(x)) #f))))))
@@ -0,0 +1,32 @@
+;;; Reading and writing JSON.
+
+(test (list (cons "a" (vector 1 5/2 -300 12345678901234567890123 #t (json-null)))
+            (list "b" (cons "c" "\xe9;\x1f600;\n"))
+            (list "e"))
+      (json-read-string "{\"a\": [1, 2.50, -3e2, 12345678901234567890123, true, null],
+                          \"b\": {\"c\": \"\\u00e9\\ud83d\\ude00\\n\"}, \"e\": {}}"))
+(test "{\"k\":0.3333333333333333,\"v\":[0.125,null,false,\"\\t\"]}"
+      (json-write-string (list (cons "k" 1/3) (cons 'v (vector 1/8 (json-null) #f "\t")))))
+(test '(1 "{\"a\":{\"b\":2},\"z\":1}")
+      (let ((h (json-read-string "{\"z\": 1, \"a\": {\"b\": 2}}" 'hash-table)))
+        (list (hash-table-ref h "z") (json-write-string h))))
+(test '(1 (("a" . 2)) #(3) #t)
+      (let* ((p (open-input-string "1 {\"a\":2}\n[3]  "))
+             (a (json-read p)) (b (json-read p)) (c (json-read p)))
+        (list a b c (eof-object? (json-read p)))))
+(test '(#t #f #f #f 1000)
+      (let ((v (json-read-string "[null]")))
+        (list (json-null? (vector-ref v 0)) (eq? (vector-ref v 0) 'null)
+              (json-null? 'null) (symbol? (vector-ref v 0))
+              (json-read-string "1e3"))))
+
+;;; Errors
+
+(test-error (json-read-string "[1,]"))
+(test-error (json-read-string "01"))
+(test-error (json-read-string "1 2"))
+(test-error (json-write-string '(1 2)))
+(test-error (let ((v (vector 1))) (vector-set! v 0 v) (json-write-string v)))
+(test-error (json-read-string "1e10000000"))
+(test-error (json-read-string "1e-99999999999999999999"))
+(test-error (json-write-string (vector 'null)))
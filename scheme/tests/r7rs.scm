@@ -0,0 +1,795 @@
+;;; R7RS-small conformance tests for (scheme base), after the layout of
+;;; chibi-scheme's r7rs-tests.scm.  Every number here is exact, so examples
+;;; from the report that use inexact numbers are left out or made exact.
+
+;;; 2.2 Whitespace and comments
+
+#| A block comment #| with one nested inside |# ends here |#
+(test '(1 3) (list 1 #;(hidden 2) 3))
+(test '(1) (list 1 #; #;2 3))
+(test 'sym #| inline |# 'sym)
+
+;;; 2.3 Other notations
+
+(test "hello world" (symbol->string '|hello world|))
+(test "aAb" (symbol->string '|a\x41;b|))
+(test #t (eq? 'abc '|abc|))
+
+;;; 2.4 Datum labels
+
+(test '(a b a b a) (let ((x '#0=(a b . #0#)))
+                     (list (car x) (cadr x) (caddr x) (cadddr x)
+                           (car (cddddr x)))))
+(test #t (let ((x '#0=(a #0#))) (eq? x (cadr x))))
+(test '((x) (x)) '(#1=(x) #1#))
+
+;;; 4.1 Primitive expression types
+
+(test 8 ((lambda (x) (+ x x)) 4))
+(test '(3 4 5 6) ((lambda x x) 3 4 5 6))
+(test '(5 6) ((lambda (x y . z) z) 3 4 5 6))
+(test 'yes (if (> 3 2) 'yes 'no))
+(test 'no (if (> 2 3) 'yes 'no))
+(test 1 (if (> 3 2) (- 3 2) (+ 3 2)))
+(define x 2)
+(test 3 (+ x 1))
+(test 3 (begin (set! x (+ x 1)) x))
+
+;;; 4.2 Derived expression types
+
+(test 'greater (cond ((> 3 2) 'greater) ((< 3 2) 'less)))
+(test 'equal (cond ((> 3 3) 'greater) ((< 3 3) 'less) (else 'equal)))
+(test 2 (cond ((assv 'b '((a 1) (b 2))) => cadr) (else #f)))
+(test 'composite (case (* 2 3) ((2 3 5 7) 'prime) ((1 4 6 8 9) 'composite)))
+(test 'consonant
+      (case (car '(c d))
+        ((a e i o u) 'vowel)
+        ((w y) 'semivowel)
+        (else 'consonant)))
+(test #t (and (= 2 2) (> 2 1)))
+(test #f (and (= 2 2) (< 2 1)))
+(test '(f g) (and 1 2 'c '(f g)))
+(test #t (and))
+(test #t (or (= 2 2) (> 2 1)))
+(test #f (or #f #f #f))
+(test '(b c) (or (memq 'b '(a b c)) (/ 3 0)))
+(test 'ok (let ((r 'none)) (when (= 1 1) (set! r 'ok)) r))
+(test 'none (let ((r 'none)) (when (= 1 2) (set! r 'ok)) r))
+(test 'ok (let ((r 'none)) (unless (= 1 2) (set! r 'ok)) r))
+(test 6 (let ((x 2) (y 3)) (* x y)))
+(test 35 (let ((x 2) (y 3)) (let ((x 7) (z (+ x y))) (* z x))))
+(test 70 (let ((x 2) (y 3)) (let* ((x 7) (z (+ x y))) (* z x))))
+(test #t (letrec ((even? (lambda (n) (if (zero? n) #t (odd? (- n 1)))))
+                  (odd? (lambda (n) (if (zero? n) #f (even? (- n 1))))))
+           (even? 88)))
+(test 5 (letrec* ((p (lambda (x) (+ 1 (q (- x 1)))))
+                  (q (lambda (y) (if (zero? y) 0 (+ 1 (p (- y 1))))))
+                  (x (p 5))
+                  (y x))
+          y))
+(test 35 (let-values (((root rem) (exact-integer-sqrt 32))) (* root rem)))
+(test 70 (let ((a 'a) (b 'b) (x 'x) (y 'y))
+           (let*-values (((a b) (values 1 2)) ((x y) (values a b)))
+             (* 35 (+ x y) (- b a) (/ 2 3)))))
+(test '#(0 1 2 3 4)
+      (do ((vec (make-vector 5)) (i 0 (+ i 1)))
+          ((= i 5) vec)
+        (vector-set! vec i i)))
+(test 25 (let ((x '(1 3 5 7 9)))
+           (do ((x x (cdr x)) (sum 0 (+ sum (car x))))
+               ((null? x) sum))))
+(test '((6 1 3) (-5 -2))
+      (let loop ((numbers '(3 -2 1 6 -5)) (nonneg '()) (neg '()))
+        (cond ((null? numbers) (list nonneg neg))
+              ((>= (car numbers) 0)
+               (loop (cdr numbers) (cons (car numbers) nonneg) neg))
+              ((< (car numbers) 0)
+               (loop (cdr numbers) nonneg (cons (car numbers) neg))))))
+(test 3 (force (delay (+ 1 2))))
+(test '(3 3) (let ((p (delay (+ 1 2)))) (list (force p) (force p))))
+(test 2 (letrec ((p (delay (begin (set! count (+ count 1))
+                                  (if (> count x) count (force p)))))
+                 (x 5)
+                 (count 0))
+          (force p)
+          (- count 4)))
+(test #t (promise? (delay 1)))
+(test #t (promise? (make-promise 1)))
+(test 1 (force (make-promise 1)))
+(test 10 (force (delay-force (delay 10))))
+(test 7 (force 7))
+(define radix (make-parameter 10 (lambda (x) (if (integer? x) x 10))))
+(test 10 (radix))
+(test 2 (parameterize ((radix 2)) (radix)))
+(test 10 (parameterize ((radix 'not-a-number)) (radix)))
+(test 10 (radix))
+(test 42 (guard (condition ((assq 'a condition) => cdr) ((assq 'b condition)))
+           (raise (list (cons 'a 42)))))
+(test '(b . 23) (guard (condition ((assq 'a condition) => cdr)
+                                  ((assq 'b condition)))
+                  (raise (list (cons 'b 23)))))
+(test 'caught (guard (e ((symbol? e) 'caught)) (raise 'oops)))
+(test "msg" (guard (e ((error-object? e) (error-object-message e)))
+              (error "msg" 1 2)))
+(test '(1 2) (guard (e ((error-object? e) (error-object-irritants e)))
+               (error "msg" 1 2)))
+(test 'outer (guard (e (#t 'outer))
+               (guard (e ((string? e) 'inner))
+                 (raise 'not-a-string))))
+(test '(list 3 4) `(list ,(+ 1 2) 4))
+(test '(list a (quote a)) (let ((name 'a)) `(list ,name ',name)))
+(test '(a 3 4 5 6 b) `(a ,(+ 1 2) ,@(map abs '(4 -5 6)) b))
+(test 'yes (cond-expand (r7rs 'yes) (else 'no)))
+(test 'no (cond-expand ((not r7rs) 'yes) (else 'no)))
+
+;;; 4.3 Macros
+
+(define-syntax swap!
+  (syntax-rules ()
+    ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))
+(test '(2 1) (let ((x 1) (y 2)) (swap! x y) (list x y)))
+(test 'now (let-syntax ((given-that (syntax-rules ()
+                                      ((_ test stmt1 stmt2 ...)
+                                       (if test (begin stmt1 stmt2 ...))))))
+             (let ((r #f))
+               (given-that #t (set! r 'now))
+               r)))
+(test 7 (letrec-syntax ((my-or (syntax-rules ()
+                                 ((_) #f)
+                                 ((_ e) e)
+                                 ((_ e r ...) (let ((t e)) (if t t (my-or r ...)))))))
+          (my-or #f 7)))
+
+;;; 5.3 Variable definitions
+
+(define (add3 x) (+ x 3))
+(test 6 (add3 3))
+(test 45 (let ((x 5))
+           (define foo (lambda (y) (bar x y)))
+           (define bar (lambda (a b) (+ (* a b) a)))
+           (foo (+ x 3))))
+(define-values (dv-q dv-r) (floor/ 17 5))
+(test '(3 2) (list dv-q dv-r))
+(define-values (dv-first . dv-rest) (values 1 2 3))
+(test '(1 (2 3)) (list dv-first dv-rest))
+(test 3 (let () (define-values (a b) (values 1 2)) (+ a b)))
+
+;;; 5.5 Record-type definitions
+
+(define-record-type <pare>
+  (kons x y)
+  pare?
+  (x kar set-kar!)
+  (y kdr))
+(test #t (pare? (kons 1 2)))
+(test #f (pare? (cons 1 2)))
+(test #f (vector? (kons 1 2)))
+(test #f (pare? (vector 1 2)))
+(test 1 (kar (kons 1 2)))
+(test 2 (kdr (kons 1 2)))
+(test 3 (let ((k (kons 1 2)))
+          (set-kar! k 3)
+          (kar k)))
+(test-error (kar (cons 1 2)))
+(test 1 (let ((record-accessor 1))
+          (define-record-type q (mk-q a) q? (a q-a))
+          (q-a (mk-q 1))))
+(test "#<pare x: 1 y: (2)>"
+      (let ((out (open-output-string)))
+        (write (kons 1 '(2)) out)
+        (get-output-string out)))
+
+;;; 6.1 Equivalence predicates
+
+(test #t (eqv? 'a 'a))
+(test #f (eqv? 'a 'b))
+(test #t (eqv? 2 2))
+(test #t (eqv? '() '()))
+(test #t (eqv? 100000000000000000000 100000000000000000000))
+(test #f (eqv? (cons 1 2) (cons 1 2)))
+(test #f (eqv? (lambda () 1) (lambda () 2)))
+(test #t (let ((p (lambda (x) x))) (eqv? p p)))
+(test #f (eqv? #f 'nil))
+(test #t (eq? 'a 'a))
+(test #f (eq? (list 'a) (list 'a)))
+(test #t (eq? '() '()))
+(test #t (eq? car car))
+(test #t (let ((x '(a))) (eq? x x)))
+(test #t (equal? 'a 'a))
+(test #t (equal? '(a) '(a)))
+(test #t (equal? '(a (b) c) '(a (b) c)))
+(test #t (equal? "abc" "abc"))
+(test #t (equal? 2 2))
+(test #t (equal? (make-vector 5 'a) (make-vector 5 'a)))
+(test #t (equal? #u8(1 2) (bytevector 1 2)))
+
+(test #t (let ((a (list 1 2)) (b (list 1 2 1 2)))
+           (set-cdr! (cdr a) a)
+           (set-cdr! (cdddr b) b)
+           (equal? a b)))
+(test #f (let ((a (list 1 2)) (b (list 1 3)))
+           (set-cdr! (cdr a) a)
+           (set-cdr! (cdr b) b)
+           (equal? a b)))
+
+;;; 6.2 Numbers
+
+(test #t (complex? 3))
+(test #t (real? 3))
+(test #t (rational? (/ 6 10)))
+(test #t (integer? 3))
+(test #f (integer? (/ 1 2)))
+(test #t (exact? 3))
+(test #f (inexact? 3))
+(test #t (exact-integer? 32))
+(test #f (exact-integer? (/ 1 3)))
+(test #t (= 1 1 1))
+(test #f (= 1 2))
+(test #t (< 1 2 3))
+(test #f (< 1 3 2))
+(test #t (> 3 2 1))
+(test #t (<= 1 1 2))
+(test #f (<= 1 2 1))
+(test #t (>= 2 2 1))
+(test #t (zero? 0))
+(test #f (zero? 1))
+(test #t (positive? 1))
+(test #f (positive? 0))
+(test #t (negative? -1))
+(test #t (odd? 3))
+(test #t (even? 10))
+(test #f (even? -1))
+(test 4 (max 3 4))
+(test 1 (min 1 2 3))
+(test 7 (+ 3 4))
+(test 3 (+ 3))
+(test 0 (+))
+(test 4 (* 4))
+(test 1 (*))
+(test -1 (- 3 4))
+(test -6 (- 3 4 5))
+(test -3 (- 3))
+(test (/ 3 20) (/ 3 4 5))
+(test (/ 1 3) (/ 3))
+(test 7 (abs -7))
+(test 7 (abs 7))
+(test '(2 1) (call-with-values (lambda () (floor/ 5 2)) list))
+(test '(-3 1) (call-with-values (lambda () (floor/ -5 2)) list))
+(test '(-3 -1) (call-with-values (lambda () (floor/ 5 -2)) list))
+(test '(2 1) (call-with-values (lambda () (truncate/ 5 2)) list))
+(test '(-2 -1) (call-with-values (lambda () (truncate/ -5 2)) list))
+(test '(-2 1) (call-with-values (lambda () (truncate/ 5 -2)) list))
+(test -3 (floor-quotient -5 2))
+(test 1 (floor-remainder -5 2))
+(test -2 (truncate-quotient -5 2))
+(test -1 (truncate-remainder -5 2))
+(test 1 (modulo 13 4))
+(test 1 (remainder 13 4))
+(test 3 (modulo -13 4))
+(test -1 (remainder -13 4))
+(test -3 (modulo 13 -4))
+(test 1 (remainder 13 -4))
+(test 4 (gcd 32 -36))
+(test 0 (gcd))
+(test 288 (lcm 32 -36))
+(test 1 (lcm))
+(test 3 (numerator (/ 6 4)))
+(test 2 (denominator (/ 6 4)))
+(test 1 (denominator 5))
+(test -5 (floor (/ -9 2)))
+(test -4 (ceiling (/ -9 2)))
+(test -4 (truncate (/ -9 2)))
+(test -4 (round (/ -9 2)))
+(test 4 (round (/ 7 2)))
+(test 2 (round (/ 5 2)))
+(test 7 (round 7))
+(test (/ 1 3) (rationalize (/ 3 10) (/ 1 10)))
+(test 9 (square 3))
+(test (/ 1 4) (square (/ 1 2)))
+(test 5 (sqrt 25))
+(test '(2 0) (call-with-values (lambda () (exact-integer-sqrt 4)) list))
+(test '(2 1) (call-with-values (lambda () (exact-integer-sqrt 5)) list))
+(test 1024 (expt 2 10))
+(test 1 (expt 0 0))
+(test 3 (exact 3))
+(test 3 (inexact 3))
+(test "100" (number->string 256 16))
+(test "-ff" (number->string -255 16))
+(test "101" (number->string 5 2))
+(test "1/3" (number->string (/ 1 3)))
+(test 100 (string->number "100"))
+(test 256 (string->number "100" 16))
+(test 255 (string->number "#xff"))
+(test 5 (string->number "#b101"))
+(test (/ 1 2) (string->number "1/2"))
+(test (/ 3 2) (string->number "1.5"))
+(test #f (string->number "abc"))
+
+;;; 6.3 Booleans
+
+(test #f (not 3))
+(test #f (not (list 3)))
+(test #t (not #f))
+(test #f (not '()))
+(test #t (boolean? #f))
+(test #f (boolean? 0))
+(test #f (boolean? '()))
+(test #t (boolean=? #t #t))
+(test #t (boolean=? #f #f #f))
+(test #f (boolean=? #t #f))
+
+;;; 6.4 Pairs and lists
+
+(define (f) (list 'not-a-constant-list))
+(define (g) '(constant-list))
+(test #t (pair? '(a . b)))
+(test #t (pair? '(a b c)))
+(test #f (pair? '()))
+(test #f (pair? '#(a b)))
+(test '(a) (cons 'a '()))
+(test '((a) b c d) (cons '(a) '(b c d)))
+(test '("a" b c) (cons "a" '(b c)))
+(test '(a . 3) (cons 'a 3))
+(test 'a (car '(a b c)))
+(test '(a) (car '((a) b c d)))
+(test '(b c d) (cdr '((a) b c d)))
+(test 2 (cdr '(1 . 2)))
+(test 'x (let ((p (f))) (set-car! p 'x) (car p)))
+(test 'y (let ((p (list 1 2))) (set-cdr! p 'y) (cdr p)))
+(test 'b (cadr '(a b c)))
+(test '(c) (cddr '(a b c)))
+(test #t (list? '(a b c)))
+(test #t (list? '()))
+(test #f (list? '(a . b)))
+(test #f (let ((x (list 'a))) (set-cdr! x x) (list? x)))
+(test #t (null? '()))
+(test #f (null? '(a)))
+(test '(3 3) (make-list 2 3))
+(test 2 (length (make-list 2)))
+(test '(a 7 c) (list 'a (+ 3 4) 'c))
+(test '() (list))
+(test 3 (length '(a b c)))
+(test 3 (length '(a (b) (c d e))))
+(test 0 (length '()))
+(test '(x y) (append '(x) '(y)))
+(test '(a b c d) (append '(a) '(b c d)))
+(test '(a (b) (c)) (append '(a (b)) '((c))))
+(test '(a b c . d) (append '(a b) '(c . d)))
+(test 'a (append '() 'a))
+(test '() (append))
+(test '(a) (append '(a)))
+(test '(c b a) (reverse '(a b c)))
+(test '((e (f)) d (b c) a) (reverse '(a (b c) d (e (f)))))
+(test '(c d e) (list-tail '(a b c d e) 2))
+(test 'c (list-ref '(a b c d) 2))
+(test '(one two three) (let ((ls (list 'one 'two 'five!)))
+                         (list-set! ls 2 'three)
+                         ls))
+(test '(a b c) (memq 'a '(a b c)))
+(test '(b c) (memq 'b '(a b c)))
+(test #f (memq 'a '(b c d)))
+(test #f (memq (list 'a) '(b (a) c)))
+(test '((a) c) (member (list 'a) '(b (a) c)))
+(test '("b" "c") (member "B" '("a" "b" "c") string-ci=?))
+(test '(101 102) (memv 101 '(100 101 102)))
+(test '(a 1) (assq 'a '((a 1) (b 2) (c 3))))
+(test '(b 2) (assq 'b '((a 1) (b 2) (c 3))))
+(test #f (assq 'd '((a 1) (b 2) (c 3))))
+(test #f (assq (list 'a) '(((a)) ((b)) ((c)))))
+(test '((a)) (assoc (list 'a) '(((a)) ((b)) ((c)))))
+(test '(2 4) (assoc 2 '((1 1) (2 4) (3 9)) =))
+(test '(5 7) (assv 5 '((2 3) (5 7) (11 13))))
+(test '(1 2 3) (list-copy '(1 2 3)))
+(test #f (let* ((a '(1 2 3)) (b (list-copy a))) (eq? a b)))
+
+;;; 6.5 Symbols
+
+(test #t (symbol? 'foo))
+(test #t (symbol? (car '(a b))))
+(test #f (symbol? "bar"))
+(test #t (symbol? 'nil))
+(test #f (symbol? '()))
+(test #f (symbol? #f))
+(test #t (symbol=? 'a 'a))
+(test #f (symbol=? 'a 'b))
+(test #t (symbol=? 'a 'a 'a))
+(test "flying-fish" (symbol->string 'flying-fish))
+(test "martin" (symbol->string 'martin))
+(test 'mISSISSIppi (string->symbol "mISSISSIppi"))
+(test #f (eq? 'abc 'ABC))
+(test "Hello" (symbol->string 'Hello))
+(test #t (symbol? (string->symbol "a b")))
+
+;;; 6.6 Characters
+
+(test #t (char? #\a))
+(test #f (char? "a"))
+(test #t (char=? #\a #\a #\a))
+(test #f (char=? #\a #\b))
+(test #t (char<? #\a #\b #\c))
+(test #f (char<? #\a #\a))
+(test #t (char>? #\c #\b))
+(test #t (char<=? #\a #\a #\b))
+(test #t (char>=? #\b #\a))
+(test #t (char-ci=? #\a #\A))
+(test #t (char-ci<? #\a #\B))
+(test #t (char-ci>? #\C #\b))
+(test #t (char-alphabetic? #\a))
+(test #f (char-alphabetic? #\1))
+(test #t (char-numeric? #\1))
+(test #t (char-whitespace? #\space))
+(test #f (char-whitespace? #\a))
+(test #t (char-upper-case? #\A))
+(test #t (char-lower-case? #\a))
+(test #t (char-alphabetic? #\λ))
+(test #t (char-numeric? #\x0663))
+(test #t (char-whitespace? #\x3000))
+(test #t (char-upper-case? #\Ä))
+(test #f (char-lower-case? #\Ä))
+(test 3 (digit-value #\3))
+(test 4 (digit-value #\x0664))
+(test #f (digit-value #\a))
+(test #\A (char-upcase #\a))
+(test #\a (char-downcase #\A))
+(test #\a (char-foldcase #\A))
+(test 97 (char->integer #\a))
+(test #\a (integer->char 97))
+(test '(7 8 127 27 10 0 13 32 9)
+      (map char->integer
+           (list #\alarm #\backspace #\delete #\escape #\newline #\null
+                 #\return #\space #\tab)))
+(test #\A #\x41)
+(test 120 (char->integer #\x))
+
+;;; 6.7 Strings
+
+(test #t (string? "a"))
+(test '(7 8 9 10 13 34 92 124 65)
+      (map char->integer (string->list "\a\b\t\n\r\"\\\|\x41;")))
+(test "ab" "a\
+         b")
+(test #f (string? 'a))
+(test "aaa" (make-string 3 #\a))
+(test "" (string))
+(test "abc" (string #\a #\b #\c))
+(test 3 (string-length "abc"))
+(test 0 (string-length ""))
+(test #\b (string-ref "abc" 1))
+(test "axc" (let ((s (make-string 3 #\a)))
+              (string-set! s 1 #\x)
+              (string-set! s 2 #\c)
+              s))
+(test #t (string=? "" ""))
+(test #t (string=? "abc" "abc" "abc"))
+(test #f (string=? "abc" "abd"))
+(test #t (string<? "abc" "abd"))
+(test #t (string<? "ab" "abc"))
+(test #f (string<? "abc" "abc"))
+(test #t (string>? "abd" "abc"))
+(test #t (string<=? "abc" "abc" "abd"))
+(test #t (string>=? "abd" "abc"))
+(test #t (string-ci=? "ABC" "abc"))
+(test #t (string-ci<? "ABC" "abd"))
+(test #t (string-ci>? "abd" "ABC"))
+(test #t (string-ci<=? "ABC" "abc"))
+(test #t (string-ci>=? "abc" "ABC"))
+(test "ABC" (string-upcase "abc"))
+(test "abc" (string-downcase "ABC"))
+(test "abc" (string-foldcase "AbC"))
+(test "STRASSE" (string-upcase "Straße"))
+(test "οδος σας" (string-downcase "ΟΔΟΣ ΣΑΣ"))
+(test "strasse" (string-foldcase "Straße"))
+(test #t (string-ci=? "STRASSE" "Straße"))
+(test "bc" (substring "abcd" 1 3))
+(test "" (string-append))
+(test "abcdef" (string-append "abc" "def"))
+(test "abc" (string-append "a" "" "bc"))
+(test '(#\a #\b #\c) (string->list "abc"))
+(test '(#\b #\c) (string->list "abc" 1))
+(test '(#\b) (string->list "abc" 1 2))
+(test "abc" (list->string '(#\a #\b #\c)))
+(test "" (list->string '()))
+(test "abc" (string-copy "abc"))
+(test "bc" (string-copy "abc" 1))
+(test "b" (string-copy "abc" 1 2))
+(test "a12de" (let ((s (string-copy "abcde")))
+                (string-copy! s 1 "12345" 0 2)
+                s))
+(test "abcab" (let ((s (string-copy "abcde")))
+                (string-copy! s 3 s 0 2)
+                s))
+(test-error (string-set! "abc" 0 #\x))
+(test-error (string-fill! (car '("abc")) #\x))
+(test-error (string-copy! "abc" 0 "xy"))
+(test "xbc" (let ((s (string-copy "abc"))) (string-set! s 0 #\x) s))
+(test "λx" (let ((s (string #\λ #\y))) (string-set! s 1 #\x) s))
+(test-error (string-set! (car '("abc")) 0 #\x))
+(test-error (string-set! (vector-ref '#("abc") 0) 0 #\x))
+(test #t (let ((data (list (string #\a) (vector 1))))
+           (eq? data (eval (list 'quote data) (scheme-report-environment 5)))))
+(test '("bc" "ac" immutable)
+      (let* ((s (string #\a #\c))
+             (lit (eval s (scheme-report-environment 5))))
+        (string-set! s 0 #\b)
+        (list s lit (guard (e (#t 'immutable)) (string-set! lit 0 #\z)))))
+(test #\λ (string-ref "aλb" 1))
+(test "xxx" (let ((s (make-string 3 #\a))) (string-fill! s #\x) s))
+(test "axx" (let ((s (make-string 3 #\a))) (string-fill! s #\x 1) s))
+(test "ABC" (string-map char-upcase "abc"))
+(test "StUdLyCaPs"
+      (string-map (lambda (c k) ((if (eqv? k #\u) char-upcase char-downcase) c))
+                  "studlycaps xxx"
+                  "ululululul"))
+(test '(#\c #\b #\a)
+      (let ((r '()))
+        (string-for-each (lambda (c) (set! r (cons c r))) "abc")
+        r))
+(test '(#\a #\b) (let ((r '()))
+                   (string-for-each (lambda (a b) (set! r (cons a r)))
+                                    "ba" "xx")
+                   r))
+
+;;; 6.8 Vectors
+
+(test #t (vector? #(1 2)))
+(test #f (vector? '(1 2)))
+(test #(0 0) (make-vector 2 0))
+(test #(a b c) (vector 'a 'b 'c))
+(test #() (vector))
+(test 3 (vector-length #(1 2 3)))
+(test 8 (vector-ref '#(1 1 2 3 5 8 13 21) 5))
+(test-error (vector-ref #(1 2) 2))
+(test #(0 ("Sue" "Sue") "Anna")
+      (let ((vec (vector 0 '(2 2 2 2) "Anna")))
+        (vector-set! vec 1 '("Sue" "Sue"))
+        vec))
+(test '(dah dah didah) (vector->list '#(dah dah didah)))
+(test '(dah didah) (vector->list '#(dah dah didah) 1))
+(test '(dah) (vector->list '#(dah dah didah) 1 2))
+(test #(dididit dah) (list->vector '(dididit dah)))
+(test "123" (vector->string #(#\1 #\2 #\3)))
+(test #(#\A #\B #\C) (string->vector "ABC"))
+(test #(1 8 2 8) (vector-copy #(1 8 2 8)))
+(test #(8 2) (vector-copy #(1 8 2 8) 1 3))
+(test #(1 10 100 4 5)
+      (let ((b (vector 1 2 3 4 5)))
+        (vector-copy! b 1 #(10 100))
+        b))
+(test #(a b c d e f) (vector-append #(a b c) #(d e f)))
+(test #(1 smash smash 4)
+      (let ((a (vector 1 2 3 4)))
+        (vector-fill! a 'smash 1 3)
+        a))
+(test #(b e h) (vector-map cadr '#((a b) (d e) (g h))))
+(test #(5 7 9) (vector-map + #(1 2 3) #(4 5 6 7)))
+(test #(0 1 4 9 16)
+      (let ((v (make-vector 5)))
+        (vector-for-each (lambda (i) (vector-set! v i (* i i)))
+                         #(0 1 2 3 4))
+        v))
+
+;;; 6.9 Bytevectors
+
+(test #t (bytevector? #u8(1 2)))
+(test #f (bytevector? #(1 2)))
+(test #u8(12 12) (make-bytevector 2 12))
+(test #u8(1 3 5 1 3 5) (bytevector 1 3 5 1 3 5))
+(test #u8() (bytevector))
+(test 3 (bytevector-length #u8(1 2 3)))
+(test 8 (bytevector-u8-ref #u8(1 1 2 3 5 8 13 21) 5))
+(test #u8(1 3 3 4)
+      (let ((bv (bytevector 1 2 3 4)))
+        (bytevector-u8-set! bv 1 3)
+        bv))
+(test #u8(3 4) (bytevector-copy #u8(1 2 3 4 5) 2 4))
+(test #u8(1 2 3 4 5)
+      (let ((b (bytevector 1 2 3 4 5)))
+        (bytevector-copy! b 1 #u8(2 3))
+        b))
+(test #u8(0 1 2 3 4 5) (bytevector-append #u8(0 1 2) #u8(3 4 5)))
+(test "A" (utf8->string #u8(65)))
+(test #u8(206 187) (string->utf8 "λ"))
+(test-error (bytevector 256))
+
+;;; 6.10 Control features
+
+(test #t (procedure? car))
+(test #f (procedure? 'car))
+(test #t (procedure? (lambda (x) (* x x))))
+(test #f (procedure? '(lambda (x) (* x x))))
+(test 7 (apply + (list 3 4)))
+(test 15 (apply + 1 2 '(3 4 5)))
+(test '(b e h) (map cadr '((a b) (d e) (g h))))
+(test '(5 7 9) (map + '(1 2 3) '(4 5 6)))
+(test '(5 7) (map + '(1 2) '(4 5 6)))
+(test #(0 1 4 9 16)
+      (let ((v (make-vector 5)))
+        (for-each (lambda (i) (vector-set! v i (* i i))) '(0 1 2 3 4))
+        v))
+(test '(3 2 1) (let ((r '()))
+                 (for-each (lambda (a b) (set! r (cons (+ a b) r)))
+                           '(0 1 2) '(1 1 1 1))
+                 r))
+(test 5 (call-with-values (lambda () (values 4 5)) (lambda (a b) b)))
+(test -1 (call-with-values * -))
+(test '(1) (call-with-values (lambda () 1) list))
+(test '(connect talk1 disconnect)
+      (let ((path '()))
+        (dynamic-wind
+          (lambda () (set! path (cons 'connect path)))
+          (lambda () (set! path (cons 'talk1 path)))
+          (lambda () (set! path (cons 'disconnect path))))
+        (reverse path)))
+(test '(in out caught)
+      (let ((path '()))
+        (guard (e (#t (set! path (cons 'caught path))))
+          (dynamic-wind
+            (lambda () (set! path (cons 'in path)))
+            (lambda () (raise 'oops))
+            (lambda () (set! path (cons 'out path)))))
+        (reverse path)))
+(test '(connect talk1 disconnect connect talk2 disconnect)
+      (let ((path '())
+            (c #f))
+        (let ((add (lambda (s) (set! path (cons s path)))))
+          (dynamic-wind
+            (lambda () (add 'connect))
+            (lambda () (add (call/cc (lambda (c0) (set! c c0) 'talk1))))
+            (lambda () (add 'disconnect)))
+          (if (< (length path) 4)
+              (c 'talk2)
+              (reverse path)))))
+(test '(before during after)
+      (let ((path '()))
+        (call/cc
+          (lambda (k)
+            (dynamic-wind
+              (lambda () (set! path (cons 'before path)))
+              (lambda () (set! path (cons 'during path)) (k 'out))
+              (lambda () (set! path (cons 'after path))))))
+        (reverse path)))
+(test '(in-1 in-2 out-2 out-1 caught)
+      (let ((path '()))
+        (guard (e (#t (set! path (cons 'caught path))))
+          (dynamic-wind
+            (lambda () (set! path (cons 'in-1 path)))
+            (lambda ()
+              (dynamic-wind
+                (lambda () (set! path (cons 'in-2 path)))
+                (lambda () (raise 'oops))
+                (lambda () (set! path (cons 'out-2 path)))))
+            (lambda () (set! path (cons 'out-1 path)))))
+        (reverse path)))
+(test -3 (call-with-current-continuation
+           (lambda (exit)
+             (for-each (lambda (x) (if (negative? x) (exit x)))
+                       '(54 0 37 -3 245 19))
+             #t)))
+(test 101 (+ 100 (call/cc (lambda (k) (+ 1000 (k 1))))))
+(test 'escaped (call/cc (lambda (k)
+                          (vector-map (lambda (x) (if (= x 2) (k 'escaped) x))
+                                      #(1 2 3)))))
+(test 3 (let ((n 0)
+              (k #f))
+          (call/cc (lambda (c) (set! k c)))
+          (set! n (+ n 1))
+          (if (< n 3) (k #f))
+          n))
+(test 6 (let* ((k #f)
+               (total (+ 1 (call/cc (lambda (c) (set! k c) 1)))))
+          (if (< total 6) (k total) total)))
+(test '(1 2) (call-with-values (lambda () (call/cc (lambda (k) (k 1 2)))) list))
+(test '(handled oops)
+      (call/cc
+        (lambda (k)
+          (with-exception-handler
+            (lambda (e) (k (list 'handled e)))
+            (lambda () (raise 'oops))))))
+(test '(4) (list (exact-integer-sqrt 17)))
+(test "4" (let ((out (open-output-string)))
+            (write (exact-integer-sqrt 17) out)
+            (get-output-string out)))
+
+;;; 6.11 Exceptions
+
+(test 42 (with-exception-handler
+           (lambda (con) 42)
+           (lambda () (+ (raise-continuable 'oops) 0))))
+(test 65 (with-exception-handler
+           (lambda (con) (if (string? con) 23 0))
+           (lambda () (+ (raise-continuable "should be a number") 42))))
+(test 'handled (guard (e (#t 'handled))
+                 (with-exception-handler
+                   (lambda (e) 'ignored)
+                   (lambda () (raise 'boom)))))
+(test #t (error-object? (guard (e (#t e)) (error "x"))))
+(test #f (error-object? 'x))
+(test #t (guard (e ((file-error? e) #t))
+           (open-input-file "/nonexistent/file/for/r7rs-tests")))
+(test #t (guard (e ((read-error? e) #t))
+           (read (open-input-string "(1 2"))))
+(test-error (raise 'x))
+(test-error (car 'x))
+(test-error (error "failed" 'x))
+
+;;; 6.13 Input and output
+
+(test #t (port? (current-input-port)))
+(test #t (input-port? (current-input-port)))
+(test #t (output-port? (current-output-port)))
+(test #t (output-port? (current-error-port)))
+(test #t (textual-port? (current-output-port)))
+(test #t (input-port-open? (open-input-string "x")))
+(test #f (let ((p (open-input-string "x"))) (close-port p) (input-port-open? p)))
+(test #f (let ((p (open-output-string))) (close-output-port p) (output-port-open? p)))
+(test '(a b) (read (open-input-string "(a b)")))
+(test 'c (let ((p (open-input-string "a b c"))) (read p) (read p) (read p)))
+(test #t (eof-object? (read (open-input-string ""))))
+(test #t (eof-object? (eof-object)))
+(test #\a (read-char (open-input-string "abc")))
+(test #\a (peek-char (open-input-string "abc")))
+(test '(#\a #\b) (let ((p (open-input-string "ab")))
+                   (let ((c (read-char p))) (list c (read-char p)))))
+(test #t (eof-object? (read-char (open-input-string ""))))
+(test "line one"
+      (read-line (open-input-string
+                   (string-append "line one" (string #\newline) "line two"))))
+(test "ab" (read-string 2 (open-input-string "abc")))
+(test 1 (read-u8 (open-input-bytevector #u8(1 2))))
+(test 1 (peek-u8 (open-input-bytevector #u8(1 2))))
+(test #u8(1 2) (read-bytevector 2 (open-input-bytevector #u8(1 2 3))))
+(test #t (char-ready? (open-input-string "a")))
+(test #t (u8-ready? (open-input-bytevector #u8(1))))
+(test "hello world"
+      (let ((out (open-output-string)))
+        (write-string "hello" out)
+        (write-char #\space out)
+        (display 'world out)
+        (get-output-string out)))
+(test "\"a\" #\\b (1 2)"
+      (let ((out (open-output-string)))
+        (write "a" out)
+        (write-char #\space out)
+        (write #\b out)
+        (write-char #\space out)
+        (write '(1 2) out)
+        (get-output-string out)))
+(test (string #\a #\newline)
+      (let ((out (open-output-string)))
+        (display "a" out)
+        (newline out)
+        (get-output-string out)))
+(test #u8(1 2 3)
+      (let ((out (open-output-bytevector)))
+        (write-u8 1 out)
+        (write-bytevector #u8(2 3) out)
+        (get-output-bytevector out)))
+(test "x" (call-with-port (open-input-string "x") read-line))
+
+(define (written write-proc obj)
+  (let ((out (open-output-string)))
+    (write-proc obj out)
+    (get-output-string out)))
+(define circular (list 1 2 3))
+(set-cdr! (cddr circular) circular)
+(test "#0=(1 2 3 . #0#)" (written write circular))
+(test "#0=(1 2 3 . #0#)" (written display circular))
+(test "((a) (a))" (let ((a (list 'a))) (written write (list a a))))
+(test "(#0=(a) #0#)" (let ((a (list 'a))) (written write-shared (list a a))))
+(test "((a) (a))" (let ((a (list 'a))) (written write-simple (list a a))))
+(test "#0=#(1 #0#)" (let ((v (vector 1 2)))
+                      (vector-set! v 1 v)
+                      (written write v)))
+
+(test "\"a\\\"b\\nc\"" (written write "a\"b\nc"))
+(test "#\\tab" (written write #\tab))
+(test "|a b|" (written write '|a b|))
+(test "(1/2 -3)" (written write (list (/ 1 2) -3)))
+(test "#<procedure car>" (written write car))
+(let ((objs (list "a\"b\\c\nd\x7;" #\x0 #\space '|| '|1+| '-> (/ -7 2) '#(a "b"))))
+  (test objs (read (open-input-string (written write objs)))))
+
+;;; 6.14 System interface
+
+(test #t (list? (features)))
+(test #t (and (memq 'r7rs (features)) #t))
@@ -0,0 +1,21 @@
+;;; Regular expressions, as strings in Go's syntax or as SREs.
+
+(test '("bob@host" "bob" "host" #f)
+      (regexp-match "(\\w+)@(\\w+)(!)?" "mail bob@host now"))
+(test '((1 . 3) (2 . 3)) (regexp-match-positions "\xe9;(x)" "a\xe9;xb" 1))
+(test '((0 . "2024-03") (year . "2024") (month . "03"))
+      (regexp-search '(: (-> year (= 4 digit)) "-" (-> month (** 1 2 digit)))
+                     "date: 2024-03"))
+(test '(#t #t #f)
+      (list (regexp-matches? '(+ alpha) "abc") (regexp-matches? "a|ab" "ab")
+            (regexp-matches? '(* (~ ("abc"))) "xa")))
+(test '("f0o" "f<oo> b<oo>" "a2b44")
+      (list (regexp-replace "o" "foo" "0") (regexp-replace-all "(o+)" "foo boo" "<$1>")
+            (regexp-replace-all '(+ digit) "a1b22"
+                                (lambda (m) (number->string (* 2 (string->number m)))))))
+(test '("a" "b" "c") (regexp-split '(+ space) "a  b\tc"))
+(test '("ABC5") (regexp-match '(: (w/nocase "abc") (or "x" (/ "09"))) "ABC5"))
+
+;;; SREs that Go's regexp can't do
+
+(test-error (regexp '(look-ahead "a")))
@@ -0,0 +1,43 @@
+;;; The string library, after SRFI 13 and SRFI 130.
+
+(test '(2 3 2 #f)
+      (list (string-index "héllo" #\l) (string-index-right "héllo" #\l)
+            (string-skip "  ab" char-whitespace?) (string-index "abc" #\z)))
+(test '(6 3 4 (0 1 2))
+      (list (string-contains "héllo world" "world")
+            (string-search-forward "b" "abab" 2)
+            (string-search-backward "b" "abab" 4)
+            (string-search-all "aa" "aaaa")))
+(test '(#t #f #t)
+      (list (string-prefix? "he" "hello") (string-suffix? "he" "hello")
+            (string-suffix-ci? "LO" "hello")))
+(test '("a, b, c" "/a/b" ("a" "b" "" "c") ("a" "b") ("a" "b c"))
+      (list (string-join '("a" "b" "c") ", ") (string-join '("a" "b") "/" 'prefix)
+            (string-split "a,b,,c" #\,) (string-split "/a/b" "/" 'prefix)
+            (string-split "a b c" " " 'infix 1)))
+(test '("x  " "  x" "x" "00012" "345" "ab..")
+      (list (string-trim "  x  ") (string-trim-right "  x  ")
+            (string-trim-both "--x--" #\-) (string-pad "12" 5 #\0)
+            (string-pad "12345" 3) (string-pad-right "ab" 4 #\.)))
+(test '(("the" "quick" "brown") "hello there" "baλ")
+      (list (string-tokenize " the  quick brown ")
+            (string-replace "hello world" "there" 6 11) (string-reverse "λab")))
+
+;;; Cursors
+
+(test '(#\h #\é #\y)
+      (let loop ((c (string-cursor-start "héy")) (acc '()))
+        (if (string-cursor=? c (string-cursor-end "héy"))
+            (reverse acc)
+            (loop (string-cursor-next "héy" c)
+                  (cons (string-cursor-ref "héy" c) acc)))))
+
+;;; Normalisation and grapheme clusters
+
+(test '(2 1 "fi")
+      (list (string-length (string-normalize-nfd "\xe9;"))
+            (string-length (string-normalize-nfc "e\x301;"))
+            (string-normalize-nfkc "\xfb01;")))
+(test '(4 ("\x1100;\x1161;\x11a8;" "a" "\r\n"))
+      (list (string-grapheme-count "e\x301;x\x1f1eb;\x1f1f7;\x1f44d;\x1f3fd;")
+            (string-graphemes "\x1100;\x1161;\x11a8;a\r\n")))
@@ -0,0 +1,21 @@
+;;; The test forms every suite in this directory is written with, loaded
+;;; before each one.
+;;;
+;;; Failures are collected in *failures* as (expression expected actual), and
+;;; suite_test.go reports each one.
+
+(define *tests-run* 0)
+(define *failures* '())
+
+(define-syntax test
+  (syntax-rules ()
+    ((test expected expr)
+     (let ((res (guard (e (#t (list 'raised e))) expr)))
+       (set! *tests-run* (+ *tests-run* 1))
+       (if (not (equal? res expected))
+           (set! *failures* (cons (list 'expr expected res) *failures*)))))))
+
+(define-syntax test-error
+  (syntax-rules ()
+    ((test-error expr)
+     (test 'raised (guard (e (#t 'raised)) expr 'returned)))))
@@ -0,0 +1,619 @@
+package scheme
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type Value interface {
+	isValue()
+}
+
+type Boolean bool
+
+func (Boolean) isValue() {}
+
+type Symbol uint
+
+func (Symbol) isValue() {}
+
+// Builtin symbols are listed in builtins.go
+
+type Char rune
+
+func (Char) isValue() {}
+
+type Vector struct {
+	v *[]Value
+}
+
+func (Vector) isValue() {}
+
+type Scope struct {
+	m     map[Symbol]Value
+	super *Scope
+}
+
+func (*Scope) isValue() {}
+
+type Procedure struct {
+	Scope   Scope
+	Args    Value
+	Ins     []Ins
+	Builtin func(int) error
+	CallCC  func(*Procedure, int) error // Takes the continuation too
+	Macros  map[Symbol]SyntaxRules
+	Name    string // What it was defined as, if anything, for write
+
+	Cont *Continuation
+}
+
+func (Procedure) isValue() {}
+
+type Pair struct {
+	Car *Value
+	Cdr *Value
+}
+
+func (*Pair) isValue() {}
+
+var Empty Value = &Pair{nil, nil}
+
+type Rational big.Rat
+
+func (Rational) isValue() {}
+
+type Integer big.Int
+
+func (Integer) isValue() {}
+
+// String holds runes rather than UTF-8, so that string-ref and string-set!
+// take constant time.  Literals are immutable, which is a property of the
+// value rather than the runes, so that a literal can share them with the data
+// it was read as.
+type String struct {
+	r         *[]rune
+	immutable bool
+}
+
+func (String) isValue() {}
+
+// NewString returns a new mutable string holding s.
+func NewString(s string) String {
+	rs := []rune(s)
+	return String{r: &rs}
+}
+
+// String converts the string to Go's representation.
+func (s String) String() string {
+	return string(*s.r)
+}
+
+// PortState is shared by every copy of a port value, so that closing one
+// copy closes them all.
+type PortState struct {
+	Closer io.Closer // The underlying file, or nil for in-memory ports
+	Name   string
+	Closed bool
+
+	// Set once read has seen a #!fold-case or #!no-fold-case directive
+	FoldCase *bool
+}
+
+func (s *PortState) Close() error {
+	if s.Closed {
+		return nil
+	}
+	s.Closed = true
+	if s.Closer != nil {
+		return s.Closer.Close()
+	}
+	return nil
+}
+
+type InputPort struct {
+	*bufio.Reader
+	*PortState
+}
+
+func (InputPort) isValue() {}
+
+func NewInputPort(r io.Reader, c io.Closer, name string) InputPort {
+	return InputPort{bufio.NewReader(r), &PortState{Closer: c, Name: name}}
+}
+
+type OutputPort struct {
+	io.Writer
+	*PortState
+}
+
+func (OutputPort) isValue() {}
+
+func NewOutputPort(w io.Writer, c io.Closer, name string) OutputPort {
+	return OutputPort{w, &PortState{Closer: c, Name: name}}
+}
+
+type Bytevector struct {
+	b *[]byte
+}
+
+func (Bytevector) isValue() {}
+
+type ErrorKind uint8
+
+const (
+	GeneralError ErrorKind = iota
+	FileError
+	ReadError
+)
+
+// ErrorObject is what error raises, and what errors from builtins become
+// when a handler or guard sees them.
+type ErrorObject struct {
+	Message   string
+	Irritants Value
+	Kind      ErrorKind
+}
+
+func (*ErrorObject) isValue() {}
+
+func (e *ErrorObject) Error() string {
+	s := e.Message
+	for cur, ok := e.Irritants.(*Pair); ok && cur != Empty; {
+		s += " " + ValueString(*cur.Car, false)
+		cur, ok = (*cur.Cdr).(*Pair)
+	}
+	return s
+}
+
+// HashTable maps keys to values through a Go map.  Each key is stored under a
+// Go value that is equal for keys the table's equivalence says are the same,
+// so only tables with a procedure of their own need to call it.
+type HashTable struct {
+	Equiv   Value // The equivalence procedure
+	Hash    Value // The hash procedure
+	kind    hashKind
+	entries map[interface{}][]*hashEntry
+	size    int
+}
+
+func (*HashTable) isValue() {}
+
+// RecordType describes the records made by one define-record-type.
+type RecordType struct {
+	Name   Symbol
+	Fields []Symbol
+}
+
+func (*RecordType) isValue() {}
+
+// Regexp is a compiled regular expression.  Source is the string or SRE it
+// was compiled from, which it prints as.
+type Regexp struct {
+	*regexp.Regexp
+	Source Value
+	whole  *regexp.Regexp // Anchored at both ends, for regexp-matches?
+}
+
+func (*Regexp) isValue() {}
+
+// GoValue holds a Go value that has no Scheme counterpart, such as a struct
+// or a handle, so that it can pass through Scheme code and back unchanged.
+// Tag names its type in Scheme.
+type GoValue struct {
+	Object interface{}
+	Tag    string
+}
+
+func (*GoValue) isValue() {}
+
+type Record struct {
+	Type   *RecordType
+	Fields []Value
+}
+
+func (*Record) isValue() {}
+
+type Scoped struct {
+	Symbol Symbol
+	Scope  Symbol
+}
+
+func (Scoped) isValue() {}
+
+type Eof struct {}
+func (Eof) isValue() {}
+
+// MultipleValues sits on the stack above the values returned by values, and
+// counts them.
+type MultipleValues int
+func (MultipleValues) isValue() {}
+
+// LabelMode says which structure write marks with datum labels.
+type LabelMode uint8
+
+const (
+	LabelCycles LabelMode = iota // As write and display do
+	LabelShared                  // As write-shared does
+	LabelNone                    // As write-simple does, which loops on cycles
+)
+
+// printer writes one value, keeping track of the datum labels within it.
+type printer struct {
+	port    OutputPort
+	display bool
+	labels  map[interface{}]int // -1 until the label's first use
+	next    int
+}
+
+// labelNode returns the identity of v if it may contain other values, and the
+// values it contains, leaving out the cdr of a pair.
+func labelNode(v Value) (interface{}, []Value) {
+	switch v := v.(type) {
+	case *Pair:
+		if v != Empty {
+			return v, []Value{*v.Car}
+		}
+	case Vector:
+		return v.v, *v.v
+	case *Record:
+		return v, v.Fields
+	}
+	return nil, nil
+}
+
+// scan finds the values in v that need labels.  Those reached again while
+// still within themselves are cycles, and with LabelShared any value reached
+// twice is labelled.
+func (p *printer) scan(v Value, mode LabelMode, seen map[interface{}]bool) {
+	within := []interface{}{} // Seen in this call, including a list's spine
+	defer func() {
+		for _, key := range within {
+			seen[key] = false
+		}
+	}()
+
+	for {
+		key, kids := labelNode(v)
+		if key == nil {
+			return
+		}
+		if inside, ok := seen[key]; ok {
+			if inside || mode == LabelShared {
+				p.labels[key] = -1
+			}
+			return
+		}
+		seen[key] = true
+		within = append(within, key)
+
+		for _, kid := range kids {
+			p.scan(kid, mode, seen)
+		}
+		pair, ok := v.(*Pair)
+		if !ok {
+			return
+		}
+		v = *pair.Cdr
+	}
+}
+
+// label writes the label that v, with identity key, may need.  It returns
+// true if v was written already, so that the label stands in for it.
+func (p *printer) label(key interface{}) bool {
+	n, ok := p.labels[key]
+	if !ok {
+		return false
+	}
+	if n >= 0 {
+		fmt.Fprintf(p.port, "#%d#", n)
+		return true
+	}
+	p.labels[key] = p.next
+	fmt.Fprintf(p.port, "#%d=", p.next)
+	p.next++
+	return false
+}
+
+func WriteValue(v Value, display bool) error {
+	return WriteLabelled(v, display, LabelCycles)
+}
+
+// WriteLabelled writes v like WriteValue, with datum labels for the structure
+// mode picks out.
+func WriteLabelled(v Value, display bool, mode LabelMode) error {
+	p := &printer{
+		port:    OutputPortStack[len(OutputPortStack)-1],
+		display: display,
+		labels:  map[interface{}]int{},
+	}
+	if mode != LabelNone {
+		p.scan(v, mode, map[interface{}]bool{})
+	}
+	p.write(v)
+	return nil
+}
+
+func (p *printer) write(v Value) {
+	port, display := p.port, p.display
+	switch v.(type) {
+	case Boolean:
+		if v.(Boolean) {
+			port.Write([]byte("#t"))
+		} else {
+			port.Write([]byte("#f"))
+		}
+	case Symbol:
+		name := SymbolNames[v.(Symbol)]
+		if !display && symbolNeedsBars(name) {
+			writeEscaped(port, name, '|')
+		} else {
+			fmt.Fprint(port, name)
+		}
+	case String:
+		if !display {
+			writeEscaped(port, v.(String).String(), '"')
+		} else {
+			fmt.Fprint(port, v.(String).String())
+		}
+	case Char:
+		ch := rune(v.(Char))
+		if display {
+			fmt.Fprintf(port, "%c", ch)
+		} else if name, ok := charWriteNames[ch]; ok {
+			fmt.Fprintf(port, "#\\%s", name)
+		} else if !unicode.IsPrint(ch) {
+			fmt.Fprintf(port, "#\\x%x", ch)
+		} else {
+			fmt.Fprintf(port, "#\\%c", ch)
+		}
+	case Vector:
+		if p.label(v.(Vector).v) {
+			return
+		}
+		fmt.Fprint(port, "#(")
+		for i, item := range *v.(Vector).v {
+			if i != 0 {
+				fmt.Fprint(port, " ")
+			}
+			p.write(item)
+		}
+		fmt.Fprint(port, ")")
+	case *Pair:
+		if v != Empty && p.label(v) {
+			return
+		}
+		fmt.Fprint(port, "(")
+
+		cur := v.(*Pair)
+		for cur != Empty {
+			p.write(*cur.Car)
+			next, ok := (*cur.Cdr).(*Pair)
+			if _, labelled := p.labels[next]; ok && !labelled {
+				if next != Empty {
+					fmt.Fprint(port, " ")
+				}
+				cur = next
+			} else {
+				fmt.Fprint(port, " . ")
+				p.write(*cur.Cdr)
+				break
+			}
+		}
+		fmt.Fprint(port, ")")
+
+	case Integer:
+		i := big.Int(v.(Integer))
+		fmt.Fprint(port, i.String())
+
+	case Rational:
+		r := big.Rat(v.(Rational))
+		fmt.Fprint(port, r.String())
+
+	case *Procedure:
+		proc := v.(*Procedure)
+		if proc.Cont != nil {
+			fmt.Fprint(port, "#<continuation>")
+		} else if proc.Name != "" {
+			fmt.Fprintf(port, "#<procedure %s>", proc.Name)
+		} else {
+			fmt.Fprint(port, "#<procedure>")
+		}
+
+	case Procedure:
+		fmt.Fprint(port, "#<procedure template>")
+
+	case *Scope:
+		fmt.Fprint(port, "#<environment>")
+
+	case Scoped:
+		p.write(v.(Scoped).Symbol)
+
+	case Eof:
+		fmt.Fprint(port, "#<eof>")
+
+	case JSONNull:
+		fmt.Fprint(port, "#<json-null>")
+
+	case MultipleValues:
+		fmt.Fprintf(port, "#<%d values>", v)
+
+	case Bytevector:
+		fmt.Fprint(port, "#u8(")
+		for i, b := range *v.(Bytevector).b {
+			if i != 0 {
+				fmt.Fprint(port, " ")
+			}
+			fmt.Fprint(port, b)
+		}
+		fmt.Fprint(port, ")")
+
+	case InputPort:
+		fmt.Fprintf(port, "#<input-port %s>", v.(InputPort).Name)
+
+	case OutputPort:
+		fmt.Fprintf(port, "#<output-port %s>", v.(OutputPort).Name)
+
+	case *ErrorObject:
+		e := v.(*ErrorObject)
+		fmt.Fprint(port, "#<error ")
+		p.write(NewString(e.Message))
+		for cur, ok := e.Irritants.(*Pair); ok && cur != Empty; {
+			fmt.Fprint(port, " ")
+			p.write(*cur.Car)
+			cur, ok = (*cur.Cdr).(*Pair)
+		}
+		fmt.Fprint(port, ">")
+
+	case *HashTable:
+		fmt.Fprintf(port, "#<hash-table %d>", v.(*HashTable).Size())
+
+	case *RecordType:
+		fmt.Fprintf(port, "#<record-type %s>", v.(*RecordType).TypeName())
+
+	case *GoValue:
+		fmt.Fprint(port, v.(*GoValue).describe())
+
+	case *Regexp:
+		fmt.Fprint(port, "#<regexp ")
+		p.write(v.(*Regexp).Source)
+		fmt.Fprint(port, ">")
+
+	case *Record:
+		r := v.(*Record)
+		if p.label(r) {
+			return
+		}
+		fmt.Fprintf(port, "#<%s", r.Type.TypeName())
+		for i, field := range r.Type.Fields {
+			fmt.Fprintf(port, " %s: ", SymbolNames[field])
+			p.write(r.Fields[i])
+		}
+		fmt.Fprint(port, ">")
+
+	default:
+		fmt.Fprintf(port, "#<unknown %T>", v)
+	}
+}
+
+// The names write gives characters, which read takes back
+var charWriteNames = map[rune]string{
+	'\a': "alarm",
+	'\b': "backspace",
+	0x7f: "delete",
+	0x1b: "escape",
+	'\n': "newline",
+	0:    "null",
+	'\r': "return",
+	' ':  "space",
+	'\t': "tab",
+}
+
+// writeEscaped writes s as a string or |symbol| between quote characters,
+// escaping whatever read wouldn't take back as it is.
+func writeEscaped(port io.Writer, s string, quote rune) {
+	var sb strings.Builder
+	sb.WriteRune(quote)
+	for _, r := range s {
+		switch r {
+		case quote, '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case '\a':
+			sb.WriteString("\\a")
+		case '\b':
+			sb.WriteString("\\b")
+		case '\t':
+			sb.WriteString("\\t")
+		case '\n':
+			sb.WriteString("\\n")
+		case '\r':
+			sb.WriteString("\\r")
+		default:
+			if unicode.IsPrint(r) {
+				sb.WriteRune(r)
+			} else {
+				fmt.Fprintf(&sb, "\\x%x;", r)
+			}
+		}
+	}
+	sb.WriteRune(quote)
+	io.WriteString(port, sb.String())
+}
+
+// symbolNeedsBars reports whether a symbol called name has to be written as
+// |name| for read to give the same symbol back.
+func symbolNeedsBars(name string) bool {
+	rs := []rune(name)
+	if len(rs) == 0 || name == "." {
+		return true
+	}
+	switch {
+	case rs[0] == '#' || rs[0] == '\'' || rs[0] == '`' || rs[0] == ',':
+		return true
+	case unicode.IsDigit(rs[0]):
+		return true
+	case rs[0] == '-' && len(rs) > 1 && (unicode.IsDigit(rs[1]) || rs[1] == '.'):
+		return true
+	}
+	for _, r := range rs {
+		if delim[r] || r == '|' || r == '"' || r == '(' ||
+			unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		if FoldCase && foldRune(r) != r {
+			return true
+		}
+	}
+	return false
+}
+
+func PrintValue(v Value) error {
+	return WriteValue(v, false)
+}
+
+// ValueString returns v as write, or display if display is set, would print
+// it.
+func ValueString(v Value, display bool) string {
+	var sb strings.Builder
+	OutputPortStack = append(OutputPortStack, NewOutputPort(&sb, nil, "string"))
+	WriteValue(v, display)
+	OutputPortStack = OutputPortStack[:len(OutputPortStack)-1]
+	return sb.String()
+}
+
+// The interned symbols by name.  Uninterned symbols have a name in
+// SymbolNames, but no entry here.
+var symbolTable = map[string]Symbol{}
+
+func init() {
+	for i, name := range SymbolNames {
+		symbolTable[name] = Symbol(i)
+	}
+}
+
+func Str2Sym(str string) Symbol {
+	if sym, ok := symbolTable[str]; ok {
+		return sym
+	}
+
+	sym := Uninterned(str)
+	symbolTable[str] = sym
+	return sym
+}
+
+// Uninterned returns a new symbol named str, which is distinct from every
+// other symbol, including any read with the same name.
+func Uninterned(str string) Symbol {
+	SymbolNames = append(SymbolNames, str)
+	return Symbol(len(SymbolNames) - 1)
+}
+
+func IsInterned(sym Symbol) bool {
+	return symbolTable[SymbolNames[sym]] == sym
+}
@@ -0,0 +1,254 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// upcaseSpecial has the chars whose upper case is more than one char, from
+// the unconditional mappings of Unicode's SpecialCasing.txt.
+var upcaseSpecial = map[rune]string{
+	'ß': "SS",
+	'ŉ': "ʼN",
+	'ǰ': "J\u030c",
+	'ΐ': "\u0399\u0308\u0301",
+	'ΰ': "\u03a5\u0308\u0301",
+	'և': "ԵՒ",
+	'ẖ': "H\u0331",
+	'ẗ': "T\u0308",
+	'ẘ': "W\u030a",
+	'ẙ': "Y\u030a",
+	'ẚ': "Aʾ",
+	'ᾳ': "ΑΙ",
+	'ῃ': "ΗΙ",
+	'ῳ': "ΩΙ",
+	'ﬀ': "FF",
+	'ﬁ': "FI",
+	'ﬂ': "FL",
+	'ﬃ': "FFI",
+	'ﬄ': "FFL",
+	'ﬅ': "ST",
+	'ﬆ': "ST",
+	'ﬓ': "ՄՆ",
+	'ﬔ': "ՄԵ",
+	'ﬕ': "ՄԻ",
+	'ﬖ': "ՎՆ",
+	'ﬗ': "ՄԽ",
+}
+
+// foldSpecial has the chars whose full case folding isn't the folding of
+// their upper case.
+var foldSpecial = map[rune]string{
+	'ẞ': "ss",
+	'İ': "i\u0307",
+}
+
+func upcaseString(rs []rune) string {
+	var sb strings.Builder
+	for _, r := range rs {
+		if s, ok := upcaseSpecial[r]; ok {
+			sb.WriteString(s)
+		} else {
+			sb.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return sb.String()
+}
+
+// isCaseIgnorable approximates the property of that name, which chars like
+// apostrophes and accents have that don't end a word.
+func isCaseIgnorable(r rune) bool {
+	return r == '\'' || r == '.' || r == ':' || r == '’' ||
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Lm, unicode.Sk)
+}
+
+func isCased(r rune) bool {
+	return unicode.IsUpper(r) || unicode.IsLower(r) || unicode.IsTitle(r)
+}
+
+// isFinalSigma says whether the capital sigma at rs[i] ends a word, in which
+// case it becomes ς rather than σ.
+func isFinalSigma(rs []rune, i int) bool {
+	j := i - 1
+	for j >= 0 && isCaseIgnorable(rs[j]) {
+		j--
+	}
+	if j < 0 || !isCased(rs[j]) {
+		return false
+	}
+	j = i + 1
+	for j < len(rs) && isCaseIgnorable(rs[j]) {
+		j++
+	}
+	return j == len(rs) || !isCased(rs[j])
+}
+
+func downcaseString(rs []rune) string {
+	var sb strings.Builder
+	for i, r := range rs {
+		switch {
+		case r == 'İ':
+			sb.WriteString("i\u0307")
+		case r == 'Σ' && isFinalSigma(rs, i):
+			sb.WriteRune('ς')
+		default:
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// foldString returns the full case folding of rs, which string-foldcase and
+// the -ci procedures on strings use.
+func foldString(rs []rune) string {
+	var sb strings.Builder
+	for _, r := range rs {
+		if s, ok := foldSpecial[r]; ok {
+			sb.WriteString(s)
+		} else if s, ok := upcaseSpecial[r]; ok {
+			sb.WriteString(strings.Map(foldRune, s))
+		} else {
+			sb.WriteRune(foldRune(r))
+		}
+	}
+	return sb.String()
+}
+
+func normalize(name string, nargs int, form norm.Form) error {
+	if nargs != 1 {
+		return fmt.Errorf("%s takes 1 argument", name)
+	}
+
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return fmt.Errorf("%s takes a string as the argument", name)
+	}
+	stack.Push(NewString(form.String(s.String())))
+	return nil
+}
+
+func FnStringNormalizeNfc(nargs int) error {
+	return normalize("string-normalize-nfc", nargs, norm.NFC)
+}
+
+func FnStringNormalizeNfd(nargs int) error {
+	return normalize("string-normalize-nfd", nargs, norm.NFD)
+}
+
+func FnStringNormalizeNfkc(nargs int) error {
+	return normalize("string-normalize-nfkc", nargs, norm.NFKC)
+}
+
+func FnStringNormalizeNfkd(nargs int) error {
+	return normalize("string-normalize-nfkd", nargs, norm.NFKD)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// hangulKind classifies the Hangul jamo and syllables by how they join: as
+// leading consonants (L), vowels (V), trailing consonants (T), or syllables
+// that end in a vowel (LV) or in a consonant (LVT).
+func hangulKind(r rune) string {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return "L"
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return "V"
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return "T"
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return "LV"
+		}
+		return "LVT"
+	}
+	return ""
+}
+
+// graphemeBreak says whether a user-perceived character ends between rs[i-1]
+// and rs[i].  It follows the main rules for extended grapheme clusters:
+// combining marks and joiners stay with what they follow, as do the parts of
+// Hangul syllables, emoji joined by a ZWJ, and pairs of regional indicators
+// making up a flag.
+func graphemeBreak(rs []rune, i int) bool {
+	prev, r := rs[i-1], rs[i]
+	switch {
+	case prev == '\r' && r == '\n':
+		return false
+	case unicode.IsControl(prev) || unicode.IsControl(r):
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) || r == '\u200d':
+		return false
+	case r >= 0x1F3FB && r <= 0x1F3FF: // Skin tones modify the emoji before
+		return false
+	case prev == '\u200d' && unicode.Is(unicode.So, r):
+		return false
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		n := 0
+		for j := i - 1; j >= 0 && isRegionalIndicator(rs[j]); j-- {
+			n++
+		}
+		return n%2 == 0
+	}
+
+	switch hangulKind(prev) + ">" + hangulKind(r) {
+	case "L>L", "L>V", "L>LV", "L>LVT", "LV>V", "LV>T", "V>V", "V>T",
+		"LVT>T", "T>T":
+		return false
+	}
+	return true
+}
+
+// graphemes returns the indices at which each user-perceived char in rs
+// starts.
+func graphemes(rs []rune) []int {
+	starts := []int{}
+	for i := range rs {
+		if i == 0 || graphemeBreak(rs, i) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+func FnStringGraphemeCount(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-grapheme-count takes 1 argument")
+	}
+
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-grapheme-count takes a string as the argument")
+	}
+	stack.Push(Integer(*big.NewInt(int64(len(graphemes(*s.r))))))
+	return nil
+}
+
+// FnStringGraphemes returns a list of the user-perceived chars of a string,
+// each as a string.
+func FnStringGraphemes(nargs int) error {
+	if nargs != 1 {
+		return errors.New("string-graphemes takes 1 argument")
+	}
+
+	s, ok := stack.Pop().(String)
+	if !ok {
+		return errors.New("string-graphemes takes a string as the argument")
+	}
+	rs := *s.r
+	starts := append(graphemes(rs), len(rs))
+	res := make([]Value, len(starts)-1)
+	for i := range res {
+		g := append([]rune{}, rs[starts[i]:starts[i+1]]...)
+		res[i] = String{r: &g}
+	}
+	stack.Push(vec2list(res))
+	return nil
+}
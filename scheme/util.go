@@ -0,0 +1,193 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+func vec2list(vec []Value) *Pair {
+	if len(vec) == 0 {
+		return Empty.(*Pair)
+	}
+
+	res := &Pair{}
+	cur := res
+
+	for i := range vec {
+		v := vec[i]
+		cur.Car = &v
+
+		var next Value = &Pair{}
+
+		if i != len(vec)-1 {
+			cur.Cdr = &next
+			cur = (*cur.Cdr).(*Pair)
+		}
+	}
+	cdr := Empty
+	cur.Cdr = &cdr
+
+	return res
+}
+
+func list2vec(list *Pair) ([]Value, error) {
+	res := []Value{}
+	for list != Empty {
+		if len(res)%limitInterval == 0 {
+			if err := pollLimits(); err != nil {
+				return nil, err
+			}
+		}
+		var ok bool
+		res = append(res, *list.Car)
+		list, ok = (*list.Cdr).(*Pair)
+		if !ok {
+			return nil, errors.New("Dotted list when regular list expected")
+		}
+	}
+	return res, nil
+}
+
+// Unscope returns v with the scopes stripped from its symbols, copying any
+// pairs in it.  Shared and cyclic structure, as datum labels give, stays so.
+func Unscope(v Value) Value {
+	if p, ok := v.(*Pair); ok && p != Empty {
+		return unscopePair(p, map[*Pair]*Pair{})
+	}
+	return unscope(v, nil)
+}
+
+// hasScopes reports whether Unscope would change any symbol in v, so that data
+// without any can be used as it is rather than copied.
+func hasScopes(v Value) bool {
+	seen := map[*Pair]bool{}
+	var scoped func(Value) bool
+	scoped = func(v Value) bool {
+		for { // Along the cdrs, as unscopePair goes
+			switch p := v.(type) {
+			case Scoped:
+				return true
+			case *Pair:
+				if p == Empty || seen[p] {
+					return false
+				}
+				seen[p] = true
+				if scoped(*p.Car) {
+					return true
+				}
+				v = *p.Cdr
+				continue
+			}
+			return false
+		}
+	}
+	return scoped(v)
+}
+
+func unscope(v Value, copies map[*Pair]*Pair) Value {
+	switch v := v.(type) {
+	case Scoped:
+		return v.Symbol
+	case *Pair:
+		if v != Empty {
+			return unscopePair(v, copies)
+		}
+	}
+	return v
+}
+
+// unscopePair copies the list starting at p, given the copies made so far.
+func unscopePair(p *Pair, copies map[*Pair]*Pair) *Pair {
+	var first, prev *Pair
+	for { // Along the cdrs, so that long lists don't recurse deeply
+		cp, ok := copies[p]
+		if !ok {
+			var car, cdr Value
+			cp = &Pair{&car, &cdr}
+			copies[p] = cp
+		}
+		if first == nil {
+			first = cp
+		} else {
+			*prev.Cdr = cp
+		}
+		if ok { // Shared or cyclic, so already copied
+			return first
+		}
+
+		*cp.Car = unscope(*p.Car, copies)
+		next, isPair := (*p.Cdr).(*Pair)
+		if !isPair || next == Empty {
+			*cp.Cdr = unscope(*p.Cdr, copies)
+			return first
+		}
+		prev, p = cp, next
+	}
+}
+
+// Apply calls proc with args from Go code and returns its result, leaving the
+// stack as it found it.
+func Apply(proc Value, args ...Value) (Value, error) {
+	stack_pos := len(stack)
+	for i := len(args) - 1; i >= 0; i-- {
+		stack.Push(args[i])
+	}
+	stack.Push(proc)
+
+	call := Procedure{
+		Scope: Top.Scope,
+		Ins:   []Ins{{Call, nil, len(args)}},
+	}
+	err := call.Eval()
+
+	var res Value = Boolean(false)
+	if len(stack) > stack_pos {
+		res = stack.Top()
+		stack = stack[:stack_pos]
+	}
+	return res, err
+}
+
+// toIndex converts a non-negative exact integer to an int.
+func toIndex(v Value) (int, bool) {
+	i, ok := v.(Integer)
+	if !ok {
+		return 0, false
+	}
+	bi := big.Int(i)
+	if bi.Sign() < 0 || !bi.IsInt64() {
+		return 0, false
+	}
+	return int(bi.Int64()), true
+}
+
+func toByte(v Value) (byte, bool) {
+	i, ok := toIndex(v)
+	if !ok || i > 255 {
+		return 0, false
+	}
+	return byte(i), true
+}
+
+// popRange pops the optional start and end arguments that follow the first k
+// arguments of a sequence procedure, defaulting to the whole sequence.
+func popRange(name string, nargs, k, length int) (int, int, error) {
+	start, end := 0, length
+	if nargs > k {
+		var ok bool
+		if start, ok = toIndex(stack.Pop()); !ok {
+			return 0, 0, fmt.Errorf("%s: start must be an index", name)
+		}
+	}
+	if nargs > k+1 {
+		var ok bool
+		if end, ok = toIndex(stack.Pop()); !ok {
+			return 0, 0, fmt.Errorf("%s: end must be an index", name)
+		}
+	}
+	if start > end || end > length {
+		return 0, 0, fmt.Errorf("%s: index out of range", name)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,334 @@
+package scheme
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+func FnIsVector(nargs int) error {
+	if nargs != 1 {
+		return errors.New("Wrong arg count to vector?")
+	}
+
+	_, ok := stack.Pop().(Vector)
+	stack.Push(Boolean(ok))
+	return nil
+}
+
+func FnMakeVector(nargs int) error {
+	if nargs != 1 && nargs != 2 {
+		return errors.New("Wrong arg count to make-vector")
+	}
+
+	n, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("make-vector requires a length for the first arg")
+	}
+
+	fill := Empty
+	if nargs == 2 {
+		fill = stack.Pop()
+	}
+
+	if err := reserve(n, valueSize); err != nil {
+		return err
+	}
+	items := make([]Value, n)
+	for i := range items {
+		if i%limitInterval == 0 {
+			if err := pollLimits(); err != nil {
+				return err
+			}
+		}
+		items[i] = fill
+	}
+	stack.Push(Vector{&items})
+	return nil
+}
+
+func FnVectorLength(nargs int) error {
+	if nargs != 1 {
+		return errors.New("vector-length takes 1 arg")
+	}
+
+	vec, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-length takes a vector as the argument")
+	}
+
+	stack.Push(Integer(*big.NewInt(int64(len(*vec.v)))))
+	return nil
+}
+
+func FnVector(nargs int) error {
+	vec := Vector{&[]Value{}}
+	for i := 0; i < nargs; i++ {
+		*vec.v = append(*vec.v, stack.Pop())
+	}
+	stack.Push(vec)
+	return nil
+}
+
+func FnVectorRef(nargs int) error {
+	if nargs != 2 {
+		return errors.New("vector-ref takes 2 args")
+	}
+
+	vec, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-ref requires a vector as the first argument")
+	}
+
+	idx, ok := stack.Pop().(Integer)
+	if !ok {
+		return errors.New(
+			"vector-ref requires an integer as the second argument")
+	}
+	idx_bi := big.Int(idx)
+	if idx_bi.Sign() < 0 || idx_bi.Cmp(big.NewInt(int64(len(*vec.v)))) >= 0 {
+		return errors.New("vector-ref: index out of range")
+	}
+
+	stack.Push((*vec.v)[idx_bi.Int64()])
+	return nil
+}
+
+func FnVectorSet(nargs int) error {
+	if nargs != 3 {
+		return errors.New("vector-set! takes 3 args")
+	}
+
+	vec, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-set requires a vector as the first argument")
+	}
+
+	idx, ok := stack.Pop().(Integer)
+	if !ok {
+		return errors.New(
+			"vector-set requires an integer as the second argument")
+	}
+	idx_bi := big.Int(idx)
+	if idx_bi.Sign() < 0 || idx_bi.Cmp(big.NewInt(int64(len(*vec.v)))) >= 0 {
+		return errors.New("vector-set!: index out of range")
+	}
+
+	(*vec.v)[idx_bi.Int64()] = stack.Pop()
+	stack.Push(vec)
+	return nil
+}
+
+func FnList2Vector(nargs int) error {
+	if nargs != 1 {
+		return errors.New("list->vector takes 1 args")
+	}
+
+	p, ok := stack.Pop().(*Pair)
+	if !ok {
+		return errors.New("list->vector takes a list as the argument")
+	}
+
+	v, err := list2vec(p)
+	if err != nil {
+		return err
+	}
+	if err := reserve(len(v), valueSize); err != nil {
+		return err
+	}
+
+	stack.Push(Vector{&v})
+	return nil
+}
+
+func FnVectorFill(nargs int) error {
+	if nargs < 2 || nargs > 4 {
+		return errors.New("vector-fill! takes 2 to 4 arguments")
+	}
+
+	vec, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-fill! takes a vector as the first argument")
+	}
+	fill := stack.Pop()
+	start, end, err := popRange("vector-fill!", nargs, 2, len(*vec.v))
+	if err != nil {
+		return err
+	}
+
+	for i := start; i < end; i++ {
+		(*vec.v)[i] = fill
+	}
+	stack.Push(vec)
+	return nil
+}
+
+func FnVectorCopy(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("vector-copy takes 1 to 3 arguments")
+	}
+
+	vec, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-copy takes a vector as the first argument")
+	}
+	start, end, err := popRange("vector-copy", nargs, 1, len(*vec.v))
+	if err != nil {
+		return err
+	}
+	if err := reserve(end-start, valueSize); err != nil {
+		return err
+	}
+
+	res := append([]Value{}, (*vec.v)[start:end]...)
+	stack.Push(Vector{&res})
+	return nil
+}
+
+func FnVectorCopyInto(nargs int) error {
+	if nargs < 3 || nargs > 5 {
+		return errors.New("vector-copy! takes 3 to 5 arguments")
+	}
+
+	to, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-copy! takes a vector as the first argument")
+	}
+	at, ok := toIndex(stack.Pop())
+	if !ok {
+		return errors.New("vector-copy! takes an index as the second argument")
+	}
+	from, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector-copy! takes a vector as the third argument")
+	}
+	start, end, err := popRange("vector-copy!", nargs, 3, len(*from.v))
+	if err != nil {
+		return err
+	}
+
+	if at+end-start > len(*to.v) {
+		return errors.New("vector-copy!: index out of range")
+	}
+	// copy handles overlapping vectors correctly
+	copy((*to.v)[at:], (*from.v)[start:end])
+	stack.Push(to)
+	return nil
+}
+
+func FnVectorAppend(nargs int) error {
+	n := 0
+	for _, v := range stack[len(stack)-nargs:] {
+		if vec, ok := v.(Vector); ok {
+			n += len(*vec.v)
+		}
+	}
+	if err := reserve(n, valueSize); err != nil {
+		return err
+	}
+
+	res := make([]Value, 0, n)
+	for i := 0; i < nargs; i++ {
+		vec, ok := stack.Pop().(Vector)
+		if !ok {
+			return errors.New("vector-append takes vectors as arguments")
+		}
+		res = append(res, *vec.v...)
+	}
+	stack.Push(Vector{&res})
+	return nil
+}
+
+func FnVector2String(nargs int) error {
+	if nargs < 1 || nargs > 3 {
+		return errors.New("vector->string takes 1 to 3 arguments")
+	}
+
+	vec, ok := stack.Pop().(Vector)
+	if !ok {
+		return errors.New("vector->string takes a vector as the first argument")
+	}
+	start, end, err := popRange("vector->string", nargs, 1, len(*vec.v))
+	if err != nil {
+		return err
+	}
+	if err := reserve(end-start, runeSize); err != nil {
+		return err
+	}
+
+	rs := []rune{}
+	for _, v := range (*vec.v)[start:end] {
+		ch, ok := v.(Char)
+		if !ok {
+			return errors.New("vector->string takes a vector of chars")
+		}
+		rs = append(rs, rune(ch))
+	}
+	s := string(rs)
+	stack.Push(NewString(s))
+	return nil
+}
+
+// vectorArgs pops the procedure and vectors that vector-map and
+// vector-for-each take.
+func vectorArgs(name string, nargs int) (Value, []Vector, int, error) {
+	if nargs < 2 {
+		return nil, nil, 0, fmt.Errorf("%s takes at least 2 arguments", name)
+	}
+
+	proc := stack.Pop()
+	vecs := []Vector{}
+	shortest := -1
+	for i := 1; i < nargs; i++ {
+		vec, ok := stack.Pop().(Vector)
+		if !ok {
+			return nil, nil, 0, fmt.Errorf("%s takes vectors", name)
+		}
+		if shortest == -1 || len(*vec.v) < shortest {
+			shortest = len(*vec.v)
+		}
+		vecs = append(vecs, vec)
+	}
+	return proc, vecs, shortest, nil
+}
+
+func FnVectorMap(nargs int) error {
+	proc, vecs, n, err := vectorArgs("vector-map", nargs)
+	if err != nil {
+		return err
+	}
+
+	res := []Value{}
+	for i := 0; i < n; i++ {
+		args := []Value{}
+		for _, vec := range vecs {
+			args = append(args, (*vec.v)[i])
+		}
+		v, err := Apply(proc, args...)
+		if err != nil {
+			return err
+		}
+		res = append(res, v)
+	}
+	stack.Push(Vector{&res})
+	return nil
+}
+
+func FnVectorForEach(nargs int) error {
+	proc, vecs, n, err := vectorArgs("vector-for-each", nargs)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		args := []Value{}
+		for _, vec := range vecs {
+			args = append(args, (*vec.v)[i])
+		}
+		if _, err := Apply(proc, args...); err != nil {
+			return err
+		}
+	}
+	stack.Push(Boolean(true))
+	return nil
+}
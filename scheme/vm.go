@@ -0,0 +1,370 @@
+package scheme
+
+import (
+	"fmt"
+	"os"
+)
+
+var OutputPortStack = []OutputPort{NewOutputPort(os.Stdout, os.Stdout, "stdout")}
+var InputPortStack = []InputPort{NewInputPort(os.Stdin, os.Stdin, "stdin")}
+var ErrorPort = NewOutputPort(os.Stderr, os.Stderr, "stderr")
+
+type Op uint8
+
+const (
+	Imm Op = iota
+	GetVar
+	Call
+	Lambda
+	Set
+	Define
+	SaveScope
+	Jump        // Skip nargs instructions
+	JumpIfFalse // Pop a value and skip nargs instructions if it is #f
+	Pop
+)
+
+type Ins struct {
+	op    Op
+	imm   Value
+	nargs int
+}
+
+func (scope *Scope) Lookup(v Value) *Scope {
+	sym, ok := v.(Symbol)
+	if !ok {
+		_, ok = v.(Scoped)
+		if !ok {
+			fmt.Printf("Found type: %T\n", v)
+			panic("Tried to lookup non-symbol")
+		}
+		sym = v.(Scoped).Symbol
+		scope = scope.Lookup(v.(Scoped).Scope)
+	}
+
+	if scope == nil {
+		return nil
+	}
+
+	if _, ok := scope.m[sym]; !ok {
+		return scope.super.Lookup(sym)
+	}
+	return scope
+}
+
+// isTail reports whether the instructions left after a call do nothing but
+// jump to the end of the procedure.
+func isTail(ins []Ins) bool {
+	for len(ins) > 0 && ins[0].op == Jump && ins[0].nargs < len(ins) {
+		ins = ins[1+ins[0].nargs:]
+	}
+	return len(ins) == 0 || (ins[0].op == Jump && ins[0].nargs == len(ins)-1)
+}
+
+// MaxCallDepth is how deeply calls can nest before an error is raised, or 0
+// for no limit but memory.  Tail calls don't nest.
+var MaxCallDepth = 10000000
+
+// callDepth is how deeply the calls being evaluated are nested.
+var callDepth int
+
+func depthError() error {
+	return fmt.Errorf("Maximum call depth (%d) exceeded", MaxCallDepth)
+}
+
+// Eval runs p's instructions.  Calls to procedures made of instructions don't
+// recurse on the Go stack: the caller is saved in the evaluation's frames,
+// which are on the heap, and taken back off when the callee has run out of
+// instructions.
+func (p *Procedure) Eval() (err error) {
+	lim := limits
+	ev := &evaluation{frames: []frame{}}
+	evaluations = append(evaluations, ev)
+	entry := winding
+	callDepth++
+	defer func() {
+		callDepth -= 1 + len(ev.frames)
+		ev.done = true
+		evaluations = evaluations[:len(evaluations)-1]
+
+		switch err.(type) {
+		case nil, *jump:
+			return
+		case *LimitError:
+			winding = entry
+		default:
+			rewind(entry)
+		}
+		// Leave the stack as returning from each frame would have
+		if len(ev.frames) > 0 && len(stack) > ev.frames[0].stack_pos {
+			stack = append(stack[:ev.frames[0].stack_pos], stack.Top())
+		}
+	}()
+	if MaxCallDepth > 0 && callDepth > MaxCallDepth {
+		return depthError()
+	}
+
+begin:
+	for len(p.Ins) > 0 {
+		if lim != nil {
+			if err := lim.step(); err != nil {
+				return err
+			}
+		}
+
+		ins := p.Ins[0]
+		p.Ins = p.Ins[1:]
+
+		switch ins.op {
+		case Imm:
+			stack.Push(ins.imm)
+		case GetVar:
+			var scope *Scope
+			switch ins.imm.(type) {
+			case Symbol, Scoped:
+				scope = p.Scope.Lookup(ins.imm)
+			default:
+				return fmt.Errorf("Tried to look up non-variable")
+			}
+			if scope == nil {
+				return fmt.Errorf("Could not find variable: %s",
+					SymbolNames[ins.imm.(Symbol)])
+			}
+
+			sym, ok := ins.imm.(Symbol)
+			if !ok {
+				sym = ins.imm.(Scoped).Symbol
+			}
+			stack.Push(scope.m[sym])
+		case Call:
+			callee := stack.Pop()
+			newp_template, ok := callee.(*Procedure)
+			if !ok {
+				return fmt.Errorf("Call to non-procedure: %s", ValueString(callee, false))
+			}
+
+			var nargs int
+			if ins.nargs == -1 { // (call-with-values ...) will use nargs of -1
+				if n, ok := stack.Top().(MultipleValues); ok {
+					stack.Pop()
+					nargs = int(n)
+				} else {
+					nargs = 1
+				}
+			} else {
+				nargs = ins.nargs
+			}
+
+			if k := newp_template.Cont; k != nil {
+				values := make([]Value, nargs)
+				for i := range values {
+					values[i] = stack.Pop()
+				}
+				if err := rewind(k.winding); err != nil {
+					return err
+				}
+				j := &jump{k, values}
+				if !j.resumes(ev) {
+					return j
+				}
+				p = ev.resume(j)
+				goto begin
+			}
+
+			var newp *Procedure
+			var res error
+			stack_pos := len(stack) - nargs
+			switch {
+			case newp_template.CallCC != nil:
+				res = newp_template.CallCC(ev.capture(p, nargs), nargs)
+			case newp_template.Builtin != nil:
+				res = newp_template.Builtin(nargs)
+				if res == nil {
+					if !wantsValues(p.Ins) {
+						firstValue(stack_pos)
+					}
+					continue
+				}
+			default:
+				newp = &Procedure{}
+				*newp = *newp_template
+				newp.Scope.m = map[Symbol]Value{}
+				n := nargs
+				cur := newp.Args
+				_, ispair := newp.Args.(*Pair)
+				for n > 0 && ispair {
+					if cur == Empty {
+						return fmt.Errorf("Wrong arg count (got %d)", n)
+					}
+					sym, ok := (*cur.(*Pair).Car).(Symbol)
+					if !ok {
+						panic("Non-symbol argument?")
+					}
+					newp.Scope.m[sym] = stack.Pop()
+					n--
+					cur = *cur.(*Pair).Cdr
+					if _, ok := cur.(*Pair); !ok {
+						break
+					}
+				}
+
+				// Dot arg
+				if s, ok := cur.(Symbol); ok {
+					rest := &Pair{}
+					cur := rest
+					if n == 0 {
+						newp.Scope.m[s] = Empty
+					} else {
+						for n > 0 {
+							v := stack.Pop()
+							n--
+							cur.Car = &v
+
+							if n == 0 {
+								cdr := Empty
+								cur.Cdr = &cdr
+								break
+							}
+							var next Value = &Pair{}
+							cur.Cdr = &next
+							cur = next.(*Pair)
+						}
+						newp.Scope.m[s] = rest
+					}
+				}
+				stack_pos = len(stack)
+			}
+
+			switch res := res.(type) {
+			case nil:
+			case *enter:
+				newp = res.p
+				stack_pos = len(stack) - res.nargs
+			case *jump:
+				if !res.resumes(ev) {
+					return res
+				}
+				p = ev.resume(res)
+				goto begin
+			default:
+				return res
+			}
+
+			if !isTail(p.Ins) {
+				if MaxCallDepth > 0 && callDepth >= MaxCallDepth {
+					return depthError()
+				}
+				ev.frames = append(ev.frames, frame{p, p.Ins, stack_pos})
+				callDepth++
+			}
+			p = newp
+			goto begin
+		case Lambda: // Procedure -> *Procedure
+			lambda := ins.imm.(Procedure)
+			lambda.Scope = Scope{}
+			lambda.Scope.m = map[Symbol]Value{}
+
+			scope := p.Scope
+			lambda.Scope.super = &scope
+
+			stack.Push(Value(&lambda))
+		case Set:
+			sym := Unscope(ins.imm).(Symbol)
+			scope := p.Scope.Lookup(ins.imm)
+			if scope == nil {
+				scope = &p.Scope
+			}
+			scope.m[sym] = stack.Top()
+		case Define:
+			sym := ins.imm.(Symbol)
+			if _, ok := p.Scope.m[sym]; ok {
+				fmt.Printf("WARNING: Redefining binding %s\n", SymbolNames[sym])
+			}
+			p.Scope.m[sym] = stack.Top()
+		case SaveScope:
+			stack.Push(&p.Scope)
+		case Jump:
+			p.Ins = p.Ins[ins.nargs:]
+		case JumpIfFalse:
+			if cond, ok := stack.Pop().(Boolean); ok && !bool(cond) {
+				p.Ins = p.Ins[ins.nargs:]
+			}
+		case Pop:
+			stack.Pop()
+		}
+	}
+
+	if len(ev.frames) > 0 { // Return to the caller
+		caller := ev.frames[len(ev.frames)-1]
+		ev.frames[len(ev.frames)-1] = frame{}
+		ev.frames = ev.frames[:len(ev.frames)-1]
+		callDepth--
+
+		// Clear temps from stack, keeping every value returned
+		keep := 1
+		if n, ok := stack.Top().(MultipleValues); ok &&
+			len(stack)-int(n)-1 >= caller.stack_pos {
+			keep += int(n)
+		}
+		stack = append(stack[:caller.stack_pos], stack[len(stack)-keep:]...)
+		p = caller.p
+		p.Ins = caller.ins
+		if !wantsValues(p.Ins) {
+			firstValue(caller.stack_pos)
+		}
+		goto begin
+	}
+	return nil
+}
+
+// Disassemble writes a listing of ins to the current output port, one
+// instruction per line.  The bodies of lambdas are listed beneath the
+// instruction that introduces them, indented one level.
+func Disassemble(ins []Ins, depth int) {
+	for i := range ins {
+		ins[i].Print(i, depth)
+	}
+}
+
+func (ins Ins) Print(idx int, depth int) {
+	port := OutputPortStack[len(OutputPortStack)-1]
+	fmt.Fprintf(port, "%*s%04d ", depth*4, "", idx)
+
+	switch ins.op {
+	case Imm:
+		fmt.Fprint(port, "IMM ")
+		WriteValue(ins.imm, false)
+	case GetVar:
+		fmt.Fprint(port, "GETVAR ")
+		WriteValue(ins.imm, false)
+	case Call:
+		fmt.Fprintf(port, "CALL %d", ins.nargs)
+	case Set:
+		fmt.Fprint(port, "SET! ")
+		WriteValue(ins.imm, false)
+	case Define:
+		fmt.Fprint(port, "DEFINE ")
+		WriteValue(ins.imm, false)
+	case Lambda:
+		args := ins.imm.(Procedure).Args
+		if args == nil { // let-syntax bodies have no argument list
+			args = Empty
+		}
+		fmt.Fprint(port, "LAMBDA ")
+		WriteValue(args, false)
+		fmt.Fprintln(port)
+		Disassemble(ins.imm.(Procedure).Ins, depth+1)
+		return
+	case SaveScope:
+		fmt.Fprint(port, "SAVE-SCOPE")
+	case Jump:
+		fmt.Fprintf(port, "JUMP +%d", ins.nargs)
+	case JumpIfFalse:
+		fmt.Fprintf(port, "JUMP-IF-FALSE +%d", ins.nargs)
+	case Pop:
+		fmt.Fprint(port, "POP")
+	default:
+		fmt.Fprintf(port, "[unknown op %d]", ins.op)
+	}
+	fmt.Fprintln(port)
+}